@@ -17,21 +17,104 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"encoding/json"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// +kubebuilder:validation:Enum=repository;organization;enterprise
+type ActionsRunnerScope string
+
+const (
+	ActionsRunnerScopeRepository   ActionsRunnerScope = "repository"
+	ActionsRunnerScopeOrganization ActionsRunnerScope = "organization"
+	ActionsRunnerScopeEnterprise   ActionsRunnerScope = "enterprise"
 )
 
 type ActionsRunnerRepository struct {
-	Owner       string `json:"owner"`
-	Name        string `json:"name"`
-	APIEndpoint string `json:"apiEndpoint,omitempty"`
+	// +kubebuilder:default=repository
+	Scope       ActionsRunnerScope `json:"scope,omitempty"`
+	Owner       string             `json:"owner"`          // organization or enterprise slug for non-repository scopes
+	Name        string             `json:"name,omitempty"` // required for the repository scope only
+	RunnerGroup string             `json:"runnerGroup,omitempty"`
+	APIEndpoint string             `json:"apiEndpoint,omitempty"`
+}
+
+func (r ActionsRunnerRepository) GetScope() ActionsRunnerScope {
+	if r.Scope == "" {
+		return ActionsRunnerScopeRepository
+	}
+
+	return r.Scope
+}
+
+// String returns the path of the runner target, e.g. "owner/name", "owner" or "enterprises/owner".
+func (r ActionsRunnerRepository) String() string {
+	switch r.GetScope() {
+	case ActionsRunnerScopeOrganization:
+		return r.Owner
+
+	case ActionsRunnerScopeEnterprise:
+		return "enterprises/" + r.Owner
+
+	default:
+		return r.Owner + "/" + r.Name
+	}
 }
 
 type ActionsRunnerPolicyRule string
 
-type ActionsRunnerPolicy struct {
-	Must    []ActionsRunnerPolicyRule `json:"must,omitempty"`
-	MustNot []ActionsRunnerPolicyRule `json:"mustNot,omitempty"`
+// ActionsRunnerPolicyRequestVariable holds the normalized job request in policy rules, which run against its contextData
+const ActionsRunnerPolicyRequestVariable = "$request"
+
+// ActionsRunnerPolicyRuleSpec documents a rule for the developers whose jobs violate it
+type ActionsRunnerPolicyRuleSpec struct {
+	Rule              ActionsRunnerPolicyRule              `json:"rule"` // jq over the job context, e.g. .github.ref, with the job request as $request
+	Description       string                               `json:"description,omitempty"`
+	Message           string                               `json:"message,omitempty"` // text/template over the job context shown instead of the rule, e.g. {{ .github.ref }}
+	DocumentationURL  string                               `json:"documentationURL,omitempty"`
+	EnforcementAction ActionsRunnerPolicyEnforcementAction `json:"enforcementAction,omitempty"` // defaults to the one of the policy
+}
+
+// ActionsRunnerPolicyInlineRule is either a bare rule, as policies used to be written, or an object documenting it
+// +kubebuilder:validation:Schemaless
+// +kubebuilder:pruning:PreserveUnknownFields
+type ActionsRunnerPolicyInlineRule struct {
+	ID                          string `json:"id,omitempty"`
+	ActionsRunnerPolicyRuleSpec `json:",inline"`
+}
+
+func (r *ActionsRunnerPolicyInlineRule) UnmarshalJSON(data []byte) error {
+	var rule ActionsRunnerPolicyRule
+	if err := json.Unmarshal(data, &rule); err == nil {
+		*r = ActionsRunnerPolicyInlineRule{}
+		r.Rule = rule
+		return nil
+	}
+
+	type plain ActionsRunnerPolicyInlineRule
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// MarshalJSON keeps bare rules as strings so existing objects round trip unchanged
+func (r ActionsRunnerPolicyInlineRule) MarshalJSON() ([]byte, error) {
+	if r.ID == "" && r.ActionsRunnerPolicyRuleSpec == (ActionsRunnerPolicyRuleSpec{Rule: r.Rule}) {
+		return json.Marshal(r.Rule)
+	}
+
+	type plain ActionsRunnerPolicyInlineRule
+	return json.Marshal(plain(r))
+}
+
+type ActionsRunnerPolicyRules struct {
+	Must    []ActionsRunnerPolicyInlineRule `json:"must,omitempty"`
+	MustNot []ActionsRunnerPolicyInlineRule `json:"mustNot,omitempty"`
+}
+
+type ActionsRunnerPolicyReference struct {
+	Name string `json:"name"` // cluster-scoped ActionsRunnerPolicy
 }
 
 type ActionsRunnerCapability string
@@ -41,14 +124,37 @@ const (
 	ActionsRunnerCapabilityDocker ActionsRunnerCapability = "docker"
 )
 
+// keys of ActionsRunnerSpec.Resources
+const (
+	ActionsRunnerResourcesRunner = "runner"
+	ActionsRunnerResourcesDocker = "docker"
+)
+
+// ActionsRunnerDrainAnnotation set to "true" stops the ActionsRunner from accepting jobs, e.g. while it awaits replacement
+const ActionsRunnerDrainAnnotation = "kube-actions.inloco.com.br/drain"
+
+// IsDraining tells whether the ActionsRunner was annotated with ActionsRunnerDrainAnnotation
+func (r *ActionsRunner) IsDraining() bool {
+	return r.GetAnnotations()[ActionsRunnerDrainAnnotation] == "true"
+}
+
+// ActionsRunnerSkipDeregistrationAnnotation set to "true" lets a deleted ActionsRunner go without removing its agent from GitHub
+const ActionsRunnerSkipDeregistrationAnnotation = "kube-actions.inloco.com.br/skip-deregistration"
+
+// SkipsDeregistration tells whether the ActionsRunner was annotated with ActionsRunnerSkipDeregistrationAnnotation
+func (r *ActionsRunner) SkipsDeregistration() bool {
+	return r.GetAnnotations()[ActionsRunnerSkipDeregistrationAnnotation] == "true"
+}
+
 // ActionsRunnerSpec defines the desired state of ActionsRunner
 type ActionsRunnerSpec struct {
-	Repository   ActionsRunnerRepository   `json:"repository"`
-	Policy       ActionsRunnerPolicy       `json:"policy,omitempty"`
-	Capabilities []ActionsRunnerCapability `json:"capabilities,omitempty"`
-	Annotations  map[string]string         `json:"annotations,omitempty"`
-	Labels       []string                  `json:"labels,omitempty"`
-	Version      string                    `json:"version,omitempty"` // image override for debugging
+	Repository   ActionsRunnerRepository        `json:"repository"`
+	Policy       ActionsRunnerPolicyRules       `json:"policy,omitempty"`
+	PolicyRefs   []ActionsRunnerPolicyReference `json:"policyRefs,omitempty"` // enforced along with Policy, defaults to the ones listed by the namespace
+	Capabilities []ActionsRunnerCapability      `json:"capabilities,omitempty"`
+	Annotations  map[string]string              `json:"annotations,omitempty"`
+	Labels       []string                       `json:"labels,omitempty"`
+	Version      string                         `json:"version,omitempty"` // image version, defaults to the one of the operator
 
 	Volumes      []corev1.Volume                        `json:"volumes,omitempty" patchStrategy:"merge,retainKeys" patchMergeKey:"name"`
 	VolumeMounts []corev1.VolumeMount                   `json:"volumeMounts,omitempty" patchStrategy:"merge" patchMergeKey:"mountPath"`
@@ -60,15 +166,52 @@ type ActionsRunnerSpec struct {
 	Affinity           *corev1.Affinity    `json:"affinity,omitempty"`
 	Tolerations        []corev1.Toleration `json:"tolerations,omitempty"`
 	NodeSelector       map[string]string   `json:"nodeSelector,omitempty"`
+
+	MaxJobDuration *metav1.Duration `json:"maxJobDuration,omitempty"` // caps the Pod deadline, defaults to 6h
+
+	// PodTemplate is a strategic merge patch applied on top of the generated Pod, e.g. {"spec":{"priorityClassName":"ci"}}.
+	// It may add to but not override the labels, annotations, volumes, mounts, env and security contexts of the operator.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplate *runtime.RawExtension `json:"podTemplate,omitempty"`
 }
 
+type ActionsRunnerConditionType string
+
+const (
+	ActionsRunnerConditionRegistered   ActionsRunnerConditionType = "Registered"
+	ActionsRunnerConditionListening    ActionsRunnerConditionType = "Listening"
+	ActionsRunnerConditionJobAssigned  ActionsRunnerConditionType = "JobAssigned"
+	ActionsRunnerConditionDegraded     ActionsRunnerConditionType = "Degraded"
+	ActionsRunnerConditionLabelsSynced ActionsRunnerConditionType = "LabelsSynced"
+)
+
 // ActionsRunnerStatus defines the observed state of ActionsRunner
 type ActionsRunnerStatus struct {
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	AgentId                int          `json:"agentId,omitempty"`
+	LastMessageType        string       `json:"lastMessageType,omitempty"`
+	LastMessageTime        *metav1.Time `json:"lastMessageTime,omitempty"`
+	LastUnrecoverableError string       `json:"lastUnrecoverableError,omitempty"`
+	Labels                 []string     `json:"labels,omitempty"` // registered on the agent, differ from .spec.labels while an update is deferred
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:categories=actions,shortName=ar
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Scope",type=string,JSONPath=`.spec.repository.scope`,priority=1
+// +kubebuilder:printcolumn:name="Owner",type=string,JSONPath=`.spec.repository.owner`
+// +kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.spec.repository.name`
+// +kubebuilder:printcolumn:name="Registered",type=string,JSONPath=`.status.conditions[?(@.type=="Registered")].status`
+// +kubebuilder:printcolumn:name="Listening",type=string,JSONPath=`.status.conditions[?(@.type=="Listening")].status`
+// +kubebuilder:printcolumn:name="Job",type=string,JSONPath=`.status.conditions[?(@.type=="JobAssigned")].status`
+// +kubebuilder:printcolumn:name="Degraded",type=string,JSONPath=`.status.conditions[?(@.type=="Degraded")].status`
+// +kubebuilder:printcolumn:name="Agent",type=integer,JSONPath=`.status.agentId`,priority=1
+// +kubebuilder:printcolumn:name="Last Message",type=string,JSONPath=`.status.lastMessageType`,priority=1
+// +kubebuilder:printcolumn:name="Last Message Time",type=date,JSONPath=`.status.lastMessageTime`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ActionsRunner is the Schema for the actionsrunners API
 type ActionsRunner struct {
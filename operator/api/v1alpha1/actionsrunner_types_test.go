@@ -0,0 +1,32 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestActionsRunnerPolicyRulesJSON(t *testing.T) {
+	data := `{"must":[".github.ref == \"refs/heads/main\""],"mustNot":[{"id":"forks","rule":".github.event.pull_request.head.repo.fork","message":"forks of {{ .github.repository }} are not allowed"}]}`
+
+	var rules ActionsRunnerPolicyRules
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rules.Must) != 1 || rules.Must[0].Rule != `.github.ref == "refs/heads/main"` {
+		t.Error("len(rules.Must) != 1 || rules.Must[0].Rule != `.github.ref == \"refs/heads/main\"`")
+	}
+
+	if len(rules.MustNot) != 1 || rules.MustNot[0].ID != "forks" || rules.MustNot[0].Message == "" {
+		t.Error(`len(rules.MustNot) != 1 || rules.MustNot[0].ID != "forks" || rules.MustNot[0].Message == ""`)
+	}
+
+	b, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != data {
+		t.Error(`string(b) != data`)
+	}
+}
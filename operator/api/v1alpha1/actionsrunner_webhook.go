@@ -17,16 +17,26 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"path"
 	"reflect"
+	"strings"
+	"text/template"
 
 	"github.com/itchyny/gojq"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/inloco/kube-actions/operator/jq"
 )
 
 // log is for logging in this package.
@@ -38,6 +48,40 @@ func (r *ActionsRunner) SetupWebhookWithManager(mgr ctrl.Manager) error {
 		Complete()
 }
 
+// actionsRunnerSpecDefaulter resolves operator level defaults, e.g. the image version, it is a no-op until the manager
+// sets it up
+var actionsRunnerSpecDefaulter func(spec *ActionsRunnerSpec)
+
+func SetActionsRunnerSpecDefaulter(defaulter func(spec *ActionsRunnerSpec)) {
+	actionsRunnerSpecDefaulter = defaulter
+}
+
+// DefaultActionsRunnerSpec fills the unset fields of spec the same way the defaulting webhook does.
+func DefaultActionsRunnerSpec(spec *ActionsRunnerSpec) {
+	if actionsRunnerSpecDefaulter != nil {
+		actionsRunnerSpecDefaulter(spec)
+	}
+}
+
+// githubAPIEndpointValidator checks the GitHub instances the operator is allowed to talk to, it is a no-op until the
+// manager sets it up
+var githubAPIEndpointValidator func(apiEndpoint string) error
+
+func SetGitHubAPIEndpointValidator(validator func(apiEndpoint string) error) {
+	githubAPIEndpointValidator = validator
+}
+
+//+kubebuilder:webhook:path=/mutate-inloco-com-br-v1alpha1-actionsrunner,mutating=true,failurePolicy=fail,sideEffects=None,groups=inloco.com.br,resources=actionsrunners,verbs=create;update,versions=v1alpha1,name=mactionsrunner.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ActionsRunner{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (ar *ActionsRunner) Default() {
+	actionsrunnerlog.Info("default", "name", ar.Name)
+
+	DefaultActionsRunnerSpec(&ar.Spec)
+}
+
 //+kubebuilder:webhook:verbs=create;update,sideEffects=none,admissionReviewVersions=v1,path=/validate-inloco-com-br-v1alpha1-actionsrunner,mutating=false,failurePolicy=fail,groups=inloco.com.br,resources=actionsrunners,versions=v1alpha1,name=vactionsrunner.kb.io
 
 var _ webhook.Validator = &ActionsRunner{}
@@ -46,19 +90,11 @@ var _ webhook.Validator = &ActionsRunner{}
 func (ar *ActionsRunner) ValidateCreate() (warnings admission.Warnings, err error) {
 	actionsrunnerlog.Info("validate create", "name", ar.Name)
 
-	for _, policyRule := range ar.Spec.Policy.Must {
-		if err := validatePolicyRule(policyRule); err != nil {
-			return nil, err
-		}
+	if err := validateRepository(ar.Spec.Repository); err != nil {
+		return nil, err
 	}
 
-	for _, policyRule := range ar.Spec.Policy.MustNot {
-		if err := validatePolicyRule(policyRule); err != nil {
-			return nil, err
-		}
-	}
-
-	return nil, nil
+	return validateSpec(field.NewPath("spec"), &ar.Spec, nil)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -74,45 +110,140 @@ func (ar *ActionsRunner) ValidateUpdate(old runtime.Object) (admission.Warnings,
 		return nil, errors.New(".Spec.Repository is immutable")
 	}
 
-	if !reflect.DeepEqual(ar.Spec.Labels, oldAR.Spec.Labels) {
-		return nil, errors.New(".Spec.Labels is immutable")
-	}
+	return validateSpec(field.NewPath("spec"), &ar.Spec, &oldAR.Spec)
+}
 
-	for _, policyRule := range ar.Spec.Policy.Must {
-		if err := validatePolicyRule(policyRule); err != nil {
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (ar *ActionsRunner) ValidateDelete() (admission.Warnings, error) {
+	actionsrunnerlog.Info("validate delete", "name", ar.Name)
+
+	return nil, nil
+}
+
+// validateSpec validates the mutable fields of spec, issues already present on the same field of oldSpec are only warned
+// about so objects admitted before a check existed can still be updated, e.g. to remove finalizers
+func validateSpec(fldPath *field.Path, spec *ActionsRunnerSpec, oldSpec *ActionsRunnerSpec) (admission.Warnings, error) {
+	for _, inlineRule := range spec.Policy.Must {
+		if err := ValidatePolicyRuleSpec(inlineRule.ActionsRunnerPolicyRuleSpec); err != nil {
 			return nil, err
 		}
 	}
 
-	for _, policyRule := range ar.Spec.Policy.MustNot {
-		if err := validatePolicyRule(policyRule); err != nil {
+	for _, inlineRule := range spec.Policy.MustNot {
+		if err := ValidatePolicyRuleSpec(inlineRule.ActionsRunnerPolicyRuleSpec); err != nil {
 			return nil, err
 		}
 	}
 
-	return nil, nil
+	if err := validatePodTemplate(spec.PodTemplate); err != nil {
+		return nil, err
+	}
+
+	warnings, errs := validateSpecFields(fldPath, spec)
+	if len(errs) == 0 || oldSpec == nil {
+		return warnings, errs.ToAggregate()
+	}
+
+	_, oldErrs := validateSpecFields(fldPath, oldSpec)
+	oldIssues := make(map[string]struct{}, len(oldErrs))
+	for _, err := range oldErrs {
+		oldIssues[err.Error()] = struct{}{}
+	}
+
+	var newErrs field.ErrorList
+	for _, err := range errs {
+		if _, ok := oldIssues[err.Error()]; ok {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+
+		newErrs = append(newErrs, err)
+	}
+
+	return warnings, newErrs.ToAggregate()
 }
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
-func (ar *ActionsRunner) ValidateDelete() (admission.Warnings, error) {
-	actionsrunnerlog.Info("validate delete", "name", ar.Name)
+func validateRepository(repository ActionsRunnerRepository) error {
+	if repository.Owner == "" {
+		return errors.New(".Spec.Repository.Owner is required")
+	}
 
-	return nil, nil
+	if repository.APIEndpoint != "" {
+		u, err := url.Parse(repository.APIEndpoint)
+		if err != nil {
+			return fmt.Errorf("unable to parse .Spec.Repository.APIEndpoint: %w", err)
+		}
+
+		if u.Scheme == "" || u.Host == "" {
+			return errors.New(".Spec.Repository.APIEndpoint must be an absolute URL")
+		}
+
+		if githubAPIEndpointValidator != nil {
+			if err := githubAPIEndpointValidator(repository.APIEndpoint); err != nil {
+				return fmt.Errorf("unsupported .Spec.Repository.APIEndpoint: %w", err)
+			}
+		}
+	}
+
+	switch scope := repository.GetScope(); scope {
+	case ActionsRunnerScopeRepository:
+		if repository.Name == "" {
+			return errors.New(".Spec.Repository.Name is required for the repository scope")
+		}
+
+		if repository.RunnerGroup != "" {
+			return errors.New(".Spec.Repository.RunnerGroup is not supported for the repository scope")
+		}
+
+	case ActionsRunnerScopeOrganization, ActionsRunnerScopeEnterprise:
+		if repository.Name != "" {
+			return fmt.Errorf(".Spec.Repository.Name is not supported for the %s scope", scope)
+		}
+
+	default:
+		return fmt.Errorf("unknown .Spec.Repository.Scope: %s", scope)
+	}
+
+	return nil
+}
+
+// ValidatePolicyRuleSpec checks the rule along with the message template and documentation URL shown on violations
+func ValidatePolicyRuleSpec(ruleSpec ActionsRunnerPolicyRuleSpec) error {
+	if err := validatePolicyRule(ruleSpec.Rule); err != nil {
+		return err
+	}
+
+	if _, err := template.New("message").Parse(ruleSpec.Message); err != nil {
+		return fmt.Errorf("unable to parse message of policy rule `%s`: %w", ruleSpec.Rule, err)
+	}
+
+	if ruleSpec.DocumentationURL != "" {
+		u, err := url.Parse(ruleSpec.DocumentationURL)
+		if err != nil {
+			return fmt.Errorf("unable to parse documentation URL of policy rule `%s`: %w", ruleSpec.Rule, err)
+		}
+
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("documentation URL of policy rule `%s` must be an absolute URL", ruleSpec.Rule)
+		}
+	}
+
+	return nil
 }
 
 func validatePolicyRule(policyRule ActionsRunnerPolicyRule) error {
-	q, err := gojq.Parse(string(policyRule))
+	q, err := jq.Parse(string(policyRule))
 	if err != nil {
 		return fmt.Errorf("unable to parse policy rule `%s`: %w", policyRule, err)
 	}
 
-	c, err := gojq.Compile(q)
+	c, err := gojq.Compile(q, jq.CompilerOptions(ActionsRunnerPolicyRequestVariable)...)
 	if err != nil {
 		return fmt.Errorf("unable to compile policy rule `%s`: %w", policyRule, err)
 	}
 
-	var input map[string]interface{}
-	it := c.Run(input)
+	var input, request, lists map[string]interface{}
+	it := c.Run(input, request, lists)
 
 	v, ok := it.Next()
 	if !ok {
@@ -125,3 +256,172 @@ func validatePolicyRule(policyRule ActionsRunnerPolicyRule) error {
 
 	return nil
 }
+
+func validatePodTemplate(podTemplate *runtime.RawExtension) error {
+	if podTemplate == nil || len(podTemplate.Raw) == 0 {
+		return nil
+	}
+
+	patched, err := strategicpatch.StrategicMergePatch([]byte("{}"), podTemplate.Raw, corev1.Pod{})
+	if err != nil {
+		return fmt.Errorf("invalid .Spec.PodTemplate: %w", err)
+	}
+
+	var pod corev1.Pod
+	if err := json.Unmarshal(patched, &pod); err != nil {
+		return fmt.Errorf("invalid .Spec.PodTemplate: %w", err)
+	}
+
+	return nil
+}
+
+// paths mounted by the operator itself, user VolumeMounts on them would be silently replaced
+var reservedMountPaths = []string{
+	"/opt/actions-runner/.credentials",
+	"/opt/actions-runner/.credentials_rsaparams",
+	"/opt/actions-runner/.runner",
+}
+
+// paths mounted from the PersistentVolumeClaim when the runner requests storage
+var reservedStorageMountPaths = []string{
+	"/home/linuxbrew",
+	"/home/runner",
+	"/home/user",
+	"/opt/actions-runner/_work",
+	"/opt/hostedtoolcache",
+	"/root",
+}
+
+// names of the Volumes created by the operator itself
+var reservedVolumeNames = []string{
+	"config-map",
+	"persistent-volume-claim",
+	"secret",
+}
+
+// validateSpecFields catches what the Pod conversion would silently ignore or override, issues that are harmless are
+// returned as warnings
+func validateSpecFields(fldPath *field.Path, spec *ActionsRunnerSpec) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var errs field.ErrorList
+
+	capabilities := make(map[ActionsRunnerCapability]struct{}, len(spec.Capabilities))
+	for i, capability := range spec.Capabilities {
+		switch capability {
+		case ActionsRunnerCapabilitySecret, ActionsRunnerCapabilityDocker:
+			capabilities[capability] = struct{}{}
+
+		default:
+			errs = append(errs, field.NotSupported(
+				fldPath.Child("capabilities").Index(i),
+				capability,
+				[]string{string(ActionsRunnerCapabilitySecret), string(ActionsRunnerCapabilityDocker)},
+			))
+		}
+	}
+	_, hasSecret := capabilities[ActionsRunnerCapabilitySecret]
+	_, hasDocker := capabilities[ActionsRunnerCapabilityDocker]
+
+	for key := range spec.Resources {
+		switch key {
+		case ActionsRunnerResourcesRunner:
+
+		case ActionsRunnerResourcesDocker:
+			if !hasDocker {
+				warnings = append(warnings, fmt.Sprintf("%s: ignored without the %s capability", fldPath.Child("resources").Key(key), ActionsRunnerCapabilityDocker))
+			}
+
+		default:
+			errs = append(errs, field.NotSupported(
+				fldPath.Child("resources").Key(key),
+				key,
+				[]string{ActionsRunnerResourcesRunner, ActionsRunnerResourcesDocker},
+			))
+		}
+	}
+
+	volumeNames := make(map[string]struct{}, len(spec.Volumes))
+	for i, volume := range spec.Volumes {
+		namePath := fldPath.Child("volumes").Index(i).Child("name")
+
+		if containsString(reservedVolumeNames, volume.Name) {
+			errs = append(errs, field.Forbidden(namePath, fmt.Sprintf("%q is reserved by the operator", volume.Name)))
+			continue
+		}
+
+		if _, ok := volumeNames[volume.Name]; ok {
+			errs = append(errs, field.Duplicate(namePath, volume.Name))
+			continue
+		}
+		volumeNames[volume.Name] = struct{}{}
+	}
+
+	requestedStorage := false
+	if resources, ok := spec.Resources[ActionsRunnerResourcesRunner]; ok {
+		if storage := resources.Requests.Storage(); storage != nil && !storage.IsZero() {
+			requestedStorage = true
+		}
+	}
+
+	mountPaths := make(map[string]struct{}, len(spec.VolumeMounts))
+	for i, volumeMount := range spec.VolumeMounts {
+		volumeMountPath := fldPath.Child("volumeMounts").Index(i)
+
+		if _, ok := volumeNames[volumeMount.Name]; !ok {
+			errs = append(errs, field.NotFound(volumeMountPath.Child("name"), volumeMount.Name))
+		}
+
+		mountPath := path.Clean(volumeMount.MountPath)
+		if isReservedMountPath(mountPath, requestedStorage) {
+			errs = append(errs, field.Forbidden(volumeMountPath.Child("mountPath"), fmt.Sprintf("%q is reserved by the operator", volumeMount.MountPath)))
+			continue
+		}
+
+		if _, ok := mountPaths[mountPath]; ok {
+			errs = append(errs, field.Duplicate(volumeMountPath.Child("mountPath"), volumeMount.MountPath))
+			continue
+		}
+		mountPaths[mountPath] = struct{}{}
+	}
+
+	if !hasSecret {
+		for i, envFromSource := range spec.EnvFrom {
+			if envFromSource.SecretRef != nil {
+				errs = append(errs, field.Forbidden(fldPath.Child("envFrom").Index(i).Child("secretRef"), fmt.Sprintf("requires the %s capability", ActionsRunnerCapabilitySecret)))
+			}
+		}
+
+		for i, envVar := range spec.Env {
+			if envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil {
+				errs = append(errs, field.Forbidden(fldPath.Child("env").Index(i).Child("valueFrom", "secretKeyRef"), fmt.Sprintf("requires the %s capability", ActionsRunnerCapabilitySecret)))
+			}
+		}
+	}
+
+	return warnings, errs
+}
+
+func isReservedMountPath(mountPath string, requestedStorage bool) bool {
+	for _, reservedMountPath := range reservedMountPaths {
+		// mounting on a parent directory would shadow the runner installation
+		if mountPath == "/" || mountPath == reservedMountPath || strings.HasPrefix(reservedMountPath, mountPath+"/") {
+			return true
+		}
+	}
+
+	if requestedStorage {
+		return containsString(reservedStorageMountPaths, mountPath)
+	}
+
+	return false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
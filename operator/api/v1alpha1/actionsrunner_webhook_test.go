@@ -0,0 +1,133 @@
+package v1alpha1
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func invalidActionsRunnerSpec() ActionsRunnerSpec {
+	return ActionsRunnerSpec{
+		Repository: ActionsRunnerRepository{
+			Owner: "inloco",
+			Name:  "kube-actions",
+		},
+		Capabilities: []ActionsRunnerCapability{"gpu"},
+		Resources: map[string]corev1.ResourceRequirements{
+			ActionsRunnerResourcesRunner: {Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}},
+			ActionsRunnerResourcesDocker: {},
+			"dind":                       {},
+		},
+		Volumes: []corev1.Volume{
+			{Name: "cache"},
+			{Name: "secret"},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "cache", MountPath: "/cache"},
+			{Name: "missing", MountPath: "/missing"},
+			{Name: "cache", MountPath: "/opt/actions-runner/.runner"},
+			{Name: "cache", MountPath: "/opt"},
+			{Name: "cache", MountPath: "/root"},
+			{Name: "cache", MountPath: "/cache/"},
+		},
+		EnvFrom: []corev1.EnvFromSource{
+			{SecretRef: &corev1.SecretEnvSource{}},
+		},
+		Env: []corev1.EnvVar{
+			{Name: "PLAIN", Value: "value"},
+			{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{}}},
+		},
+	}
+}
+
+func TestValidateSpecFields(t *testing.T) {
+	spec := invalidActionsRunnerSpec()
+
+	warnings, errs := validateSpecFields(field.NewPath("spec"), &spec)
+
+	if len(warnings) != 1 || warnings[0] != "spec.resources[docker]: ignored without the docker capability" {
+		t.Error(`len(warnings) != 1 || warnings[0] != "spec.resources[docker]: ignored without the docker capability"`)
+	}
+
+	fields := make(map[string]struct{}, len(errs))
+	for _, err := range errs {
+		fields[err.Field] = struct{}{}
+	}
+
+	for _, f := range []string{
+		"spec.capabilities[0]",
+		"spec.resources[dind]",
+		"spec.volumes[1].name",
+		"spec.volumeMounts[1].name",
+		"spec.volumeMounts[2].mountPath",
+		"spec.volumeMounts[3].mountPath",
+		"spec.volumeMounts[4].mountPath",
+		"spec.volumeMounts[5].mountPath",
+		"spec.envFrom[0].secretRef",
+		"spec.env[1].valueFrom.secretKeyRef",
+	} {
+		if _, ok := fields[f]; !ok {
+			t.Errorf("_, ok := fields[%q]; !ok", f)
+		}
+	}
+
+	if len(errs) != 10 {
+		t.Error(`len(errs) != 10`)
+	}
+}
+
+func TestValidateSpecRatchets(t *testing.T) {
+	spec := invalidActionsRunnerSpec()
+
+	if _, err := validateSpec(field.NewPath("spec"), &spec, nil); err == nil {
+		t.Error(`_, err := validateSpec(field.NewPath("spec"), &spec, nil); err == nil`)
+	}
+
+	oldSpec := spec.DeepCopy()
+	warnings, err := validateSpec(field.NewPath("spec"), &spec, oldSpec)
+	if err != nil {
+		t.Error(`err != nil`)
+	}
+	if len(warnings) != 11 {
+		t.Error(`len(warnings) != 11`)
+	}
+
+	// unrelated changes keep the old issues as warnings
+	spec.Labels = []string{"changed"}
+	if _, err := validateSpec(field.NewPath("spec"), &spec, oldSpec); err != nil {
+		t.Error(`_, err := validateSpec(field.NewPath("spec"), &spec, oldSpec); err != nil`)
+	}
+
+	spec.Capabilities = append(spec.Capabilities, "new")
+	if _, err := validateSpec(field.NewPath("spec"), &spec, oldSpec); err == nil {
+		t.Error(`_, err := validateSpec(field.NewPath("spec"), &spec, oldSpec); err == nil`)
+	}
+}
+
+func TestValidateRepositoryAPIEndpoint(t *testing.T) {
+	defer SetGitHubAPIEndpointValidator(nil)
+	SetGitHubAPIEndpointValidator(func(apiEndpoint string) error {
+		if apiEndpoint != "https://ghe.example.com/api/v3" {
+			return errors.New("not allowed")
+		}
+
+		return nil
+	})
+
+	repository := ActionsRunnerRepository{
+		Owner:       "inloco",
+		Name:        "kube-actions",
+		APIEndpoint: "https://ghe.example.com/api/v3",
+	}
+	if err := validateRepository(repository); err != nil {
+		t.Error(`err != nil`)
+	}
+
+	repository.APIEndpoint = "https://evil.example.com/api/v3"
+	if err := validateRepository(repository); err == nil {
+		t.Error(`err == nil`)
+	}
+}
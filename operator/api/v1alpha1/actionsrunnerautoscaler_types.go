@@ -0,0 +1,96 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ActionsRunnerAutoscalerScaleTargetRef struct {
+	Name string `json:"name"` // ActionsRunnerReplicaSet in the same namespace
+}
+
+// ActionsRunnerAutoscalerSpec defines the desired state of ActionsRunnerAutoscaler
+type ActionsRunnerAutoscalerSpec struct {
+	ScaleTargetRef ActionsRunnerAutoscalerScaleTargetRef `json:"scaleTargetRef"`
+	Repositories   []string                              `json:"repositories,omitempty"` // "owner/name" to watch, defaults to the target repository
+
+	MinReplicas uint `json:"minReplicas,omitempty"` // 0 allows scaling to zero
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas uint `json:"maxReplicas"`
+
+	ScaleUpStabilizationWindow   *metav1.Duration `json:"scaleUpStabilizationWindow,omitempty"`
+	ScaleDownStabilizationWindow *metav1.Duration `json:"scaleDownStabilizationWindow,omitempty"`
+	PollInterval                 *metav1.Duration `json:"pollInterval,omitempty"`
+}
+
+type ActionsRunnerAutoscalerConditionType string
+
+const (
+	ActionsRunnerAutoscalerConditionScalingActive  ActionsRunnerAutoscalerConditionType = "ScalingActive"
+	ActionsRunnerAutoscalerConditionScalingLimited ActionsRunnerAutoscalerConditionType = "ScalingLimited"
+)
+
+// ActionsRunnerAutoscalerStatus defines the observed state of ActionsRunnerAutoscaler
+type ActionsRunnerAutoscalerStatus struct {
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	ObservedGeneration int64        `json:"observedGeneration,omitempty"`
+	QueuedJobs         uint         `json:"queuedJobs,omitempty"`
+	InProgressJobs     uint         `json:"inProgressJobs,omitempty"`
+	CurrentReplicas    uint         `json:"currentReplicas,omitempty"`
+	DesiredReplicas    uint         `json:"desiredReplicas,omitempty"`
+	LastScaleTime      *metav1.Time `json:"lastScaleTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories=actions,shortName=ara
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.scaleTargetRef.name`
+// +kubebuilder:printcolumn:name="Min",type=integer,JSONPath=`.spec.minReplicas`
+// +kubebuilder:printcolumn:name="Max",type=integer,JSONPath=`.spec.maxReplicas`
+// +kubebuilder:printcolumn:name="Queued",type=integer,JSONPath=`.status.queuedJobs`
+// +kubebuilder:printcolumn:name="In Progress",type=integer,JSONPath=`.status.inProgressJobs`
+// +kubebuilder:printcolumn:name="Current",type=integer,JSONPath=`.status.currentReplicas`
+// +kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.desiredReplicas`
+// +kubebuilder:printcolumn:name="Active",type=string,JSONPath=`.status.conditions[?(@.type=="ScalingActive")].status`,priority=1
+// +kubebuilder:printcolumn:name="Last Scale Time",type=date,JSONPath=`.status.lastScaleTime`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ActionsRunnerAutoscaler is the Schema for the actionsrunnerautoscalers API
+type ActionsRunnerAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ActionsRunnerAutoscalerSpec   `json:"spec,omitempty"`
+	Status ActionsRunnerAutoscalerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ActionsRunnerAutoscalerList contains a list of ActionsRunnerAutoscaler
+type ActionsRunnerAutoscalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ActionsRunnerAutoscaler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ActionsRunnerAutoscaler{}, &ActionsRunnerAutoscalerList{})
+}
@@ -21,18 +21,64 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+type ActionsRunnerJobPlan struct {
+	PlanId          string `json:"planId,omitempty"`
+	PlanType        string `json:"planType,omitempty"`
+	ScopeIdentifier string `json:"scopeIdentifier,omitempty"`
+}
+
 // ActionsRunnerJobSpec defines the desired state of ActionsRunnerJob
-type ActionsRunnerJobSpec struct{}
+type ActionsRunnerJobSpec struct {
+	JobId           string               `json:"jobId,omitempty"`
+	RequestId       uint64               `json:"requestId,omitempty"`
+	Plan            ActionsRunnerJobPlan `json:"plan,omitempty"`
+	TimelineId      string               `json:"timelineId,omitempty"`
+	OrchestrationId string               `json:"orchestrationId,omitempty"`
+
+	Workflow       string `json:"workflow,omitempty"`
+	JobName        string `json:"jobName,omitempty"`
+	JobDisplayName string `json:"jobDisplayName,omitempty"`
+	RunId          string `json:"runId,omitempty"`
+	RunAttempt     string `json:"runAttempt,omitempty"`
+	Ref            string `json:"ref,omitempty"`
+	Sha            string `json:"sha,omitempty"`
+	Actor          string `json:"actor,omitempty"`
+	Event          string `json:"event,omitempty"`
+
+	TimeoutMinutes uint `json:"timeoutMinutes,omitempty"` // timeout-minutes of the job, 360 unless set by the workflow
+}
+
+type ActionsRunnerJobConditionType string
+
+const (
+	ActionsRunnerJobConditionPodCreated ActionsRunnerJobConditionType = "PodCreated"
+)
 
 // ActionsRunnerJobStatus defines the observed state of ActionsRunnerJob
 type ActionsRunnerJobStatus struct {
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
 	PersistentVolumeClaimPhase corev1.PersistentVolumeClaimPhase `json:"persistentVolumeClaimPhase,omitempty"`
 	PodPhase                   corev1.PodPhase                   `json:"podPhase,omitempty"`
+	PodReason                  string                            `json:"podReason,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:categories=actions,shortName=arj
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Workflow",type=string,JSONPath=`.spec.workflow`
+// +kubebuilder:printcolumn:name="Job",type=string,JSONPath=`.spec.jobDisplayName`
+// +kubebuilder:printcolumn:name="Run",type=string,JSONPath=`.spec.runId`
+// +kubebuilder:printcolumn:name="Attempt",type=string,JSONPath=`.spec.runAttempt`,priority=1
+// +kubebuilder:printcolumn:name="Ref",type=string,JSONPath=`.spec.ref`
+// +kubebuilder:printcolumn:name="SHA",type=string,JSONPath=`.spec.sha`,priority=1
+// +kubebuilder:printcolumn:name="Actor",type=string,JSONPath=`.spec.actor`
+// +kubebuilder:printcolumn:name="Event",type=string,JSONPath=`.spec.event`,priority=1
+// +kubebuilder:printcolumn:name="Pod",type=string,JSONPath=`.status.podPhase`
+// +kubebuilder:printcolumn:name="Pod Created",type=string,JSONPath=`.status.conditions[?(@.type=="PodCreated")].status`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ActionsRunnerJob is the Schema for the actionsrunnerjobs API
 type ActionsRunnerJob struct {
@@ -0,0 +1,94 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=deny;warn;audit
+type ActionsRunnerPolicyEnforcementAction string
+
+const (
+	// ActionsRunnerPolicyEnforcementActionDeny fails the job
+	ActionsRunnerPolicyEnforcementActionDeny ActionsRunnerPolicyEnforcementAction = "deny"
+	// ActionsRunnerPolicyEnforcementActionWarn runs the job with a warning on its timeline
+	ActionsRunnerPolicyEnforcementActionWarn ActionsRunnerPolicyEnforcementAction = "warn"
+	// ActionsRunnerPolicyEnforcementActionAudit runs the job, only recording an Event and a metric
+	ActionsRunnerPolicyEnforcementActionAudit ActionsRunnerPolicyEnforcementAction = "audit"
+)
+
+type ActionsRunnerPolicyNamedRule struct {
+	Name                        string `json:"name"` // identifies the rule within the policy
+	ActionsRunnerPolicyRuleSpec `json:",inline"`
+}
+
+// ActionsRunnerPolicySpec defines the desired state of ActionsRunnerPolicy
+type ActionsRunnerPolicySpec struct {
+	EnforcementAction ActionsRunnerPolicyEnforcementAction `json:"enforcementAction,omitempty"` // defaults to deny
+
+	// +listType=map
+	// +listMapKey=name
+	Must []ActionsRunnerPolicyNamedRule `json:"must,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	MustNot []ActionsRunnerPolicyNamedRule `json:"mustNot,omitempty"`
+}
+
+type ActionsRunnerPolicyConditionType string
+
+const (
+	ActionsRunnerPolicyConditionValid ActionsRunnerPolicyConditionType = "Valid"
+)
+
+// ActionsRunnerPolicyStatus defines the observed state of ActionsRunnerPolicy
+type ActionsRunnerPolicyStatus struct {
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	ObservedGeneration int64    `json:"observedGeneration,omitempty"`
+	InvalidRules       []string `json:"invalidRules,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,categories=actions,shortName=arp
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.conditions[?(@.type=="Valid")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ActionsRunnerPolicy is the Schema for the actionsrunnerpolicies API
+type ActionsRunnerPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ActionsRunnerPolicySpec   `json:"spec,omitempty"`
+	Status ActionsRunnerPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ActionsRunnerPolicyList contains a list of ActionsRunnerPolicy
+type ActionsRunnerPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ActionsRunnerPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ActionsRunnerPolicy{}, &ActionsRunnerPolicyList{})
+}
@@ -18,18 +18,66 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// +kubebuilder:validation:Enum=Sunday;Monday;Tuesday;Wednesday;Thursday;Friday;Saturday
+type Weekday string
+
+// ActionsRunnerReplicaSetSchedule sets the replicas within a daily time window, windows ending before they start span midnight
+type ActionsRunnerReplicaSetSchedule struct {
+	Name string    `json:"name"`
+	Days []Weekday `json:"days,omitempty"` // days the window starts on, defaults to every day
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End      string `json:"end"`
+	TimeZone string `json:"timeZone,omitempty"` // IANA name, defaults to UTC
+	Replicas uint   `json:"replicas"`
+}
+
+// ActionsRunnerReplicaSetOverride sets the replicas regardless of schedules until it expires
+// ActionsRunnerReplicaSetActiveScheduleOverride is the ActiveSchedule while the override is in effect, so no schedule
+// may be named after it
+const ActionsRunnerReplicaSetActiveScheduleOverride = "override"
+
+type ActionsRunnerReplicaSetOverride struct {
+	Replicas uint        `json:"replicas"`
+	Until    metav1.Time `json:"until"`
+}
+
+// ActionsRunnerReplicaSetStrategy rolls out template changes, ActionsRunners whose repository changed are replaced once
+// idle while others are updated in place
+type ActionsRunnerReplicaSetStrategy struct {
+	MaxSurge       *intstr.IntOrString `json:"maxSurge,omitempty"`       // defaults to 25%
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"` // defaults to 25%
+}
+
 // ActionsRunnerReplicaSetSpec defines the desired state of ActionsRunnerReplicaSet
 type ActionsRunnerReplicaSetSpec struct {
-	Replicas uint              `json:"replicas,omitempty"`
-	Template ActionsRunnerSpec `json:"template,omitempty"`
+	Replicas  uint                              `json:"replicas,omitempty"`  // used outside of schedules, it is what ActionsRunnerAutoscalers set
+	Schedules []ActionsRunnerReplicaSetSchedule `json:"schedules,omitempty"` // the first active schedule wins, pausing ActionsRunnerAutoscalers
+	Override  *ActionsRunnerReplicaSetOverride  `json:"override,omitempty"`  // takes precedence over schedules
+	Template  ActionsRunnerSpec                 `json:"template,omitempty"`
+
+	Strategy       ActionsRunnerReplicaSetStrategy `json:"strategy,omitempty"`
+	ForceScaleDown bool                            `json:"forceScaleDown,omitempty"` // allows deleting ActionsRunners with running jobs
 }
 
 // ActionsRunnerReplicaSetStatus defines the observed state of ActionsRunnerReplicaSet
 type ActionsRunnerReplicaSetStatus struct {
-	Replicas uint   `json:"replicas,omitempty"`
-	Selector string `json:"selector,omitempty"`
+	Replicas        uint   `json:"replicas,omitempty"`
+	Selector        string `json:"selector,omitempty"`
+	DesiredReplicas uint   `json:"desiredReplicas,omitempty"`
+	ActiveSchedule  string `json:"activeSchedule,omitempty"` // name of the schedule in effect, "override" or empty
+
+	IdleReplicas        uint `json:"idleReplicas,omitempty"`
+	BusyReplicas        uint `json:"busyReplicas,omitempty"`
+	TerminatingReplicas uint `json:"terminatingReplicas,omitempty"`
+
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	UpdatedReplicas    uint  `json:"updatedReplicas,omitempty"`
+	AvailableReplicas  uint  `json:"availableReplicas,omitempty"`
 }
 
 // +kubebuilder:object:root=true
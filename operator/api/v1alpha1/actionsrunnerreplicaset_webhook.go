@@ -17,9 +17,14 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -35,6 +40,35 @@ func (r *ActionsRunnerReplicaSet) SetupWebhookWithManager(mgr ctrl.Manager) erro
 		Complete()
 }
 
+// actionsRunnerReplicaSetAutoscalers names the ActionsRunnerAutoscalers scaling an ActionsRunnerReplicaSet, it finds none
+// until the manager sets it up
+var actionsRunnerReplicaSetAutoscalers func(ctx context.Context, arrs *ActionsRunnerReplicaSet) ([]string, error)
+
+func SetActionsRunnerReplicaSetAutoscalers(lookup func(ctx context.Context, arrs *ActionsRunnerReplicaSet) ([]string, error)) {
+	actionsRunnerReplicaSetAutoscalers = lookup
+}
+
+//+kubebuilder:webhook:path=/mutate-inloco-com-br-v1alpha1-actionsrunnerreplicaset,mutating=true,failurePolicy=fail,sideEffects=None,groups=inloco.com.br,resources=actionsrunnerreplicasets,verbs=create;update,versions=v1alpha1,name=mactionsrunnerreplicaset.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ActionsRunnerReplicaSet{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (arrs *ActionsRunnerReplicaSet) Default() {
+	actionsrunnerreplicasetlog.Info("default", "name", arrs.Name)
+
+	if arrs.Spec.Strategy.MaxSurge == nil {
+		maxSurge := intstr.FromString("25%")
+		arrs.Spec.Strategy.MaxSurge = &maxSurge
+	}
+
+	if arrs.Spec.Strategy.MaxUnavailable == nil {
+		maxUnavailable := intstr.FromString("25%")
+		arrs.Spec.Strategy.MaxUnavailable = &maxUnavailable
+	}
+
+	DefaultActionsRunnerSpec(&arrs.Spec.Template)
+}
+
 //+kubebuilder:webhook:path=/validate-inloco-com-br-v1alpha1-actionsrunnerreplicaset,mutating=false,failurePolicy=fail,sideEffects=None,groups=inloco.com.br,resources=actionsrunnerreplicasets,verbs=create;update,versions=v1alpha1,name=vactionsrunnerreplicaset.kb.io,admissionReviewVersions=v1
 
 var _ webhook.Validator = &ActionsRunnerReplicaSet{}
@@ -43,10 +77,20 @@ var _ webhook.Validator = &ActionsRunnerReplicaSet{}
 func (arrs *ActionsRunnerReplicaSet) ValidateCreate() (warnings admission.Warnings, err error) {
 	actionsrunnerreplicasetlog.Info("validate create", "name", arrs.Name)
 
-	ar := &ActionsRunner{
-		Spec: arrs.Spec.Template,
+	if err := validateSchedules(arrs.Spec.Schedules); err != nil {
+		return nil, err
+	}
+
+	if err := validateStrategy(arrs.Spec.Strategy); err != nil {
+		return nil, err
 	}
-	return ar.ValidateCreate()
+
+	if err := validateRepository(arrs.Spec.Template.Repository); err != nil {
+		return nil, err
+	}
+
+	warnings, err = validateSpec(field.NewPath("spec", "template"), &arrs.Spec.Template, nil)
+	return append(warnings, scheduleWarnings(arrs)...), err
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -58,13 +102,45 @@ func (arrs *ActionsRunnerReplicaSet) ValidateUpdate(old runtime.Object) (admissi
 		return nil, errors.New("old.(*ActionsRunnerReplicaSet) == nil")
 	}
 
-	ar := &ActionsRunner{
-		Spec: arrs.Spec.Template,
+	if err := validateSchedules(arrs.Spec.Schedules); err != nil {
+		return nil, err
+	}
+
+	if err := validateStrategy(arrs.Spec.Strategy); err != nil {
+		return nil, err
 	}
-	oldAR := &ActionsRunner{
-		Spec: oldARRS.Spec.Template,
+
+	// immutable fields of the template are rolled out by replacing ActionsRunners
+	if err := validateRepository(arrs.Spec.Template.Repository); err != nil {
+		return nil, err
 	}
-	return ar.ValidateUpdate(oldAR)
+
+	warnings, err := validateSpec(field.NewPath("spec", "template"), &arrs.Spec.Template, &oldARRS.Spec.Template)
+	return append(warnings, scheduleWarnings(arrs)...), err
+}
+
+// scheduleWarnings tells ActionsRunnerAutoscalers pause while a schedule or override sets the replicas
+func scheduleWarnings(arrs *ActionsRunnerReplicaSet) admission.Warnings {
+	if len(arrs.Spec.Schedules) == 0 && arrs.Spec.Override == nil {
+		return nil
+	}
+
+	if actionsRunnerReplicaSetAutoscalers == nil {
+		return nil
+	}
+
+	autoscalers, err := actionsRunnerReplicaSetAutoscalers(context.Background(), arrs)
+	if err != nil {
+		actionsrunnerreplicasetlog.Error(err, "Failed to list ActionsRunnerAutoscalers", "name", arrs.Name)
+		return nil
+	}
+
+	var warnings admission.Warnings
+	for _, autoscaler := range autoscalers {
+		warnings = append(warnings, fmt.Sprintf("ActionsRunnerAutoscaler %q scales this ActionsRunnerReplicaSet, it is paused while a schedule or override is active", autoscaler))
+	}
+
+	return warnings
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -76,3 +152,52 @@ func (arrs *ActionsRunnerReplicaSet) ValidateDelete() (admission.Warnings, error
 	}
 	return ar.ValidateDelete()
 }
+
+func validateSchedules(schedules []ActionsRunnerReplicaSetSchedule) error {
+	names := make(map[string]struct{}, len(schedules))
+
+	for i, schedule := range schedules {
+		if schedule.Name == "" {
+			return fmt.Errorf(".Spec.Schedules[%d].Name is required", i)
+		}
+
+		if schedule.Name == ActionsRunnerReplicaSetActiveScheduleOverride {
+			return fmt.Errorf(".Spec.Schedules[%d].Name %q is reserved for .Spec.Override", i, schedule.Name)
+		}
+
+		if _, ok := names[schedule.Name]; ok {
+			return fmt.Errorf(".Spec.Schedules[%d].Name %q is duplicated", i, schedule.Name)
+		}
+		names[schedule.Name] = struct{}{}
+
+		if _, err := time.Parse("15:04", schedule.Start); err != nil {
+			return fmt.Errorf("unable to parse .Spec.Schedules[%d].Start: %w", i, err)
+		}
+
+		if _, err := time.Parse("15:04", schedule.End); err != nil {
+			return fmt.Errorf("unable to parse .Spec.Schedules[%d].End: %w", i, err)
+		}
+
+		if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+			return fmt.Errorf("unable to load .Spec.Schedules[%d].TimeZone: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func validateStrategy(strategy ActionsRunnerReplicaSetStrategy) error {
+	if maxSurge := strategy.MaxSurge; maxSurge != nil {
+		if _, err := intstr.GetScaledValueFromIntOrPercent(maxSurge, 100, true); err != nil {
+			return fmt.Errorf("invalid .Spec.Strategy.MaxSurge: %w", err)
+		}
+	}
+
+	if maxUnavailable := strategy.MaxUnavailable; maxUnavailable != nil {
+		if _, err := intstr.GetScaledValueFromIntOrPercent(maxUnavailable, 100, false); err != nil {
+			return fmt.Errorf("invalid .Spec.Strategy.MaxUnavailable: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScheduleWarnings(t *testing.T) {
+	defer SetActionsRunnerReplicaSetAutoscalers(nil)
+	SetActionsRunnerReplicaSetAutoscalers(func(ctx context.Context, arrs *ActionsRunnerReplicaSet) ([]string, error) {
+		return []string{"autoscaler"}, nil
+	})
+
+	arrs := &ActionsRunnerReplicaSet{}
+	if len(scheduleWarnings(arrs)) != 0 {
+		t.Error(`len(scheduleWarnings(arrs)) != 0`)
+	}
+
+	arrs.Spec.Schedules = []ActionsRunnerReplicaSetSchedule{{Name: "office-hours", Start: "09:00", End: "18:00"}}
+	if len(scheduleWarnings(arrs)) != 1 {
+		t.Error(`len(scheduleWarnings(arrs)) != 1`)
+	}
+}
+
+func TestValidateSchedulesRejectsOverride(t *testing.T) {
+	schedules := []ActionsRunnerReplicaSetSchedule{{Name: "office-hours", Start: "09:00", End: "18:00"}}
+	if err := validateSchedules(schedules); err != nil {
+		t.Error(`err := validateSchedules(schedules); err != nil`)
+	}
+
+	schedules = append(schedules, ActionsRunnerReplicaSetSchedule{Name: ActionsRunnerReplicaSetActiveScheduleOverride, Start: "18:00", End: "09:00"})
+	if err := validateSchedules(schedules); err == nil {
+		t.Error(`err := validateSchedules(schedules); err == nil`)
+	}
+}
@@ -23,7 +23,9 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -32,7 +34,7 @@ func (in *ActionsRunner) DeepCopyInto(out *ActionsRunner) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunner.
@@ -53,13 +55,149 @@ func (in *ActionsRunner) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerAutoscaler) DeepCopyInto(out *ActionsRunnerAutoscaler) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerAutoscaler.
+func (in *ActionsRunnerAutoscaler) DeepCopy() *ActionsRunnerAutoscaler {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerAutoscaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ActionsRunnerAutoscaler) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerAutoscalerList) DeepCopyInto(out *ActionsRunnerAutoscalerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ActionsRunnerAutoscaler, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerAutoscalerList.
+func (in *ActionsRunnerAutoscalerList) DeepCopy() *ActionsRunnerAutoscalerList {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerAutoscalerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ActionsRunnerAutoscalerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerAutoscalerScaleTargetRef) DeepCopyInto(out *ActionsRunnerAutoscalerScaleTargetRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerAutoscalerScaleTargetRef.
+func (in *ActionsRunnerAutoscalerScaleTargetRef) DeepCopy() *ActionsRunnerAutoscalerScaleTargetRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerAutoscalerScaleTargetRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerAutoscalerSpec) DeepCopyInto(out *ActionsRunnerAutoscalerSpec) {
+	*out = *in
+	out.ScaleTargetRef = in.ScaleTargetRef
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScaleUpStabilizationWindow != nil {
+		in, out := &in.ScaleUpStabilizationWindow, &out.ScaleUpStabilizationWindow
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ScaleDownStabilizationWindow != nil {
+		in, out := &in.ScaleDownStabilizationWindow, &out.ScaleDownStabilizationWindow
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerAutoscalerSpec.
+func (in *ActionsRunnerAutoscalerSpec) DeepCopy() *ActionsRunnerAutoscalerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerAutoscalerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerAutoscalerStatus) DeepCopyInto(out *ActionsRunnerAutoscalerStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerAutoscalerStatus.
+func (in *ActionsRunnerAutoscalerStatus) DeepCopy() *ActionsRunnerAutoscalerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerAutoscalerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActionsRunnerJob) DeepCopyInto(out *ActionsRunnerJob) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerJob.
@@ -112,9 +250,25 @@ func (in *ActionsRunnerJobList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerJobPlan) DeepCopyInto(out *ActionsRunnerJobPlan) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerJobPlan.
+func (in *ActionsRunnerJobPlan) DeepCopy() *ActionsRunnerJobPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerJobPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActionsRunnerJobSpec) DeepCopyInto(out *ActionsRunnerJobSpec) {
 	*out = *in
+	out.Plan = in.Plan
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerJobSpec.
@@ -130,6 +284,13 @@ func (in *ActionsRunnerJobSpec) DeepCopy() *ActionsRunnerJobSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActionsRunnerJobStatus) DeepCopyInto(out *ActionsRunnerJobStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerJobStatus.
@@ -176,25 +337,198 @@ func (in *ActionsRunnerList) DeepCopyObject() runtime.Object {
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActionsRunnerPolicy) DeepCopyInto(out *ActionsRunnerPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicy.
+func (in *ActionsRunnerPolicy) DeepCopy() *ActionsRunnerPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ActionsRunnerPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerPolicyInlineRule) DeepCopyInto(out *ActionsRunnerPolicyInlineRule) {
+	*out = *in
+	out.ActionsRunnerPolicyRuleSpec = in.ActionsRunnerPolicyRuleSpec
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicyInlineRule.
+func (in *ActionsRunnerPolicyInlineRule) DeepCopy() *ActionsRunnerPolicyInlineRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerPolicyInlineRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerPolicyList) DeepCopyInto(out *ActionsRunnerPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ActionsRunnerPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicyList.
+func (in *ActionsRunnerPolicyList) DeepCopy() *ActionsRunnerPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ActionsRunnerPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerPolicyNamedRule) DeepCopyInto(out *ActionsRunnerPolicyNamedRule) {
+	*out = *in
+	out.ActionsRunnerPolicyRuleSpec = in.ActionsRunnerPolicyRuleSpec
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicyNamedRule.
+func (in *ActionsRunnerPolicyNamedRule) DeepCopy() *ActionsRunnerPolicyNamedRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerPolicyNamedRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerPolicyReference) DeepCopyInto(out *ActionsRunnerPolicyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicyReference.
+func (in *ActionsRunnerPolicyReference) DeepCopy() *ActionsRunnerPolicyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerPolicyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerPolicyRuleSpec) DeepCopyInto(out *ActionsRunnerPolicyRuleSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicyRuleSpec.
+func (in *ActionsRunnerPolicyRuleSpec) DeepCopy() *ActionsRunnerPolicyRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerPolicyRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerPolicyRules) DeepCopyInto(out *ActionsRunnerPolicyRules) {
 	*out = *in
 	if in.Must != nil {
 		in, out := &in.Must, &out.Must
-		*out = make([]ActionsRunnerPolicyRule, len(*in))
+		*out = make([]ActionsRunnerPolicyInlineRule, len(*in))
 		copy(*out, *in)
 	}
 	if in.MustNot != nil {
 		in, out := &in.MustNot, &out.MustNot
-		*out = make([]ActionsRunnerPolicyRule, len(*in))
+		*out = make([]ActionsRunnerPolicyInlineRule, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicy.
-func (in *ActionsRunnerPolicy) DeepCopy() *ActionsRunnerPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicyRules.
+func (in *ActionsRunnerPolicyRules) DeepCopy() *ActionsRunnerPolicyRules {
 	if in == nil {
 		return nil
 	}
-	out := new(ActionsRunnerPolicy)
+	out := new(ActionsRunnerPolicyRules)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerPolicySpec) DeepCopyInto(out *ActionsRunnerPolicySpec) {
+	*out = *in
+	if in.Must != nil {
+		in, out := &in.Must, &out.Must
+		*out = make([]ActionsRunnerPolicyNamedRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.MustNot != nil {
+		in, out := &in.MustNot, &out.MustNot
+		*out = make([]ActionsRunnerPolicyNamedRule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicySpec.
+func (in *ActionsRunnerPolicySpec) DeepCopy() *ActionsRunnerPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerPolicyStatus) DeepCopyInto(out *ActionsRunnerPolicyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InvalidRules != nil {
+		in, out := &in.InvalidRules, &out.InvalidRules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerPolicyStatus.
+func (in *ActionsRunnerPolicyStatus) DeepCopy() *ActionsRunnerPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerPolicyStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -258,10 +592,59 @@ func (in *ActionsRunnerReplicaSetList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerReplicaSetOverride) DeepCopyInto(out *ActionsRunnerReplicaSetOverride) {
+	*out = *in
+	in.Until.DeepCopyInto(&out.Until)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerReplicaSetOverride.
+func (in *ActionsRunnerReplicaSetOverride) DeepCopy() *ActionsRunnerReplicaSetOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerReplicaSetOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerReplicaSetSchedule) DeepCopyInto(out *ActionsRunnerReplicaSetSchedule) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerReplicaSetSchedule.
+func (in *ActionsRunnerReplicaSetSchedule) DeepCopy() *ActionsRunnerReplicaSetSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerReplicaSetSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActionsRunnerReplicaSetSpec) DeepCopyInto(out *ActionsRunnerReplicaSetSpec) {
 	*out = *in
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]ActionsRunnerReplicaSetSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Override != nil {
+		in, out := &in.Override, &out.Override
+		*out = new(ActionsRunnerReplicaSetOverride)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Template.DeepCopyInto(&out.Template)
+	in.Strategy.DeepCopyInto(&out.Strategy)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerReplicaSetSpec.
@@ -289,6 +672,31 @@ func (in *ActionsRunnerReplicaSetStatus) DeepCopy() *ActionsRunnerReplicaSetStat
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionsRunnerReplicaSetStrategy) DeepCopyInto(out *ActionsRunnerReplicaSetStrategy) {
+	*out = *in
+	if in.MaxSurge != nil {
+		in, out := &in.MaxSurge, &out.MaxSurge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerReplicaSetStrategy.
+func (in *ActionsRunnerReplicaSetStrategy) DeepCopy() *ActionsRunnerReplicaSetStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionsRunnerReplicaSetStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActionsRunnerRepository) DeepCopyInto(out *ActionsRunnerRepository) {
 	*out = *in
@@ -309,6 +717,11 @@ func (in *ActionsRunnerSpec) DeepCopyInto(out *ActionsRunnerSpec) {
 	*out = *in
 	out.Repository = in.Repository
 	in.Policy.DeepCopyInto(&out.Policy)
+	if in.PolicyRefs != nil {
+		in, out := &in.PolicyRefs, &out.PolicyRefs
+		*out = make([]ActionsRunnerPolicyReference, len(*in))
+		copy(*out, *in)
+	}
 	if in.Capabilities != nil {
 		in, out := &in.Capabilities, &out.Capabilities
 		*out = make([]ActionsRunnerCapability, len(*in))
@@ -380,6 +793,16 @@ func (in *ActionsRunnerSpec) DeepCopyInto(out *ActionsRunnerSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.MaxJobDuration != nil {
+		in, out := &in.MaxJobDuration, &out.MaxJobDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerSpec.
@@ -395,6 +818,22 @@ func (in *ActionsRunnerSpec) DeepCopy() *ActionsRunnerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActionsRunnerStatus) DeepCopyInto(out *ActionsRunnerStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastMessageTime != nil {
+		in, out := &in.LastMessageTime, &out.LastMessageTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionsRunnerStatus.
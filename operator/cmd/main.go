@@ -36,8 +36,14 @@ import (
 
 	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
 	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/util"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/wire"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunnerautoscaler"
 	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunnerjob"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunnerpolicy"
 	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunnerreplicaset"
+	"github.com/inloco/kube-actions/operator/internal/controller/runnergc"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -69,6 +75,13 @@ func main() {
 		"The address the metric endpoint binds to.",
 	)
 
+	var messageCaptureBindAddress string
+	flag.StringVar(&messageCaptureBindAddress,
+		"message-capture-bind-address",
+		"127.0.0.1:6061",
+		"The address captured job messages are served on, keep it on localhost and reach it with kubectl port-forward. Set this to \"0\" to disable it.",
+	)
+
 	var healthProbeBindAddress string
 	flag.StringVar(&healthProbeBindAddress,
 		"health-probe-bind-address",
@@ -100,6 +113,46 @@ func main() {
 		"Name of the configmap that is used for holding the leader lock.",
 	)
 
+	var runnerGCInterval time.Duration
+	flag.DurationVar(
+		&runnerGCInterval,
+		"runner-gc-interval",
+		10*time.Minute,
+		"How often orphaned GitHub runners are looked for, 0 disables the runner garbage collector.",
+	)
+
+	var runnerGCGracePeriod time.Duration
+	flag.DurationVar(
+		&runnerGCGracePeriod,
+		"runner-gc-grace-period",
+		time.Hour,
+		"How long a runner must stay orphaned before it is removed.",
+	)
+
+	var runnerGCDryRun bool
+	flag.BoolVar(
+		&runnerGCDryRun,
+		"runner-gc-dry-run",
+		false,
+		"Only log and count the orphaned runners that would be removed.",
+	)
+
+	var runnerGCTargets string
+	flag.StringVar(
+		&runnerGCTargets,
+		"runner-gc-targets",
+		"",
+		"Comma separated runner targets, like owner/name, owner or enterprises/owner, swept even without ActionsRunners.",
+	)
+
+	var deregistrationTimeout time.Duration
+	flag.DurationVar(
+		&deregistrationTimeout,
+		"deregistration-timeout",
+		0,
+		"How long the deregistration of a deleted ActionsRunner is retried before its finalizer is removed anyway, 0 retries until it succeeds.",
+	)
+
 	opts := zap.Options{
 		Development: true,
 	}
@@ -128,6 +181,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := util.ParseRunnerScheduling(); err != nil {
+		setupLog.Error(err, "unable to parse runner scheduling")
+		os.Exit(1)
+	}
+
+	actionsRunnerSpecDefaulter, err := util.NewActionsRunnerSpecDefaulter()
+	if err != nil {
+		setupLog.Error(err, "unable to parse ActionsRunner defaults")
+		os.Exit(1)
+	}
+	inlocov1alpha1.SetActionsRunnerSpecDefaulter(actionsRunnerSpecDefaulter)
+	inlocov1alpha1.SetGitHubAPIEndpointValidator(facades.ValidateGitHubAPIEndpoint)
+	inlocov1alpha1.SetActionsRunnerReplicaSetAutoscalers(actionsrunnerautoscaler.NewScaleTargetLookup(mgr.GetAPIReader()))
+
 	var arrs inlocov1alpha1.ActionsRunnerReplicaSet
 	if err := arrs.SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "ActionsRunnerReplicaSet")
@@ -144,6 +211,7 @@ func main() {
 		Client:                  mgr.GetClient(),
 		Log:                     mgr.GetLogger(),
 		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("kube-actions"),
 		MaxConcurrentReconciles: maxConcurrentReconciles,
 	}
 	if err := arrsReconciler.SetupWithManager(mgr); err != nil {
@@ -151,11 +219,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	// served apart from pprof, captured messages hold job details and are kept only for ActionsRunners that opt in
+	messageCapture := wire.NewMessageCapture(mgr.GetClient())
+	if messageCaptureBindAddress != "0" {
+		messageCaptureMux := http.NewServeMux()
+		messageCaptureMux.Handle("/debug/kube-actions/messages/", messageCapture)
+
+		go func() {
+			if err := http.ListenAndServe(messageCaptureBindAddress, messageCaptureMux); err != nil {
+				setupLog.Error(err, "unable to serve captured messages")
+			}
+		}()
+	}
+
 	arReconciler := actionsrunner.Reconciler{
 		Client:                  mgr.GetClient(),
 		Log:                     mgr.GetLogger(),
 		Scheme:                  mgr.GetScheme(),
 		MaxConcurrentReconciles: maxConcurrentReconciles,
+		Recorder:                mgr.GetEventRecorderFor("kube-actions"),
+		MessageCapture:          messageCapture,
+		DeregistrationTimeout:   deregistrationTimeout,
 	}
 	if err := arReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "actionsRunner")
@@ -166,12 +250,54 @@ func main() {
 		Client:                  mgr.GetClient(),
 		Scheme:                  mgr.GetScheme(),
 		MaxConcurrentReconciles: maxConcurrentReconciles,
+		Recorder:                mgr.GetEventRecorderFor("kube-actions"),
 	}
 	if err := arjReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ActionsRunnerJob")
 		os.Exit(1)
 	}
 
+	araReconciler := actionsrunnerautoscaler.Reconciler{
+		Client:                  mgr.GetClient(),
+		Log:                     mgr.GetLogger(),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+	}
+	if err := araReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ActionsRunnerAutoscaler")
+		os.Exit(1)
+	}
+
+	arpReconciler := actionsrunnerpolicy.Reconciler{
+		Client:                  mgr.GetClient(),
+		Log:                     mgr.GetLogger(),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+	}
+	if err := arpReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ActionsRunnerPolicy")
+		os.Exit(1)
+	}
+
+	runnerGCRepositories, err := runnergc.ParseTargets(runnerGCTargets)
+	if err != nil {
+		setupLog.Error(err, "unable to parse runner garbage collector targets")
+		os.Exit(1)
+	}
+
+	runnerGC := runnergc.Collector{
+		Client:      mgr.GetClient(),
+		Log:         mgr.GetLogger(),
+		Interval:    runnerGCInterval,
+		GracePeriod: runnerGCGracePeriod,
+		DryRun:      runnerGCDryRun,
+		Targets:     runnerGCRepositories,
+	}
+	if err := runnerGC.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create runner garbage collector")
+		os.Exit(1)
+	}
+
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,219 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apimachineryruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/wire"
+)
+
+var scheme = apimachineryruntime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(inlocov1alpha1.AddToScheme(scheme))
+}
+
+// environment stands for the cluster, policies and lists are read from the manifests through a fake client
+type environment struct {
+	actionsRunners []*inlocov1alpha1.ActionsRunner
+	validator      *wire.PolicyValidator
+}
+
+func loadEnvironment(paths []string) (*environment, error) {
+	var objects []client.Object
+	for _, path := range paths {
+		objs, err := loadManifest(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		objects = append(objects, objs...)
+	}
+
+	var (
+		actionsRunners []*inlocov1alpha1.ActionsRunner
+		policyRefs     []inlocov1alpha1.ActionsRunnerPolicyReference
+		namespaces     = make(map[string]bool)
+	)
+	for _, object := range objects {
+		switch o := object.(type) {
+		case *inlocov1alpha1.ActionsRunner:
+			actionsRunners = append(actionsRunners, o)
+
+		case *inlocov1alpha1.ActionsRunnerReplicaSet:
+			actionsRunners = append(actionsRunners, &inlocov1alpha1.ActionsRunner{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: o.GetNamespace(),
+					Name:      o.GetName(),
+				},
+				Spec: o.Spec.Template,
+			})
+
+		case *inlocov1alpha1.ActionsRunnerPolicy:
+			policyRefs = append(policyRefs, inlocov1alpha1.ActionsRunnerPolicyReference{Name: o.GetName()})
+
+		case *corev1.Namespace:
+			namespaces[o.GetName()] = true
+		}
+	}
+
+	if len(actionsRunners) == 0 {
+		actionsRunners = append(actionsRunners, &inlocov1alpha1.ActionsRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: metav1.NamespaceDefault,
+				Name:      "policytest",
+			},
+			Spec: inlocov1alpha1.ActionsRunnerSpec{
+				PolicyRefs: policyRefs,
+			},
+		})
+	}
+
+	// runners without policyRefs read the default policies of their namespace
+	for _, actionsRunner := range actionsRunners {
+		if actionsRunner.GetNamespace() == "" {
+			actionsRunner.SetNamespace(metav1.NamespaceDefault)
+		}
+
+		if namespace := actionsRunner.GetNamespace(); !namespaces[namespace] {
+			objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+			namespaces[namespace] = true
+		}
+	}
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	return &environment{
+		actionsRunners: actionsRunners,
+		validator:      wire.NewPolicyValidator(reader),
+	}, nil
+}
+
+func loadManifest(path string) ([]client.Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	deserializer := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	reader := yaml.NewYAMLReader(bufio.NewReader(f))
+
+	var objects []client.Object
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var meta metav1.TypeMeta
+		if err := yaml.Unmarshal(doc, &meta); err != nil {
+			return nil, err
+		}
+		if meta.Kind == "" {
+			continue
+		}
+
+		obj, _, err := deserializer.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode %s: %w", meta.Kind, err)
+		}
+
+		object, ok := obj.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object", meta.Kind)
+		}
+
+		objects = append(objects, object)
+	}
+}
+
+// loadJobRequest accepts contextData alone, like the fixtures of the wire package
+func loadJobRequest(path string) (*wire.PipelineAgentJobRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	var pajr wire.PipelineAgentJobRequest
+	if _, ok := fields["contextData"]; ok {
+		if err := json.Unmarshal(data, &pajr); err != nil {
+			return nil, err
+		}
+
+		return &pajr, nil
+	}
+
+	var contextData map[string]wire.PipelineContextData
+	if err := json.Unmarshal(data, &contextData); err != nil {
+		return nil, err
+	}
+	pajr.ContextData = &contextData
+
+	return &pajr, nil
+}
+
+// loadCapturedMessages accepts what the debug server returns or a single message taken out of it
+func loadCapturedMessages(path string) ([]wire.CapturedMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var messages []wire.CapturedMessage
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, err
+		}
+
+		return messages, nil
+	}
+
+	var message wire.CapturedMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, err
+	}
+
+	return []wire.CapturedMessage{message}, nil
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// policytest evaluates the policies of ActionsRunners against captured job requests without a cluster:
+//
+//	policytest -f runner.yaml -f policies.yaml job.json...
+//	policytest -t policies_test.yaml...
+//	policytest -replay -f runner.yaml messages.json...
+//
+// Manifests may hold ActionsRunners, ActionsRunnerReplicaSets, ActionsRunnerPolicies, Namespaces and the ConfigMap of
+// KUBEACTIONS_POLICY_LISTS. Without runners, the job requests are checked against every policy. Job requests are the
+// JSON of a PipelineAgentJobRequest or of its contextData alone, replayed messages are the ones captured by the operator
+// at /debug/kube-actions/messages/<namespace>/<name>, e.g. through kubectl port-forward to 6061.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/wire"
+)
+
+type stringsFlag []string
+
+func (sf *stringsFlag) String() string {
+	return strings.Join(*sf, ",")
+}
+
+func (sf *stringsFlag) Set(value string) error {
+	*sf = append(*sf, value)
+	return nil
+}
+
+func main() {
+	var manifests stringsFlag
+	flag.Var(&manifests,
+		"f",
+		"Manifest of the ActionsRunners and policies to evaluate, may be repeated.",
+	)
+
+	var testFiles stringsFlag
+	flag.Var(&testFiles,
+		"t",
+		"Policy test file to run instead of explaining job requests, may be repeated.",
+	)
+
+	var showRequest bool
+	flag.BoolVar(&showRequest,
+		"request",
+		false,
+		"Also print the normalized job request rules see as $request.",
+	)
+
+	var replay bool
+	flag.BoolVar(&replay,
+		"replay",
+		false,
+		"Replay captured job messages through the listener conversions and policies instead of explaining job requests.",
+	)
+
+	flag.Parse()
+
+	if len(testFiles) > 0 {
+		failed := false
+		for _, testFile := range testFiles {
+			ok, err := runTestFile(context.Background(), os.Stdout, testFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", testFile, err)
+				os.Exit(2)
+			}
+
+			failed = failed || !ok
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	env, err := loadEnvironment(manifests)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if replay {
+		for _, path := range flag.Args() {
+			if err := replayMessages(context.Background(), os.Stdout, env, path); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+				os.Exit(2)
+			}
+		}
+
+		return
+	}
+
+	for _, path := range flag.Args() {
+		pajr, err := loadJobRequest(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			os.Exit(2)
+		}
+
+		for _, actionsRunner := range env.actionsRunners {
+			id := actionsRunner.GetNamespace() + "/" + actionsRunner.GetName()
+
+			violations, err := env.validator.Validate(context.Background(), actionsRunner, pajr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s: %v\n", path, id, err)
+				os.Exit(2)
+			}
+
+			evaluation, err := env.validator.Evaluate(context.Background(), actionsRunner, pajr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s: %v\n", path, id, err)
+				os.Exit(2)
+			}
+
+			fmt.Printf("%s: %s: %s\n\n", path, id, verdict(violations))
+			printEvaluation(os.Stdout, evaluation, showRequest)
+		}
+	}
+}
+
+// verdict mirrors what the Wire does with the violations of a job
+func verdict(violations []*wire.PolicyViolation) string {
+	if denied := wire.DeniedBy(violations); denied != nil {
+		return "denied by " + denied.String()
+	}
+
+	if len(violations) > 0 {
+		return fmt.Sprintf("allowed with %d violations", len(violations))
+	}
+
+	return "allowed"
+}
+
+func replayMessages(ctx context.Context, w io.Writer, env *environment, path string) error {
+	messages, err := loadCapturedMessages(path)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		for _, actionsRunner := range env.actionsRunners {
+			fmt.Fprintf(w, "%s: message %d: %s/%s: ", path, message.Id, actionsRunner.GetNamespace(), actionsRunner.GetName())
+
+			outcome, err := wire.Replay(ctx, env.validator, actionsRunner, message)
+			switch {
+			case err != nil:
+				fmt.Fprintf(w, "unable to handle message: %v\n", err)
+
+			case outcome.PolicyError != nil:
+				fmt.Fprintf(w, "failed, unable to evaluate policies: %v\n", outcome.PolicyError)
+
+			case outcome.Spec == nil:
+				fmt.Fprintln(w, verdict(outcome.Violations))
+
+			default:
+				fmt.Fprintf(w, "%s, job %q of workflow %q\n", verdict(outcome.Violations), outcome.Spec.JobDisplayName, outcome.Spec.Workflow)
+			}
+
+			if message.Error != "" {
+				fmt.Fprintf(w, "\tcaptured with error: %s\n", message.Error)
+			}
+		}
+	}
+
+	return nil
+}
+
+func printEvaluation(w io.Writer, evaluation *wire.PolicyEvaluation, showRequest bool) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tKIND\tACTION\tDECISION\tOUTPUT")
+	for _, rule := range evaluation.Rules {
+		kind := "must"
+		if rule.MustNot {
+			kind = "mustNot"
+		}
+
+		decision := "ok"
+		if rule.Violation != nil {
+			decision = "violated"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", wire.RuleID(rule.Policy, rule.Name), kind, rule.Action, decision, output(rule.Output))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\ncontext:\n%s\n", indent(evaluation.ContextData))
+	if showRequest {
+		fmt.Fprintf(w, "\n$request:\n%s\n", indent(evaluation.Request))
+	}
+	fmt.Fprintln(w)
+}
+
+// output tells errors apart from strings, which are quoted
+func output(v interface{}) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(data)
+}
+
+func indent(v interface{}) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	utilruntime.Must(err)
+
+	return string(data)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/wire"
+)
+
+// testFile keeps policy regression tests next to the manifests, paths are relative to the file:
+//
+//	manifests:
+//	- runner.yaml
+//	- policies.yaml
+//	tests:
+//	- name: pushes to master run
+//	  request: jobs/push.json
+//	  allowed: true
+//	- name: pull requests from forks do not
+//	  request: jobs/fork.json
+//	  runner: ci/runner
+//	  allowed: false
+//	  violations: [no-forks/is-fork]
+//	- name: incident with an unexpected container token
+//	  message: incidents/container.json
+//	  error: unable to parse
+type testFile struct {
+	Manifests []string     `json:"manifests"`
+	Tests     []policyTest `json:"tests"`
+}
+
+type policyTest struct {
+	Name       string    `json:"name"`
+	Request    string    `json:"request,omitempty"`
+	Message    string    `json:"message,omitempty"`    // a single message captured by the operator, replayed instead of request
+	Runner     string    `json:"runner,omitempty"`     // namespace/name, required when the manifests hold more than one
+	Allowed    *bool     `json:"allowed,omitempty"`    // whether no rule denies the job
+	Violations *[]string `json:"violations,omitempty"` // ids of the violated rules, up to the one denying the job
+	Error      string    `json:"error,omitempty"`      // part of the error handling the job is expected to fail with
+}
+
+// runTestFile tells whether every test of the file passed, errors are reserved for files that can't be run
+func runTestFile(ctx context.Context, w io.Writer, path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	var tf testFile
+	if err := yaml.UnmarshalStrict(data, &tf); err != nil {
+		return false, err
+	}
+
+	dir := filepath.Dir(path)
+
+	manifests := make([]string, 0, len(tf.Manifests))
+	for _, manifest := range tf.Manifests {
+		manifests = append(manifests, filepath.Join(dir, manifest))
+	}
+
+	env, err := loadEnvironment(manifests)
+	if err != nil {
+		return false, err
+	}
+
+	passed := true
+	for _, test := range tf.Tests {
+		if err := runTest(ctx, env, dir, test); err != nil {
+			fmt.Fprintf(w, "FAIL\t%s\t%s: %v\n", path, test.Name, err)
+			passed = false
+			continue
+		}
+
+		fmt.Fprintf(w, "PASS\t%s\t%s\n", path, test.Name)
+	}
+
+	return passed, nil
+}
+
+func runTest(ctx context.Context, env *environment, dir string, test policyTest) error {
+	actionsRunner, err := env.actionsRunner(test.Runner)
+	if err != nil {
+		return err
+	}
+
+	var (
+		violations []*wire.PolicyViolation
+		testErr    error
+	)
+	switch {
+	case test.Message != "":
+		messages, err := loadCapturedMessages(filepath.Join(dir, test.Message))
+		if err != nil {
+			return err
+		}
+		if len(messages) != 1 {
+			return fmt.Errorf("%s holds %d messages instead of one", test.Message, len(messages))
+		}
+
+		outcome, err := wire.Replay(ctx, env.validator, actionsRunner, messages[0])
+		switch {
+		case err != nil:
+			testErr = err
+
+		case outcome.PolicyError != nil:
+			testErr = outcome.PolicyError
+
+		default:
+			violations = outcome.Violations
+		}
+
+	case test.Request != "":
+		pajr, err := loadJobRequest(filepath.Join(dir, test.Request))
+		if err != nil {
+			return err
+		}
+
+		violations, testErr = env.validator.Validate(ctx, actionsRunner, pajr)
+
+	default:
+		return errors.New("request or message is required")
+	}
+
+	if test.Error != "" {
+		if testErr == nil || !strings.Contains(testErr.Error(), test.Error) {
+			return fmt.Errorf("expected an error containing %q, got %v", test.Error, testErr)
+		}
+
+		return nil
+	}
+
+	if testErr != nil {
+		return testErr
+	}
+
+	deniedBy := wire.DeniedBy(violations)
+	if test.Allowed != nil && *test.Allowed != (deniedBy == nil) {
+		if deniedBy != nil {
+			return fmt.Errorf("expected the job to be allowed, denied by %s", deniedBy)
+		}
+
+		return fmt.Errorf("expected the job to be denied, it was allowed")
+	}
+
+	if test.Violations != nil {
+		ids := []string{}
+		for _, violation := range violations {
+			ids = append(ids, wire.RuleID(violation.Policy, violation.Name))
+		}
+
+		if !reflect.DeepEqual(ids, append([]string{}, *test.Violations...)) {
+			return fmt.Errorf("expected violations %v, got %v", *test.Violations, ids)
+		}
+	}
+
+	return nil
+}
+
+func (e *environment) actionsRunner(namespacedName string) (*inlocov1alpha1.ActionsRunner, error) {
+	if namespacedName == "" {
+		if len(e.actionsRunners) != 1 {
+			return nil, fmt.Errorf("runner is required with %d ActionsRunners", len(e.actionsRunners))
+		}
+
+		return e.actionsRunners[0], nil
+	}
+
+	for _, actionsRunner := range e.actionsRunners {
+		if actionsRunner.GetNamespace()+"/"+actionsRunner.GetName() == namespacedName {
+			return actionsRunner, nil
+		}
+	}
+
+	return nil, fmt.Errorf("ActionsRunner %q not found", namespacedName)
+}
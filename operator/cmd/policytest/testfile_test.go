@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testManifests = `
+apiVersion: inloco.com.br/v1alpha1
+kind: ActionsRunnerPolicy
+metadata:
+  name: branches
+spec:
+  must:
+  - name: master
+    rule: .github.ref == "refs/heads/master"
+---
+apiVersion: inloco.com.br/v1alpha1
+kind: ActionsRunner
+metadata:
+  namespace: ci
+  name: runner
+spec:
+  repository:
+    owner: inloco
+    name: kube-actions
+  policyRefs:
+  - name: branches
+`
+
+	testPolicyTests = `
+manifests:
+- manifests.yaml
+tests:
+- name: master
+  request: master.json
+  allowed: true
+  violations: []
+- name: tags
+  request: tag.json
+  allowed: false
+  violations: [branches/master]
+- name: replayed message
+  message: message.json
+  allowed: true
+- name: wrong expectation
+  request: tag.json
+  allowed: true
+`
+)
+
+func TestRunTestFile(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"manifests.yaml": testManifests,
+		"tests.yaml":     testPolicyTests,
+		"master.json":    `{"github": {"t": 2, "d": [{"k": "ref", "v": "refs/heads/master"}]}}`,
+		"tag.json":       `{"contextData": {"github": {"t": 2, "d": [{"k": "ref", "v": "refs/tags/v1"}]}}}`,
+		"message.json":   `[{"id": 1, "type": "PipelineAgentJobRequest", "body": {"jobName": "build", "contextData": {"github": {"t": 2, "d": [{"k": "ref", "v": "refs/heads/master"}]}}}}]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out strings.Builder
+	passed, err := runTestFile(context.Background(), &out, filepath.Join(dir, "tests.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if passed {
+		t.Error(`passed`)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatal(`len(lines) != 4`)
+	}
+
+	for _, line := range lines[:3] {
+		if !strings.HasPrefix(line, "PASS") {
+			t.Error(`!strings.HasPrefix(line, "PASS")`)
+		}
+	}
+
+	if !strings.HasPrefix(lines[3], "FAIL") {
+		t.Error(`!strings.HasPrefix(lines[3], "FAIL")`)
+	}
+}
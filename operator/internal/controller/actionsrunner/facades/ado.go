@@ -46,8 +46,8 @@ import (
 )
 
 const (
-	ownerName = "Kube Actions"
-	poolId    = 1
+	ownerName     = "Kube Actions"
+	defaultPoolId = 1
 )
 
 var (
@@ -60,25 +60,32 @@ var (
 	}
 )
 
+func AgentLabels(labels []string) []string {
+	agentLabels := make([]string, 0, len(agentLabelsBase)+len(labels))
+	agentLabels = append(agentLabels, agentLabelsBase...)
+	return append(agentLabels, labels...)
+}
+
 type AzureDevOps struct {
 	RSAPrivateKey *rsa.PrivateKey
+	PoolId        int
 
 	Connection      *azuredevops.Connection
 	TaskAgentClient taskagent.Client
 	LocationClient  location.Client
 	TaskAgent       *taskagent.TaskAgent
 
-	BridgeConnection      *azuredevops.Connection
-	TaskAgentBridgeClient taskagent.Client
-	TaskAgentSession      *taskagent.TaskAgentSession
+	BridgeConnection *azuredevops.Connection
+	BridgeBroker     Broker
+	TaskAgentSession *taskagent.TaskAgentSession
 
 	Plan          *task.TaskOrchestrationPlanReference
 	Timeline      *build.TimelineReference
 	JobConnection *azuredevops.Connection
-	TaskClient    task.Client
+	JobBroker     Broker
 }
 
-func (ado *AzureDevOps) InitForCRUD(ctx context.Context, dotFiles *dot.Files, labels []string, token string, url string) error {
+func (ado *AzureDevOps) InitForCRUD(ctx context.Context, dotFiles *dot.Files, labels []string, runnerGroup string, token string, url string) error {
 	if err := ado.initRSAPrivateKey(dotFiles); err != nil {
 		return err
 	}
@@ -91,10 +98,38 @@ func (ado *AzureDevOps) InitForCRUD(ctx context.Context, dotFiles *dot.Files, la
 		return err
 	}
 
-	if err := ado.initAzureDevOpsTaskAgent(ctx, dotFiles, append(agentLabelsBase, labels...)); err != nil {
+	if err := ado.initAzureDevOpsPool(ctx, dotFiles, runnerGroup); err != nil {
+		return err
+	}
+
+	if err := ado.initAzureDevOpsTaskAgent(ctx, dotFiles, AgentLabels(labels)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InitForRemoval only looks the agent up by name, unlike InitForCRUD it never registers it again
+func (ado *AzureDevOps) InitForRemoval(ctx context.Context, dotFiles *dot.Files, runnerGroup string, token string, url string) error {
+	if err := ado.initAzureDevOpsConnection(token, url); err != nil {
+		return err
+	}
+
+	if err := ado.initAzureDevOpsTaskAgentClient(ctx); err != nil {
+		return err
+	}
+
+	if err := ado.initAzureDevOpsPool(ctx, dotFiles, runnerGroup); err != nil {
 		return err
 	}
 
+	if dotFiles.Runner.PoolId != 0 {
+		ado.PoolId = dotFiles.Runner.PoolId
+	}
+
+	ado.TaskAgent = &taskagent.TaskAgent{
+		Name: github.String(dotFiles.Runner.AgentName),
+	}
 	return nil
 }
 
@@ -106,7 +141,7 @@ func (ado *AzureDevOps) InitForRun(ctx context.Context, dotFiles *dot.Files, lab
 		return err
 	}
 
-	if err := ado.initAzureDevOpsTaskAgent(ctx, dotFiles, append(agentLabelsBase, labels...)); err != nil {
+	if err := ado.initAzureDevOpsTaskAgent(ctx, dotFiles, AgentLabels(labels)); err != nil {
 		logger.Error(err, "Error initializing Azure DevOps task agent")
 		return err
 	}
@@ -116,8 +151,8 @@ func (ado *AzureDevOps) InitForRun(ctx context.Context, dotFiles *dot.Files, lab
 		return err
 	}
 
-	if err := ado.initAzureDevOpsBridgeTaskAgentClient(ctx); err != nil {
-		logger.Error(err, "Error initializing Azure DevOps bridge task agent client")
+	if err := ado.initAzureDevOpsBridgeBroker(ctx); err != nil {
+		logger.Error(err, "Error initializing Azure DevOps bridge broker")
 		return err
 	}
 
@@ -131,7 +166,7 @@ func (ado *AzureDevOps) RefreshForRun(ctx context.Context, dotFiles *dot.Files)
 		return err
 	}
 
-	if err := ado.initAzureDevOpsBridgeTaskAgentClient(ctx); err != nil {
+	if err := ado.initAzureDevOpsBridgeBroker(ctx); err != nil {
 		return err
 	}
 
@@ -178,13 +213,52 @@ func (ado *AzureDevOps) initAzureDevOpsTaskAgentClient(ctx context.Context) erro
 	return nil
 }
 
+// runner groups are exposed as agent pools, repository runners always live in the default one
+func (ado *AzureDevOps) initAzureDevOpsPool(ctx context.Context, dotFiles *dot.Files, runnerGroup string) error {
+	if runnerGroup == "" {
+		return nil
+	}
+
+	if ado.TaskAgentClient == nil {
+		return errors.New(".TaskAgentClient == nil")
+	}
+
+	pools, err := ado.TaskAgentClient.GetAgentPools(ctx, taskagent.GetAgentPoolsArgs{
+		PoolName: github.String(runnerGroup),
+	})
+	if err != nil {
+		return err
+	}
+
+	if pools == nil || len(*pools) != 1 {
+		return fmt.Errorf("runner group %q not found", runnerGroup)
+	}
+
+	pool := (*pools)[0]
+	if pool.Id == nil {
+		return errors.New("pool.Id == nil")
+	}
+
+	dotFiles.Runner.PoolId = *pool.Id
+	dotFiles.Runner.PoolName = runnerGroup
+	return nil
+}
+
+func (ado *AzureDevOps) getPoolId() *int {
+	if ado.PoolId == 0 {
+		return github.Int(defaultPoolId)
+	}
+
+	return github.Int(ado.PoolId)
+}
+
 func (ado *AzureDevOps) GetAgent(ctx context.Context) (*taskagent.TaskAgent, error) {
 	if ado.TaskAgentClient == nil {
 		return nil, errors.New(".TaskAgentClient == nil")
 	}
 
 	agent, err := ado.TaskAgentClient.GetAgent(ctx, taskagent.GetAgentArgs{
-		PoolId:  github.Int(poolId),
+		PoolId:  ado.getPoolId(),
 		AgentId: ado.TaskAgent.Id,
 	})
 	if err == nil {
@@ -192,7 +266,7 @@ func (ado *AzureDevOps) GetAgent(ctx context.Context) (*taskagent.TaskAgent, err
 	}
 
 	agents, _ := ado.TaskAgentClient.GetAgents(ctx, taskagent.GetAgentsArgs{
-		PoolId:    github.Int(poolId),
+		PoolId:    ado.getPoolId(),
 		AgentName: ado.TaskAgent.Name,
 	})
 	if agents != nil && len(*agents) == 1 {
@@ -208,7 +282,7 @@ func (ado *AzureDevOps) AddAgent(ctx context.Context) (*taskagent.TaskAgent, err
 	}
 
 	taskAgent, err := ado.TaskAgentClient.AddAgent(ctx, taskagent.AddAgentArgs{
-		PoolId: github.Int(poolId),
+		PoolId: ado.getPoolId(),
 		Agent:  ado.TaskAgent,
 	})
 	if err != nil {
@@ -224,7 +298,7 @@ func (ado *AzureDevOps) ReplaceAgent(ctx context.Context) (*taskagent.TaskAgent,
 	}
 
 	taskAgent, err := ado.TaskAgentClient.ReplaceAgent(ctx, taskagent.ReplaceAgentArgs{
-		PoolId:  github.Int(poolId),
+		PoolId:  ado.getPoolId(),
 		AgentId: ado.TaskAgent.Id,
 		Agent:   ado.TaskAgent,
 	})
@@ -246,17 +320,58 @@ func (ado *AzureDevOps) DeleteAgent(ctx context.Context) error {
 	}
 
 	return ado.TaskAgentClient.DeleteAgent(ctx, taskagent.DeleteAgentArgs{
-		PoolId:  github.Int(poolId),
+		PoolId:  ado.getPoolId(),
 		AgentId: ado.TaskAgent.Id,
 	})
 }
 
+// RemoveAgent deletes every agent named after the runner, it is a no-op if they are already gone
+func (ado *AzureDevOps) RemoveAgent(ctx context.Context) error {
+	if ado.TaskAgentClient == nil {
+		return errors.New(".TaskAgentClient == nil")
+	}
+
+	if ado.TaskAgent == nil {
+		return errors.New(".TaskAgent == nil")
+	}
+
+	agents, err := ado.TaskAgentClient.GetAgents(ctx, taskagent.GetAgentsArgs{
+		PoolId:    ado.getPoolId(),
+		AgentName: ado.TaskAgent.Name,
+	})
+	if err != nil {
+		return err
+	}
+	if agents == nil {
+		return nil
+	}
+
+	for _, agent := range *agents {
+		if agent.Id == nil {
+			continue
+		}
+
+		if err := ado.TaskAgentClient.DeleteAgent(ctx, taskagent.DeleteAgentArgs{
+			PoolId:  ado.getPoolId(),
+			AgentId: agent.Id,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (ado *AzureDevOps) initAzureDevOpsTaskAgent(ctx context.Context, dotFiles *dot.Files, labels []string) error {
 	// TODO
 	//if rc.rsaParameters == nil {
 	//	return errors.New(".rsaParameters == nil")
 	//}
 
+	if dotFiles.Runner.PoolId != 0 {
+		ado.PoolId = dotFiles.Runner.PoolId
+	}
+
 	publicKey := taskagent.TaskAgentPublicKey{
 		Exponent: &dotFiles.RSAParameters.Exponent,
 		Modulus:  &dotFiles.RSAParameters.Modulus,
@@ -342,17 +457,17 @@ func (ado *AzureDevOps) initAzureDevOpsBridgeConnection(ctx context.Context, dot
 	return nil
 }
 
-func (ado *AzureDevOps) initAzureDevOpsBridgeTaskAgentClient(ctx context.Context) error {
+func (ado *AzureDevOps) initAzureDevOpsBridgeBroker(ctx context.Context) error {
 	if ado.BridgeConnection == nil {
 		return errors.New(".BridgeConnection == nil")
 	}
 
-	client, err := taskagent.NewClient(ctx, ado.BridgeConnection)
+	broker, err := NewAzureDevOpsBroker(ctx, ado.BridgeConnection)
 	if err != nil {
 		return err
 	}
 
-	ado.TaskAgentBridgeClient = client
+	ado.BridgeBroker = broker
 	return nil
 }
 
@@ -361,11 +476,11 @@ func (ado *AzureDevOps) CreateAgentSession(ctx context.Context) (*taskagent.Task
 		return nil, errors.New(".TaskAgent == nil")
 	}
 
-	if ado.TaskAgentBridgeClient == nil {
-		return nil, errors.New(".TaskAgentBridgeClient == nil")
+	if ado.BridgeBroker == nil {
+		return nil, errors.New(".BridgeBroker == nil")
 	}
 
-	return ado.TaskAgentBridgeClient.CreateAgentSession(ctx, taskagent.CreateAgentSessionArgs{
+	return ado.BridgeBroker.CreateAgentSession(ctx, taskagent.CreateAgentSessionArgs{
 		Session: &taskagent.TaskAgentSession{
 			Agent: &taskagent.TaskAgentReference{
 				Links:             ado.TaskAgent.Links,
@@ -381,7 +496,7 @@ func (ado *AzureDevOps) CreateAgentSession(ctx context.Context) (*taskagent.Task
 			OwnerName: github.String(ownerName),
 			SessionId: &uuid.UUID{},
 		},
-		PoolId: github.Int(poolId),
+		PoolId: ado.getPoolId(),
 	})
 }
 
@@ -421,16 +536,16 @@ func (ado *AzureDevOps) InitAzureDevOpsTaskAgentSession(ctx context.Context) err
 }
 
 func (ado *AzureDevOps) DeleteAgentSession(ctx context.Context) error {
-	if ado.TaskAgentBridgeClient == nil {
-		return errors.New(".TaskAgentBridgeClient == nil")
+	if ado.BridgeBroker == nil {
+		return errors.New(".BridgeBroker == nil")
 	}
 
 	if ado.TaskAgentSession == nil {
 		return errors.New(".TaskAgentSession == nil")
 	}
 
-	return ado.TaskAgentBridgeClient.DeleteAgentSession(ctx, taskagent.DeleteAgentSessionArgs{
-		PoolId:    github.Int(poolId),
+	return ado.BridgeBroker.DeleteAgentSession(ctx, taskagent.DeleteAgentSessionArgs{
+		PoolId:    ado.getPoolId(),
 		SessionId: ado.TaskAgentSession.SessionId,
 	})
 }
@@ -451,8 +566,8 @@ func (ado *AzureDevOps) DeinitAzureDevOpsTaskAgentSession(ctx context.Context) e
 func (ado *AzureDevOps) GetMessage(ctx context.Context, lastMessageId *uint64) (*taskagent.TaskAgentMessage, error) {
 	logger := log.FromContext(ctx)
 
-	if ado.TaskAgentBridgeClient == nil {
-		return nil, errors.New(".TaskAgentBridgeClient == nil")
+	if ado.BridgeBroker == nil {
+		return nil, errors.New(".BridgeBroker == nil")
 	}
 
 	if ado.TaskAgentSession == nil {
@@ -460,12 +575,12 @@ func (ado *AzureDevOps) GetMessage(ctx context.Context, lastMessageId *uint64) (
 	}
 
 	logger.Info("Getting message",
-		"PoolId", poolId,
+		"PoolId", *ado.getPoolId(),
 		"SessionId", ado.TaskAgentSession.SessionId,
 		"lastMessageId", lastMessageId,
 	)
-	message, err := ado.TaskAgentBridgeClient.GetMessage(ctx, taskagent.GetMessageArgs{
-		PoolId:        github.Int(poolId),
+	message, err := ado.BridgeBroker.GetMessage(ctx, taskagent.GetMessageArgs{
+		PoolId:        ado.getPoolId(),
 		SessionId:     ado.TaskAgentSession.SessionId,
 		LastMessageId: lastMessageId,
 	})
@@ -503,16 +618,16 @@ func (ado *AzureDevOps) GetMessage(ctx context.Context, lastMessageId *uint64) (
 }
 
 func (ado *AzureDevOps) DeleteMessage(ctx context.Context, messageId uint64) error {
-	if ado.TaskAgentBridgeClient == nil {
-		return errors.New(".TaskAgentBridgeClient == nil")
+	if ado.BridgeBroker == nil {
+		return errors.New(".BridgeBroker == nil")
 	}
 
 	if ado.TaskAgentSession == nil {
 		return errors.New(".TaskAgentSession == nil")
 	}
 
-	return ado.TaskAgentBridgeClient.DeleteMessage(ctx, taskagent.DeleteMessageArgs{
-		PoolId:    github.Int(poolId),
+	return ado.BridgeBroker.DeleteMessage(ctx, taskagent.DeleteMessageArgs{
+		PoolId:    ado.getPoolId(),
 		MessageId: &messageId,
 		SessionId: ado.TaskAgentSession.SessionId,
 	})
@@ -524,17 +639,17 @@ func (ado *AzureDevOps) UpdateAgentRequest(ctx context.Context, request *taskage
 	//	return errors.New(".runnerSettings == nil")
 	//}
 
-	if ado.TaskAgentBridgeClient == nil {
-		return nil, errors.New(".TaskAgentBridgeClient == nil")
+	if ado.BridgeBroker == nil {
+		return nil, errors.New(".BridgeBroker == nil")
 	}
 
 	if request == nil {
 		return nil, errors.New("request == nil")
 	}
 
-	return ado.TaskAgentBridgeClient.UpdateAgentRequest(ctx, taskagent.UpdateAgentRequestArgs{
+	return ado.BridgeBroker.UpdateAgentRequest(ctx, taskagent.UpdateAgentRequestArgs{
 		Request:         request,
-		PoolId:          github.Int(poolId),
+		PoolId:          ado.getPoolId(),
 		RequestId:       request.RequestId,
 		LockToken:       new(uuid.UUID),
 		OrchestrationId: orchestrationId,
@@ -598,7 +713,7 @@ func (ado *AzureDevOps) initAzureDevOpsJobConnection(serviceEndpoints []servicee
 	return nil
 }
 
-func (ado *AzureDevOps) InitAzureDevOpsTaskClient(plan *task.TaskOrchestrationPlanReference, timeline *build.TimelineReference, endpoints []serviceendpoint.ServiceEndpoint) error {
+func (ado *AzureDevOps) InitAzureDevOpsJobBroker(ctx context.Context, plan *task.TaskOrchestrationPlanReference, timeline *build.TimelineReference, endpoints []serviceendpoint.ServiceEndpoint) error {
 	if err := ado.initAzureDevOpsPlan(plan); err != nil {
 		return err
 	}
@@ -611,7 +726,12 @@ func (ado *AzureDevOps) InitAzureDevOpsTaskClient(plan *task.TaskOrchestrationPl
 		return err
 	}
 
-	ado.TaskClient = task.NewClient(context.TODO(), ado.JobConnection)
+	broker, err := NewAzureDevOpsBroker(ctx, ado.JobConnection)
+	if err != nil {
+		return err
+	}
+
+	ado.JobBroker = broker
 	return nil
 }
 
@@ -624,8 +744,8 @@ func (ado *AzureDevOps) UpdateRecord(ctx context.Context, timelineRecords []task
 		return nil, errors.New(".Timeline == nil")
 	}
 
-	if ado.TaskClient == nil {
-		return nil, errors.New(".TaskClient == nil")
+	if ado.JobBroker == nil {
+		return nil, errors.New(".JobBroker == nil")
 	}
 
 	count := len(timelineRecords)
@@ -640,7 +760,7 @@ func (ado *AzureDevOps) UpdateRecord(ctx context.Context, timelineRecords []task
 		Value: &value,
 	}
 
-	res, err := ado.TaskClient.UpdateRecords(ctx, task.UpdateRecordsArgs{
+	res, err := ado.JobBroker.UpdateRecords(ctx, task.UpdateRecordsArgs{
 		Records:         &records,
 		ScopeIdentifier: ado.Plan.ScopeIdentifier,
 		HubName:         ado.Plan.PlanType,
@@ -661,15 +781,15 @@ func (ado *AzureDevOps) RaisePlanEvent(ctx context.Context, eventData *task.JobE
 		return errors.New(".Plan == nil")
 	}
 
-	if ado.TaskClient == nil {
-		return errors.New(".TaskClient == nil")
+	if ado.JobBroker == nil {
+		return errors.New(".JobBroker == nil")
 	}
 
 	if eventData == nil {
 		return errors.New("eventData == nil")
 	}
 
-	return ado.TaskClient.RaisePlanEvent(ctx, task.RaisePlanEventArgs{
+	return ado.JobBroker.RaisePlanEvent(ctx, task.RaisePlanEventArgs{
 		EventData:       eventData,
 		ScopeIdentifier: ado.Plan.ScopeIdentifier,
 		HubName:         ado.Plan.PlanType,
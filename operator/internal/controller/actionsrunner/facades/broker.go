@@ -0,0 +1,125 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package facades
+
+import (
+	"context"
+	"errors"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/task"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/taskagent"
+)
+
+// Broker is the service handing jobs to registered agents. AzureDevOps reaches it with the credentials of the agent
+// for sessions, messages and job requests, and with the ones of the job for its timeline and plan events.
+type Broker interface {
+	CreateAgentSession(ctx context.Context, args taskagent.CreateAgentSessionArgs) (*taskagent.TaskAgentSession, error)
+	DeleteAgentSession(ctx context.Context, args taskagent.DeleteAgentSessionArgs) error
+	GetMessage(ctx context.Context, args taskagent.GetMessageArgs) (*taskagent.TaskAgentMessage, error)
+	DeleteMessage(ctx context.Context, args taskagent.DeleteMessageArgs) error
+	UpdateAgentRequest(ctx context.Context, args taskagent.UpdateAgentRequestArgs) (*taskagent.TaskAgentJobRequest, error)
+	UpdateRecords(ctx context.Context, args task.UpdateRecordsArgs) (*[]task.TimelineRecord, error)
+	RaisePlanEvent(ctx context.Context, args task.RaisePlanEventArgs) error
+}
+
+// NewAzureDevOpsBroker makes the Broker reached through connection
+func NewAzureDevOpsBroker(ctx context.Context, connection *azuredevops.Connection) (Broker, error) {
+	if connection == nil {
+		return nil, errors.New("connection == nil")
+	}
+
+	return &azureDevOpsBroker{
+		connection: connection,
+		taskClient: task.NewClient(ctx, connection),
+	}, nil
+}
+
+type azureDevOpsBroker struct {
+	connection *azuredevops.Connection
+
+	// created on first use, as job connections are never used for agents
+	taskAgentClient taskagent.Client
+	taskClient      task.Client
+}
+
+func (b *azureDevOpsBroker) getTaskAgentClient(ctx context.Context) (taskagent.Client, error) {
+	if b.taskAgentClient != nil {
+		return b.taskAgentClient, nil
+	}
+
+	client, err := taskagent.NewClient(ctx, b.connection)
+	if err != nil {
+		return nil, err
+	}
+
+	b.taskAgentClient = client
+	return client, nil
+}
+
+func (b *azureDevOpsBroker) CreateAgentSession(ctx context.Context, args taskagent.CreateAgentSessionArgs) (*taskagent.TaskAgentSession, error) {
+	client, err := b.getTaskAgentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.CreateAgentSession(ctx, args)
+}
+
+func (b *azureDevOpsBroker) DeleteAgentSession(ctx context.Context, args taskagent.DeleteAgentSessionArgs) error {
+	client, err := b.getTaskAgentClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	return client.DeleteAgentSession(ctx, args)
+}
+
+func (b *azureDevOpsBroker) GetMessage(ctx context.Context, args taskagent.GetMessageArgs) (*taskagent.TaskAgentMessage, error) {
+	client, err := b.getTaskAgentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetMessage(ctx, args)
+}
+
+func (b *azureDevOpsBroker) DeleteMessage(ctx context.Context, args taskagent.DeleteMessageArgs) error {
+	client, err := b.getTaskAgentClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	return client.DeleteMessage(ctx, args)
+}
+
+func (b *azureDevOpsBroker) UpdateAgentRequest(ctx context.Context, args taskagent.UpdateAgentRequestArgs) (*taskagent.TaskAgentJobRequest, error) {
+	client, err := b.getTaskAgentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.UpdateAgentRequest(ctx, args)
+}
+
+func (b *azureDevOpsBroker) UpdateRecords(ctx context.Context, args task.UpdateRecordsArgs) (*[]task.TimelineRecord, error) {
+	return b.taskClient.UpdateRecords(ctx, args)
+}
+
+func (b *azureDevOpsBroker) RaisePlanEvent(ctx context.Context, args task.RaisePlanEventArgs) error {
+	return b.taskClient.RaisePlanEvent(ctx, args)
+}
@@ -0,0 +1,687 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/task"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/taskagent"
+)
+
+const (
+	locationsPath     = "/_apis"
+	resourceAreasPath = "/_apis/ResourceAreas"
+	poolsPath         = "/_apis/distributedtask/pools"
+
+	defaultPoolId   = 1
+	defaultPoolName = "Default"
+
+	sessionKeySize = 32
+)
+
+var (
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// location is an API resource location, clients look routes up by their id with OPTIONS on locationsPath
+type location struct {
+	Id              uuid.UUID `json:"id"`
+	Area            string    `json:"area"`
+	ResourceName    string    `json:"resourceName"`
+	RouteTemplate   string    `json:"routeTemplate"`
+	ResourceVersion int       `json:"resourceVersion"`
+	MinVersion      string    `json:"minVersion"`
+	MaxVersion      string    `json:"maxVersion"`
+	ReleasedVersion string    `json:"releasedVersion"`
+}
+
+func newLocation(id string, area string, resourceName string, routeTemplate string) location {
+	return location{
+		Id:              uuid.MustParse(id),
+		Area:            area,
+		ResourceName:    resourceName,
+		RouteTemplate:   routeTemplate,
+		ResourceVersion: 1,
+		MinVersion:      "1.0",
+		MaxVersion:      "7.1",
+		ReleasedVersion: "0.0",
+	}
+}
+
+// locations of the routes the taskagent and task clients call, the ids are the ones generated into the clients
+var locations = []location{
+	newLocation("e81700f7-3be2-46de-8624-2eb35882fcaa", "Location", "ResourceAreas", "_apis/{resource}/{areaId}"),
+	newLocation("a8c47e17-4d56-4a56-92bb-de7ea7dc65be", "distributedtask", "pools", "_apis/{area}/{resource}/{poolId}"),
+	newLocation("e298ef32-5878-4cab-993c-043836571f42", "distributedtask", "agents", "_apis/{area}/pools/{poolId}/{resource}/{agentId}"),
+	newLocation("134e239e-2df3-4794-a6f6-24f1f19ec8dc", "distributedtask", "sessions", "_apis/{area}/pools/{poolId}/{resource}/{sessionId}"),
+	newLocation("c3a054f6-7a8a-49c0-944e-3a8e5d7adfd7", "distributedtask", "messages", "_apis/{area}/pools/{poolId}/{resource}/{messageId}"),
+	newLocation("fc825784-c92a-4299-9221-998a02d1b54f", "distributedtask", "jobrequests", "_apis/{area}/pools/{poolId}/{resource}/{requestId}"),
+	newLocation("8893bc5b-35b2-4be7-83cb-99e683551db4", "distributedtask", "records", "{scopeIdentifier}/_apis/{area}/hubs/{hubName}/plans/{planId}/timelines/{timelineId}/{resource}"),
+	newLocation("557624af-b29e-4c20-8ab0-0399d2204f3f", "distributedtask", "events", "{scopeIdentifier}/_apis/{area}/hubs/{hubName}/plans/{planId}/{resource}"),
+}
+
+// collection is how lists are wrapped on the wire
+type collection struct {
+	Count int         `json:"count"`
+	Value interface{} `json:"value"`
+}
+
+func writeCollection(w http.ResponseWriter, value interface{}, count int) {
+	writeJSON(w, http.StatusOK, collection{Count: count, Value: value})
+}
+
+// writeError writes the wrapped exception Azure DevOps answers failed calls with
+func writeError(w http.ResponseWriter, statusCode int, typeKey string, message string) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"message":  message,
+		"typeName": "Microsoft.TeamFoundation.DistributedTask.WebApi." + typeKey,
+		"typeKey":  typeKey,
+	})
+}
+
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// authorizeTenant checks the call is made with a tenant credential, which agents are registered and removed with
+func (s *Server) authorizeTenant(w http.ResponseWriter, r *http.Request) bool {
+	s.mutex.Lock()
+	_, ok := s.tenantTokens[bearerToken(r)]
+	s.mutex.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "UnauthorizedRequestException", "unauthorized")
+	}
+
+	return ok
+}
+
+// authorizeAgent returns the agent the call is made for, with a token of the token endpoint
+func (s *Server) authorizeAgent(w http.ResponseWriter, r *http.Request) *agent {
+	s.mutex.Lock()
+	a := s.tokens[bearerToken(r)]
+	s.mutex.Unlock()
+
+	if a == nil {
+		writeError(w, http.StatusUnauthorized, "UnauthorizedRequestException", "connection is not authorized for an agent")
+	}
+
+	return a
+}
+
+// authorizeJob returns the orchestration the call is made for, with the token of its SystemVssConnection
+func (s *Server) authorizeJob(w http.ResponseWriter, r *http.Request) string {
+	s.mutex.Lock()
+	orchestrationId := s.jobTokens[bearerToken(r)]
+	s.mutex.Unlock()
+
+	if orchestrationId == "" {
+		writeError(w, http.StatusUnauthorized, "UnauthorizedRequestException", "connection is not authorized for a job")
+	}
+
+	return orchestrationId
+}
+
+func (s *Server) serveLocations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodOptions {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeCollection(w, locations, len(locations))
+}
+
+// serveResourceAreas lists none, like on-premises servers, so clients keep calling the base URL of their connection
+func (s *Server) serveResourceAreas(w http.ResponseWriter, r *http.Request) {
+	writeCollection(w, []interface{}{}, 0)
+}
+
+// servePools serves poolsPath and the agents, sessions, messages and job requests under poolsPath/{poolId}
+func (s *Server) servePools(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, poolsPath), "/")
+	if path == "" {
+		s.serveAgentPools(w, r)
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != strconv.Itoa(defaultPoolId) {
+		writeError(w, http.StatusNotFound, "TaskAgentPoolNotFoundException", "pool "+parts[0]+" not found")
+		return
+	}
+
+	id := ""
+	if len(parts) == 3 {
+		id = parts[2]
+	}
+
+	switch parts[1] {
+	case "agents":
+		s.serveAgents(w, r, id)
+
+	case "sessions":
+		s.serveSessions(w, r, id)
+
+	case "messages":
+		s.serveMessages(w, r, id)
+
+	case "jobrequests":
+		s.serveJobRequests(w, r, id)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveAgentPools lists the default pool, the only one repository runners are registered in
+func (s *Server) serveAgentPools(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeTenant(w, r) {
+		return
+	}
+
+	pools := []taskagent.TaskAgentPool{}
+	if poolName := r.URL.Query().Get("poolName"); poolName == "" || poolName == defaultPoolName {
+		pools = append(pools, taskagent.TaskAgentPool{
+			Id:   github.Int(defaultPoolId),
+			Name: github.String(defaultPoolName),
+		})
+	}
+
+	writeCollection(w, pools, len(pools))
+}
+
+func agentNotFound(w http.ResponseWriter, id string) {
+	writeError(w, http.StatusNotFound, "TaskAgentNotFoundException", "agent "+id+" not found")
+}
+
+func (s *Server) serveAgents(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.authorizeTenant(w, r) {
+		return
+	}
+
+	if id == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.serveListAgents(w, r)
+
+		case http.MethodPost:
+			s.serveAddAgent(w, r)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a := s.agentById(id)
+	if a == nil {
+		agentNotFound(w, id)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.taskAgent(a))
+
+	case http.MethodPut:
+		var taskAgent taskagent.TaskAgent
+		if err := json.NewDecoder(r.Body).Decode(&taskAgent); err != nil {
+			writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+			return
+		}
+
+		publicKey, err := agentPublicKey(taskAgent)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+			return
+		}
+
+		a.name = agentName(taskAgent)
+		a.publicKey = publicKey
+		a.labels = agentLabels(taskAgent)
+		writeJSON(w, http.StatusOK, s.taskAgent(a))
+
+	case http.MethodDelete:
+		delete(s.agents, a.clientId)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) serveListAgents(w http.ResponseWriter, r *http.Request) {
+	agentName := r.URL.Query().Get("agentName")
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	agents := []taskagent.TaskAgent{}
+	for _, a := range s.agents {
+		if agentName == "" || a.name == agentName {
+			agents = append(agents, s.taskAgent(a))
+		}
+	}
+
+	writeCollection(w, agents, len(agents))
+}
+
+func (s *Server) serveAddAgent(w http.ResponseWriter, r *http.Request) {
+	var taskAgent taskagent.TaskAgent
+	if err := json.NewDecoder(r.Body).Decode(&taskAgent); err != nil {
+		writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+		return
+	}
+
+	publicKey, err := agentPublicKey(taskAgent)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, a := range s.agents {
+		if a.name == agentName(taskAgent) {
+			writeError(w, http.StatusConflict, "TaskAgentExistsException", "agent "+a.name+" already exists")
+			return
+		}
+	}
+
+	a := s.addAgent(agentName(taskAgent), publicKey)
+	a.labels = agentLabels(taskAgent)
+	writeJSON(w, http.StatusOK, s.taskAgent(a))
+}
+
+// agentById looks an agent up by the id clients know it by, callers must hold mutex
+func (s *Server) agentById(id string) *agent {
+	for _, a := range s.agents {
+		if strconv.Itoa(a.id) == id {
+			return a
+		}
+	}
+
+	return nil
+}
+
+// taskAgent is a as returned to clients, callers must hold mutex
+func (s *Server) taskAgent(a *agent) taskagent.TaskAgent {
+	clientId := uuid.MustParse(a.clientId)
+	labels := append([]string{}, a.labels...)
+
+	return taskagent.TaskAgent{
+		Id:     github.Int(a.id),
+		Name:   github.String(a.name),
+		Labels: &labels,
+		Authorization: &taskagent.TaskAgentAuthorization{
+			AuthorizationUrl: github.String(s.URL + TokenPath),
+			ClientId:         &clientId,
+		},
+	}
+}
+
+func agentPublicKey(taskAgent taskagent.TaskAgent) (*rsa.PublicKey, error) {
+	if taskAgent.Authorization == nil || taskAgent.Authorization.PublicKey == nil {
+		return nil, errors.New("agent has no public key")
+	}
+
+	publicKey := taskAgent.Authorization.PublicKey
+	if publicKey.Modulus == nil || publicKey.Exponent == nil {
+		return nil, errors.New("agent has no public key")
+	}
+
+	return newPublicKey(*publicKey.Modulus, *publicKey.Exponent)
+}
+
+func agentName(taskAgent taskagent.TaskAgent) string {
+	if taskAgent.Name == nil {
+		return ""
+	}
+
+	return *taskAgent.Name
+}
+
+func agentLabels(taskAgent taskagent.TaskAgent) []string {
+	if taskAgent.Labels == nil {
+		return nil
+	}
+
+	return append([]string{}, *taskAgent.Labels...)
+}
+
+func newPublicKey(modulus []byte, exponent []byte) (*rsa.PublicKey, error) {
+	if len(modulus) == 0 || len(exponent) == 0 {
+		return nil, errors.New("public key has no modulus or exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}, nil
+}
+
+func sessionNotFound(w http.ResponseWriter, sessionId string) {
+	writeError(w, http.StatusNotFound, "TaskAgentSessionExpiredException", "session "+sessionId+" not found")
+}
+
+func (s *Server) serveSessions(w http.ResponseWriter, r *http.Request, id string) {
+	a := s.authorizeAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		s.serveCreateSession(w, r, a)
+
+	case id != "" && r.Method == http.MethodDelete:
+		sessionId, err := uuid.Parse(id)
+		if err != nil {
+			sessionNotFound(w, id)
+			return
+		}
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if _, ok := s.sessions[sessionId]; !ok {
+			sessionNotFound(w, id)
+			return
+		}
+
+		delete(s.sessions, sessionId)
+		s.notify()
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveCreateSession hands out a session key encrypted with the public key of the agent
+func (s *Server) serveCreateSession(w http.ResponseWriter, r *http.Request, a *agent) {
+	var taskAgentSession taskagent.TaskAgentSession
+	if err := json.NewDecoder(r.Body).Decode(&taskAgentSession); err != nil {
+		writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+		return
+	}
+
+	key := make([]byte, sessionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		writeError(w, http.StatusInternalServerError, "Exception", err.Error())
+		return
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, a.publicKey, key, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Exception", err.Error())
+		return
+	}
+
+	sessionId := uuid.New()
+
+	s.mutex.Lock()
+	s.sessions[sessionId] = &session{
+		agent: a,
+		key:   key,
+	}
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, taskagent.TaskAgentSession{
+		Agent: taskAgentSession.Agent,
+		EncryptionKey: &taskagent.TaskAgentSessionKey{
+			Encrypted: github.Bool(true),
+			Value:     &encryptedKey,
+		},
+		OwnerName:         taskAgentSession.OwnerName,
+		SessionId:         &sessionId,
+		UseFipsEncryption: github.Bool(false),
+	})
+}
+
+func (s *Server) serveMessages(w http.ResponseWriter, r *http.Request, id string) {
+	a := s.authorizeAgent(w, r)
+	if a == nil {
+		return
+	}
+
+	query := r.URL.Query()
+
+	sessionId, err := uuid.Parse(query.Get("sessionId"))
+	if err != nil {
+		sessionNotFound(w, query.Get("sessionId"))
+		return
+	}
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		var lastMessageId *uint64
+		if v := query.Get("lastMessageId"); v != "" {
+			messageId, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+				return
+			}
+
+			lastMessageId = &messageId
+		}
+
+		s.serveGetMessage(w, r, sessionId, lastMessageId)
+
+	case id != "" && r.Method == http.MethodDelete:
+		messageId, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+			return
+		}
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if s.sessions[sessionId] == nil {
+			sessionNotFound(w, sessionId.String())
+			return
+		}
+
+		for i, message := range a.messages {
+			if *message.MessageId == messageId {
+				a.messages = append(a.messages[:i], a.messages[i+1:]...)
+				break
+			}
+		}
+		s.deleted = append(s.deleted, messageId)
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveGetMessage waits up to longPollTimeout for a message newer than lastMessageId, answering 202 Accepted with no
+// body if there is none
+func (s *Server) serveGetMessage(w http.ResponseWriter, r *http.Request, sessionId uuid.UUID, lastMessageId *uint64) {
+	timer := time.NewTimer(longPollTimeout)
+	defer timer.Stop()
+
+	for {
+		s.mutex.Lock()
+
+		ss, ok := s.sessions[sessionId]
+		if !ok {
+			s.mutex.Unlock()
+			sessionNotFound(w, sessionId.String())
+			return
+		}
+
+		if message := ss.agent.nextMessage(lastMessageId); message != nil {
+			s.mutex.Unlock()
+
+			encrypted, err := encryptMessage(*message, ss.key)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "Exception", err.Error())
+				return
+			}
+
+			writeJSON(w, http.StatusOK, encrypted)
+			return
+		}
+
+		changed := s.changed
+		s.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			w.WriteHeader(http.StatusAccepted)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// nextMessage is the oldest message not acknowledged by lastMessageId, callers must hold the mutex of the server
+func (a *agent) nextMessage(lastMessageId *uint64) *taskagent.TaskAgentMessage {
+	for i := range a.messages {
+		if lastMessageId != nil && *a.messages[i].MessageId <= *lastMessageId {
+			continue
+		}
+
+		return &a.messages[i]
+	}
+
+	return nil
+}
+
+// encryptMessage prepends a BOM to the body and encrypts it with AES-CBC and PKCS #7 padding as the real broker does
+func encryptMessage(message taskagent.TaskAgentMessage, key []byte) (*taskagent.TaskAgentMessage, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, block.BlockSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	plaintext := append(append([]byte{}, utf8BOM...), *message.Body...)
+	padding := block.BlockSize() - len(plaintext)%block.BlockSize()
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	message.Body = github.String(base64.StdEncoding.EncodeToString(ciphertext))
+	message.Iv = &iv
+
+	return &message, nil
+}
+
+func (s *Server) serveJobRequests(w http.ResponseWriter, r *http.Request, id string) {
+	if s.authorizeAgent(w, r) == nil {
+		return
+	}
+
+	if id == "" || r.Method != http.MethodPatch {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var update AgentRequestUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update.Request); err != nil {
+		writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+		return
+	}
+	update.OrchestrationId = r.URL.Query().Get("orchestrationId")
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, update)
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, update.Request)
+}
+
+// serveHubs serves the timeline records and plan events of jobs, under
+// /{scopeIdentifier}/_apis/distributedtask/hubs/{hubName}/plans/{planId}
+func (s *Server) serveHubs(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 8 || parts[1] != "_apis" || parts[2] != "distributedtask" || parts[3] != "hubs" || parts[5] != "plans" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if s.authorizeJob(w, r) == "" {
+		return
+	}
+
+	switch resource := parts[7:]; {
+	case len(resource) == 3 && resource[0] == "timelines" && resource[2] == "records" && r.Method == http.MethodPatch:
+		var records struct {
+			Value []task.TimelineRecord `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+			writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+			return
+		}
+
+		s.mutex.Lock()
+		s.records = append(s.records, records.Value...)
+		s.mutex.Unlock()
+
+		writeCollection(w, records.Value, len(records.Value))
+
+	case len(resource) == 1 && resource[0] == "events" && r.Method == http.MethodPost:
+		var event task.JobEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			writeError(w, http.StatusBadRequest, "ArgumentException", err.Error())
+			return
+		}
+
+		s.mutex.Lock()
+		s.planEvents = append(s.planEvents, event)
+		s.mutex.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/google/uuid"
+)
+
+const (
+	GitHubAPIPath = "/api/v3"
+
+	// what the GitHub endpoint set up by tests authenticates with
+	GitHubPAT = "pat"
+)
+
+// GitHubAPIEndpoint is the apiEndpoint of ActionsRunners registered with the Server, see facades.SetGitHubEndpoint
+func (s *Server) GitHubAPIEndpoint() string {
+	return s.URL + GitHubAPIPath
+}
+
+// AddRepository makes owner/name exist, runners may be registered with or removed from it
+func (s *Server) AddRepository(owner string, name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.repositories[owner+"/"+name] = struct{}{}
+}
+
+// AddOrganization makes login exist, runners may be registered with or removed from it
+func (s *Server) AddOrganization(login string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.organizations[login] = struct{}{}
+}
+
+// FailGitHub makes the repository and organization endpoints answer with statusCode, 0 restores them
+func (s *Server) FailGitHub(statusCode int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.githubFailure = statusCode
+}
+
+// RunnerTokens returns the registration and remove tokens handed out, oldest first
+func (s *Server) RunnerTokens() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string{}, s.runnerTokens...)
+}
+
+func (s *Server) serveRateLimit(w http.ResponseWriter, r *http.Request) {
+	reset := github.Timestamp{Time: time.Now().Add(time.Hour)}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"resources": map[string]interface{}{
+			"core": github.Rate{Limit: 5000, Remaining: 5000, Reset: reset},
+		},
+	})
+}
+
+// authorizeGitHub checks the personal access token of the entry client and injected failures
+func (s *Server) authorizeGitHub(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") != "Bearer "+GitHubPAT {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+		return false
+	}
+
+	s.mutex.Lock()
+	failure := s.githubFailure
+	s.mutex.Unlock()
+
+	if failure != 0 {
+		writeJSON(w, failure, map[string]string{"message": http.StatusText(failure)})
+		return false
+	}
+
+	return true
+}
+
+// serveRepository serves /repos/{owner}/{name} and the runner tokens under /repos/{owner}/{name}/actions/runners
+func (s *Server) serveRepository(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeGitHub(w, r) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, GitHubAPIPath+"/repos/"), "/")
+	if len(parts) < 2 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	owner, name := parts[0], parts[1]
+
+	s.mutex.Lock()
+	_, ok := s.repositories[owner+"/"+name]
+	s.mutex.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+
+	if len(parts) == 2 && r.Method == http.MethodGet {
+		htmlURL := fmt.Sprintf("%s/%s/%s", s.URL, owner, name)
+		writeJSON(w, http.StatusOK, github.Repository{
+			Name:          github.String(name),
+			FullName:      github.String(owner + "/" + name),
+			Owner:         &github.User{Login: github.String(owner)},
+			Private:       github.Bool(false),
+			HTMLURL:       github.String(htmlURL),
+			GitCommitsURL: github.String(htmlURL + "/git/commits{/sha}"),
+		})
+		return
+	}
+
+	s.serveRunnerToken(w, r, parts[2:])
+}
+
+// serveOrganization serves /orgs/{login} and the runner tokens under /orgs/{login}/actions/runners
+func (s *Server) serveOrganization(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeGitHub(w, r) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, GitHubAPIPath+"/orgs/"), "/")
+	login := parts[0]
+
+	s.mutex.Lock()
+	_, ok := s.organizations[login]
+	s.mutex.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+
+	if len(parts) == 1 && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, github.Organization{
+			Login:   github.String(login),
+			HTMLURL: github.String(s.URL + "/" + login),
+		})
+		return
+	}
+
+	s.serveRunnerToken(w, r, parts[1:])
+}
+
+// serveRunnerToken hands out registration and remove tokens, which serveTenantCredential accepts
+func (s *Server) serveRunnerToken(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) != 3 || parts[0] != "actions" || parts[1] != "runners" || (parts[2] != "registration-token" && parts[2] != "remove-token") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "Method Not Allowed"})
+		return
+	}
+
+	token := parts[2] + "-" + uuid.New().String()
+
+	s.mutex.Lock()
+	s.runnerTokens = append(s.runnerTokens, token)
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":      token,
+		"expires_at": github.Timestamp{Time: time.Now().Add(time.Hour)},
+	})
+}
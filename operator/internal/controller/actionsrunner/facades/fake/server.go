@@ -0,0 +1,466 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an in-process Azure DevOps job broker and GitHub API, so the listener and the reconciler can be
+// driven end to end without network access.
+package fake
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/serviceendpoint"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/task"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/taskagent"
+
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/dot"
+)
+
+const (
+	TokenPath            = "/_apis/oauth2/token"
+	TenantCredentialPath = "/api/v3/actions/runner-registration"
+
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	tokenExpiry = time.Hour
+
+	// GetMessage returns nothing after waiting this long, like the long polling of the real broker
+	longPollTimeout = 100 * time.Millisecond
+)
+
+type agent struct {
+	id        int
+	name      string
+	labels    []string
+	clientId  string
+	publicKey *rsa.PublicKey
+	revoked   bool
+
+	messages []taskagent.TaskAgentMessage
+}
+
+type session struct {
+	agent *agent
+	key   []byte
+}
+
+// Server speaks the HTTP protocol of the Azure DevOps job broker and of the GitHub API. It serves the OAuth token
+// endpoint agents exchange their client assertions at, the agents, sessions, messages and job requests of the default
+// pool, the timeline records and plan events of jobs, and the repositories, organizations, runner tokens and tenant
+// credentials of GitHub. Job messages are encrypted with the session key exactly like the real broker does.
+type Server struct {
+	*httptest.Server
+
+	key *rsa.PrivateKey
+
+	mutex        sync.Mutex
+	changed      chan struct{} // closed and replaced on every change of messages or sessions
+	agents       map[string]*agent
+	tokens       map[string]*agent
+	jobTokens    map[string]string
+	tenantTokens map[string]struct{}
+	sessions     map[uuid.UUID]*session
+	nextAgent    int
+	nextMsgId    uint64
+	deleted      []uint64
+	requests     []AgentRequestUpdate
+	records      []task.TimelineRecord
+	planEvents   []task.JobEvent
+
+	repositories  map[string]struct{}
+	organizations map[string]struct{}
+	runnerTokens  []string
+	githubFailure int
+}
+
+// AgentRequestUpdate is a job request as updated by an agent, along with the orchestration it was updated for
+type AgentRequestUpdate struct {
+	Request         taskagent.TaskAgentJobRequest
+	OrchestrationId string
+}
+
+func NewServer() (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		key:          key,
+		changed:      make(chan struct{}),
+		agents:       make(map[string]*agent),
+		tokens:       make(map[string]*agent),
+		jobTokens:    make(map[string]string),
+		tenantTokens: make(map[string]struct{}),
+		sessions:     make(map[uuid.UUID]*session),
+
+		repositories:  make(map[string]struct{}),
+		organizations: make(map[string]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(TokenPath, s.serveToken)
+	mux.HandleFunc(TenantCredentialPath, s.serveTenantCredential)
+	mux.HandleFunc(locationsPath, s.serveLocations)
+	mux.HandleFunc(resourceAreasPath, s.serveResourceAreas)
+	mux.HandleFunc(poolsPath, s.servePools)
+	mux.HandleFunc(poolsPath+"/", s.servePools)
+	mux.HandleFunc("/", s.serveHubs)
+	mux.HandleFunc(GitHubAPIPath+"/rate_limit", s.serveRateLimit)
+	mux.HandleFunc(GitHubAPIPath+"/repos/", s.serveRepository)
+	mux.HandleFunc(GitHubAPIPath+"/orgs/", s.serveOrganization)
+	s.Server = httptest.NewServer(mux)
+
+	return s, nil
+}
+
+// Register does what registering an agent with the broker would, filling the .runner and .credentials of dotFiles
+func (s *Server) Register(dotFiles *dot.Files) error {
+	if dotFiles == nil {
+		return errors.New("dotFiles == nil")
+	}
+
+	publicKey, err := newPublicKey(dotFiles.RSAParameters.Modulus, dotFiles.RSAParameters.Exponent)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a := s.addAgent(dotFiles.Runner.AgentName, publicKey)
+
+	dotFiles.Runner.AgentId = a.id
+	dotFiles.Runner.PoolId = defaultPoolId
+	dotFiles.Runner.PoolName = defaultPoolName
+	dotFiles.Runner.ServerUrl = s.URL
+	dotFiles.Credentials.Scheme = "OAuth"
+	dotFiles.Credentials.Data.ClientId = a.clientId
+	dotFiles.Credentials.Data.AuthorizationURL = s.URL + TokenPath
+	dotFiles.Credentials.Data.OAuthEndpointURL = s.URL + TokenPath
+	dotFiles.Credentials.Data.RequireFipsCryptography = "False"
+
+	return nil
+}
+
+// addAgent registers an agent in the default pool, callers must hold mutex
+func (s *Server) addAgent(name string, publicKey *rsa.PublicKey) *agent {
+	s.nextAgent++
+	a := &agent{
+		id:        s.nextAgent,
+		name:      name,
+		clientId:  uuid.New().String(),
+		publicKey: publicKey,
+	}
+	s.agents[a.clientId] = a
+
+	return a
+}
+
+// Revoke makes the token endpoint reject the client assertions of an agent as the real one does for deleted agents
+func (s *Server) Revoke(dotFiles *dot.Files) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a, ok := s.agents[dotFiles.Credentials.Data.ClientId]
+	if !ok {
+		return fmt.Errorf("agent %q not found", dotFiles.Credentials.Data.ClientId)
+	}
+
+	a.revoked = true
+	return nil
+}
+
+// QueueMessage hands a message to the agent registered with dotFiles on its next GetMessage
+func (s *Server) QueueMessage(dotFiles *dot.Files, messageType string, body string) (uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a, ok := s.agents[dotFiles.Credentials.Data.ClientId]
+	if !ok {
+		return 0, fmt.Errorf("agent %q not found", dotFiles.Credentials.Data.ClientId)
+	}
+
+	s.nextMsgId++
+	messageId := s.nextMsgId
+
+	a.messages = append(a.messages, taskagent.TaskAgentMessage{
+		Body:        &body,
+		MessageId:   &messageId,
+		MessageType: &messageType,
+	})
+	s.notify()
+
+	return messageId, nil
+}
+
+// SystemVssConnection is the endpoint job requests carry for the runner to reach the broker, its token authorizes the
+// timeline and plan event calls of a job and names orchestrationId in its orchid claim
+func (s *Server) SystemVssConnection(orchestrationId string) (*serviceendpoint.ServiceEndpoint, error) {
+	token, err := s.signedToken(map[string]interface{}{
+		"orchid": orchestrationId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.jobTokens[token] = orchestrationId
+	s.mutex.Unlock()
+
+	name := "SystemVssConnection"
+	scheme := "OAuth"
+	parameters := map[string]string{
+		"AccessToken": token,
+	}
+
+	return &serviceendpoint.ServiceEndpoint{
+		Name: &name,
+		Url:  &s.URL,
+		Authorization: &serviceendpoint.EndpointAuthorization{
+			Scheme:     &scheme,
+			Parameters: &parameters,
+		},
+	}, nil
+}
+
+// MessagesDeleted returns the ids of the messages agents deleted, in order
+func (s *Server) MessagesDeleted() []uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]uint64{}, s.deleted...)
+}
+
+// AgentRequestUpdates returns the job requests updated by agents, in order
+func (s *Server) AgentRequestUpdates() []AgentRequestUpdate {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]AgentRequestUpdate{}, s.requests...)
+}
+
+// TimelineRecords returns the timeline records updated by jobs, in order
+func (s *Server) TimelineRecords() []task.TimelineRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]task.TimelineRecord{}, s.records...)
+}
+
+// PlanEvents returns the events raised by jobs, in order
+func (s *Server) PlanEvents() []task.JobEvent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]task.JobEvent{}, s.planEvents...)
+}
+
+// Agents returns the number of registered agents
+func (s *Server) Agents() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.agents)
+}
+
+// Sessions returns the number of open agent sessions
+func (s *Server) Sessions() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.sessions)
+}
+
+// notify wakes up every pending GetMessage, callers must hold mutex
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) signedToken(claims map[string]interface{}) (string, error) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: s.key}, new(jose.SignerOptions).WithType("JWT"))
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	registered := jwt.Claims{
+		Issuer:   s.URL,
+		Expiry:   jwt.NewNumericDate(now.Add(tokenExpiry)),
+		IssuedAt: jwt.NewNumericDate(now),
+		ID:       uuid.New().String(),
+	}
+
+	return jwt.Signed(signer).Claims(registered).Claims(claims).CompactSerialize()
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token,omitempty"`
+	TokenType        string `json:"token_type,omitempty"`
+	ExpiresIn        int64  `json:"expires_in,omitempty"`
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// serveToken is the client credentials grant of RFC 6749 authenticated by the JWT client assertion of RFC 7523
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, tokenResponse{Error: "invalid_request"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, tokenResponse{Error: "invalid_request", ErrorDescription: err.Error()})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, tokenResponse{Error: "unsupported_grant_type"})
+		return
+	}
+
+	if r.PostForm.Get("client_assertion_type") != clientAssertionType {
+		writeJSON(w, http.StatusBadRequest, tokenResponse{Error: "invalid_request", ErrorDescription: "unsupported client_assertion_type"})
+		return
+	}
+
+	a, err := s.authenticate(r.PostForm.Get("client_assertion"))
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, tokenResponse{Error: "invalid_client", ErrorDescription: err.Error()})
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		writeJSON(w, http.StatusInternalServerError, tokenResponse{Error: "server_error"})
+		return
+	}
+	token := hex.EncodeToString(b)
+
+	s.mutex.Lock()
+	s.tokens[token] = a
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken: token,
+		TokenType:   "bearer",
+		ExpiresIn:   int64(tokenExpiry.Seconds()),
+	})
+}
+
+func (s *Server) authenticate(assertion string) (*agent, error) {
+	token, err := jwt.ParseSigned(assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	var unverified jwt.Claims
+	if err := token.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	a, ok := s.agents[unverified.Issuer]
+	s.mutex.Unlock()
+
+	if !ok || a.revoked {
+		return nil, fmt.Errorf("agent %q not found", unverified.Issuer)
+	}
+
+	var claims jwt.Claims
+	if err := token.Claims(a.publicKey, &claims); err != nil {
+		return nil, err
+	}
+
+	return a, claims.Validate(jwt.Expected{
+		Issuer:   a.clientId,
+		Subject:  a.clientId,
+		Audience: jwt.Audience{s.URL + TokenPath},
+		Time:     time.Now(),
+	})
+}
+
+type tenantCredentialRequest struct {
+	RunnerEvent string `json:"runner_event"`
+	URL         string `json:"url"`
+}
+
+type tenantCredential struct {
+	Token       string `json:"token"`
+	TokenSchema string `json:"token_schema"`
+	URL         string `json:"url"`
+}
+
+// serveTenantCredential trades the registration or remove token of a runner for the credential and URL of its tenant
+func (s *Server) serveTenantCredential(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "RemoteAuth ") {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+		return
+	}
+
+	var req tenantCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	if (req.RunnerEvent != "register" && req.RunnerEvent != "remove") || req.URL == "" {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+		return
+	}
+
+	token, err := s.signedToken(map[string]interface{}{
+		"runner_event": req.RunnerEvent,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+
+	s.mutex.Lock()
+	s.tenantTokens[token] = struct{}{}
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusCreated, tenantCredential{
+		Token:       token,
+		TokenSchema: "OAuthAccessToken",
+		URL:         s.URL,
+	})
+}
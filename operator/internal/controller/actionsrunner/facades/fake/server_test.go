@@ -0,0 +1,242 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"golang.org/x/oauth2"
+
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/dot"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/util"
+)
+
+func newRegisteredDotFiles(t *testing.T, server *Server) *dot.Files {
+	rsaParameters, err := dot.NewRSAParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dotFiles := &dot.Files{RSAParameters: *rsaParameters}
+	dotFiles.Runner.AgentName = "runner"
+	if err := server.Register(dotFiles); err != nil {
+		t.Fatal(err)
+	}
+
+	return dotFiles
+}
+
+func TestTokenEndpoint(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	dotFiles := newRegisteredDotFiles(t, server)
+
+	key, err := dotFiles.RSAParameters.ToRSAPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertion, err := util.ClientAssertion(dotFiles.Credentials.Data.ClientId, dotFiles.Credentials.Data.AuthorizationURL, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := util.AccessToken(context.Background(), dotFiles.Credentials.Data.OAuthEndpointURL, assertion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token == "" {
+		t.Error(`token == ""`)
+	}
+
+	other := newRegisteredDotFiles(t, server)
+	forged, err := util.ClientAssertion(other.Credentials.Data.ClientId, other.Credentials.Data.AuthorizationURL, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := util.AccessToken(context.Background(), other.Credentials.Data.OAuthEndpointURL, forged); !errors.Is(err, util.ErrOAuth2InvalidClient) {
+		t.Error(`!errors.Is(err, util.ErrOAuth2InvalidClient)`)
+	}
+
+	if err := server.Revoke(dotFiles); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := util.AccessToken(context.Background(), dotFiles.Credentials.Data.OAuthEndpointURL, assertion); !errors.Is(err, util.ErrOAuth2InvalidClient) {
+		t.Error(`!errors.Is(err, util.ErrOAuth2InvalidClient)`)
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	dotFiles := newRegisteredDotFiles(t, server)
+
+	var ado facades.AzureDevOps
+	if err := ado.InitForRun(ctx, dotFiles, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ado.InitAzureDevOpsTaskAgentSession(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if message, err := ado.GetMessage(ctx, nil); err != nil || message != nil {
+		t.Error(`err != nil || message != nil`)
+	}
+
+	body := `{"jobName": "build"}`
+	messageId, err := server.QueueMessage(dotFiles, "PipelineAgentJobRequest", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := ado.GetMessage(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message == nil || *message.MessageId != messageId || *message.Body != body || message.Iv != nil {
+		t.Fatal(`message == nil || *message.MessageId != messageId || *message.Body != body || message.Iv != nil`)
+	}
+
+	if message, err := ado.GetMessage(ctx, &messageId); err != nil || message != nil {
+		t.Error(`err != nil || message != nil`)
+	}
+
+	if err := ado.DeleteMessage(ctx, messageId); err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted := server.MessagesDeleted(); len(deleted) != 1 || deleted[0] != messageId {
+		t.Error(`len(deleted) != 1 || deleted[0] != messageId`)
+	}
+
+	if err := ado.DeinitAzureDevOpsTaskAgentSession(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if server.Sessions() != 0 {
+		t.Error(`server.Sessions() != 0`)
+	}
+}
+
+func newBridgeClient(t *testing.T, server *Server) *github.Client {
+	httpClient := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{
+		AccessToken: "registration-token",
+		TokenType:   "RemoteAuth",
+	}))
+
+	client, err := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", httpClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestTenantCredential(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	client := newBridgeClient(t, server)
+
+	tenantCredential, _, err := client.Actions.CreateTenantCredential(context.Background(), "register", "https://github.example.com/inloco/kube-actions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tenantCredential.GetURL() != server.URL || tenantCredential.GetToken() == "" || tenantCredential.GetTokenSchema() != "OAuthAccessToken" {
+		t.Error(`tenantCredential.GetURL() != server.URL || tenantCredential.GetToken() == "" || tenantCredential.GetTokenSchema() != "OAuthAccessToken"`)
+	}
+
+	if _, _, err := client.Actions.CreateTenantCredential(context.Background(), "unknown", "https://github.example.com/inloco/kube-actions"); err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func TestAgentRegistration(t *testing.T) {
+	ctx := context.Background()
+
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	tenantCredential, _, err := newBridgeClient(t, server).Actions.CreateTenantCredential(ctx, "register", "https://github.example.com/inloco/kube-actions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaParameters, err := dot.NewRSAParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dotFiles := &dot.Files{RSAParameters: *rsaParameters}
+	dotFiles.Runner.AgentName = "runner"
+	dotFiles.Runner.ServerUrl = tenantCredential.GetURL()
+
+	var registered facades.AzureDevOps
+	if err := registered.InitForCRUD(ctx, dotFiles, []string{"linux"}, "", tenantCredential.GetToken(), tenantCredential.GetURL()); err != nil {
+		t.Fatal(err)
+	}
+
+	agentId := dotFiles.Runner.AgentId
+	if agentId == 0 || dotFiles.Credentials.Data.ClientId == "" {
+		t.Fatal(`agentId == 0 || dotFiles.Credentials.Data.ClientId == ""`)
+	}
+
+	// registering it again replaces the agent, keeping its id and credentials
+	var replaced facades.AzureDevOps
+	if err := replaced.InitForCRUD(ctx, dotFiles, []string{"gpu"}, "", tenantCredential.GetToken(), tenantCredential.GetURL()); err != nil {
+		t.Fatal(err)
+	}
+
+	if dotFiles.Runner.AgentId != agentId {
+		t.Error(`dotFiles.Runner.AgentId != agentId`)
+	}
+
+	if labels := replaced.TaskAgent.Labels; labels == nil || !reflect.DeepEqual(*labels, facades.AgentLabels([]string{"gpu"})) {
+		t.Error(`labels == nil || !reflect.DeepEqual(*labels, facades.AgentLabels([]string{"gpu"}))`)
+	}
+
+	var run facades.AzureDevOps
+	if err := run.InitForRun(ctx, dotFiles, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var removal facades.AzureDevOps
+	if err := removal.InitForRemoval(ctx, dotFiles, "", tenantCredential.GetToken(), tenantCredential.GetURL()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removal.RemoveAgent(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// removed agents can't get a token anymore
+	var removed facades.AzureDevOps
+	if err := removed.InitForRun(ctx, dotFiles, nil); !errors.Is(err, util.ErrOAuth2InvalidClient) {
+		t.Error(`!errors.Is(err, util.ErrOAuth2InvalidClient)`)
+	}
+}
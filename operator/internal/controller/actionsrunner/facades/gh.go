@@ -22,11 +22,11 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
 
@@ -34,9 +34,10 @@ import (
 	"github.com/go-jose/go-jose/v3/jwt"
 	"github.com/google/go-github/v32/github"
 	"github.com/inloco/kube-actions/operator/metrics"
-	"github.com/patrickmn/go-cache"
 	"golang.org/x/oauth2"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
 )
 
 const (
@@ -46,6 +47,8 @@ const (
 
 var (
 	pem2base64 = regexp.MustCompile(`(-----.+?-----)|[\n ]`)
+
+	ErrNotInGitHubOwners = errors.New("not in githubOwners")
 )
 
 type repositoryVisibility uint8
@@ -56,59 +59,39 @@ const (
 )
 
 var (
-	githubPAT     = os.Getenv("KUBEACTIONS_GITHUB_PAT")
-	githubAppId   = os.Getenv("KUBEACTIONS_GITHUB_APP_ID")
-	githubAppPK   = os.Getenv("KUBEACTIONS_GITHUB_APP_PK")
-	githubInstlId = os.Getenv("KUBEACTIONS_GITHUB_INSTL_ID")
+	githubOwners       = parseGitHubOwners(strings.Split(os.Getenv("KUBEACTIONS_GITHUB_OWNERS"), ","))
+	githubVisibilities = parseGitHubVisibilities(strings.Split(os.Getenv("KUBEACTIONS_GITHUB_VISIBILITIES"), ","))
+)
 
-	githubOwners = func() map[string]struct{} {
-		allowed := make(map[string]struct{})
+func parseGitHubOwners(owners []string) map[string]struct{} {
+	allowed := make(map[string]struct{})
 
-		owners := os.Getenv("KUBEACTIONS_GITHUB_OWNERS")
-		for _, allowedOwner := range strings.Split(owners, ",") {
-			allowed[allowedOwner] = struct{}{}
-		}
+	for _, allowedOwner := range owners {
+		allowed[allowedOwner] = struct{}{}
+	}
 
-		return allowed
-	}()
+	return allowed
+}
 
-	githubVisibilities = func() repositoryVisibility {
-		allowed := repositoryVisibility(0)
+func parseGitHubVisibilities(visibilities []string) repositoryVisibility {
+	allowed := repositoryVisibility(0)
 
-		visibility := os.Getenv("KUBEACTIONS_GITHUB_VISIBILITIES")
-		for _, allowedVisibility := range strings.Split(visibility, ",") {
-			switch allowedVisibility {
-			case "pub", "public":
-				allowed |= repoPublic
+	for _, allowedVisibility := range visibilities {
+		switch allowedVisibility {
+		case "pub", "public":
+			allowed |= repoPublic
 
-			case "priv", "private":
-				allowed |= repoPrivate
+		case "priv", "private":
+			allowed |= repoPrivate
 
-			default:
-				logger := log.FromContext(context.TODO())
-				logger.Info("unknown visility: " + allowedVisibility)
-			}
+		default:
+			logger := log.FromContext(context.TODO())
+			logger.Info("unknown visility: " + allowedVisibility)
 		}
+	}
 
-		return allowed
-	}()
-
-	githubClient       *github.Client
-	githubClientExpiry time.Time
-	githubClientMutext sync.Mutex
-
-	githubRepositories       = cache.New(time.Hour, time.Hour)
-	githubRepositoriesMutext sync.Mutex
-
-	githubRegistrationTokens       = cache.New(20*time.Minute, 20*time.Minute)
-	githubRegistrationTokensMutext sync.Mutex
-
-	githubRemoveTokens       = cache.New(20*time.Minute, 20*time.Minute)
-	githubRemoveTokensMutext sync.Mutex
-
-	githubTenantCredentials       = cache.New(time.Hour, time.Hour)
-	githubTenantCredentialsMutext sync.Mutex
-)
+	return allowed
+}
 
 func collectGitHubRateLimitMetrics(ctx context.Context, client *github.Client, clientName string) error {
 	logger := log.FromContext(ctx)
@@ -184,16 +167,16 @@ func handleGitHubResponse(ctx context.Context, client *github.Client, clientName
 	return nil
 }
 
-func getGitHubAppToken() (string, error) {
-	if githubAppId == "" {
-		return "", errors.New(`githubAppId == ""`)
+func getGitHubAppToken(credentials githubCredentials) (string, error) {
+	if credentials.AppId == "" {
+		return "", errors.New(`credentials.AppId == ""`)
 	}
 
-	if githubAppPK == "" {
-		return "", errors.New(`githubAppPK == ""`)
+	if credentials.AppPK == "" {
+		return "", errors.New(`credentials.AppPK == ""`)
 	}
 
-	der, err := base64.StdEncoding.DecodeString(string(pem2base64.ReplaceAll([]byte(githubAppPK), []byte{})))
+	der, err := base64.StdEncoding.DecodeString(string(pem2base64.ReplaceAll([]byte(credentials.AppPK), []byte{})))
 	if err != nil {
 		return "", err
 	}
@@ -216,7 +199,7 @@ func getGitHubAppToken() (string, error) {
 
 	now := time.Now()
 	claims := jwt.Claims{
-		Issuer:   githubAppId,
+		Issuer:   credentials.AppId,
 		Expiry:   jwt.NewNumericDate(now.Add(expJWT)),
 		IssuedAt: jwt.NewNumericDate(now),
 	}
@@ -229,27 +212,18 @@ func getGitHubAppToken() (string, error) {
 	return token, nil
 }
 
-func newGitHubAppClient(ctx context.Context) (*github.Client, error) {
-	token, err := getGitHubAppToken()
+func (e *githubEndpoint) newAppClient(ctx context.Context) (*github.Client, error) {
+	token, err := getGitHubAppToken(e.credentials)
 	if err != nil {
 		return nil, err
 	}
 
-	appClient := github.NewClient(
-		oauth2.NewClient(
-			ctx,
-			oauth2.StaticTokenSource(
-				&oauth2.Token{
-					AccessToken: token,
-				},
-			),
-		),
-	)
-
-	return appClient, nil
+	return e.newClient(ctx, &oauth2.Token{
+		AccessToken: token,
+	})
 }
 
-func getGitHubInstallationToken(ctx context.Context, appClient *github.Client) (*github.InstallationToken, error) {
+func (e *githubEndpoint) getInstallationToken(ctx context.Context, appClient *github.Client) (*github.InstallationToken, error) {
 	logger := log.FromContext(ctx)
 
 	if appClient == nil {
@@ -257,18 +231,18 @@ func getGitHubInstallationToken(ctx context.Context, appClient *github.Client) (
 	}
 
 	var installationId int64
-	if githubInstlId != "" {
-		id, err := strconv.ParseInt(githubInstlId, 10, 0)
+	if e.credentials.InstlId != "" {
+		id, err := strconv.ParseInt(e.credentials.InstlId, 10, 0)
 		if err != nil {
 			return nil, err
 		}
 
 		installationId = id
 	} else {
-		logger.Info(`githubInstlId == ""`)
+		logger.Info(`credentials.InstlId == ""`)
 
 		installations, githubResponse, err := appClient.Apps.ListInstallations(ctx, nil)
-		if err := handleGitHubResponse(ctx, appClient, "app", githubResponse, err); err != nil {
+		if err := handleGitHubResponse(ctx, appClient, e.clientName("app"), githubResponse, err); err != nil {
 			return nil, err
 		}
 
@@ -280,93 +254,99 @@ func getGitHubInstallationToken(ctx context.Context, appClient *github.Client) (
 	}
 
 	installationToken, githubResponse, err := appClient.Apps.CreateInstallationToken(ctx, installationId, nil)
-	if err := handleGitHubResponse(ctx, appClient, "app", githubResponse, err); err != nil {
+	if err := handleGitHubResponse(ctx, appClient, e.clientName("app"), githubResponse, err); err != nil {
 		return nil, err
 	}
 
 	return installationToken, nil
 }
 
-func initGitHubClient(ctx context.Context) error {
-	githubClientMutext.Lock()
-	defer githubClientMutext.Unlock()
+func getGitHubRepository(ctx context.Context, endpoint *githubEndpoint, owner string, name string) (*github.Repository, error) {
+	key := fmt.Sprintf("%s/%s", owner, name)
 
-	if githubClient != nil && (githubClientExpiry.IsZero() || githubClientExpiry.After(time.Now().Add(time.Minute))) {
-		return nil
+	if repository, ok := endpoint.repositories.Get(key); ok {
+		metrics.IncGitHubCacheHitCollector("repository", true)
+		return repository.(*github.Repository), nil
 	}
 
-	token := oauth2.Token{
-		AccessToken: githubPAT,
+	endpoint.repositoriesMutext.Lock()
+	defer endpoint.repositoriesMutext.Unlock()
+
+	if repository, ok := endpoint.repositories.Get(key); ok {
+		metrics.IncGitHubCacheHitCollector("repository", true)
+		return repository.(*github.Repository), nil
 	}
-	if token.AccessToken == "" {
-		appClient, err := newGitHubAppClient(ctx)
-		if err != nil {
-			return err
-		}
 
-		githubIAT, err := getGitHubInstallationToken(ctx, appClient)
-		if err != nil {
-			return err
-		}
+	metrics.IncGitHubCacheHitCollector("repository", false)
 
-		token.AccessToken = githubIAT.GetToken()
-		token.Expiry = githubIAT.GetExpiresAt()
+	client := endpoint.currentClient()
+	if client == nil {
+		return nil, errors.New("client == nil")
 	}
 
-	client := github.NewClient(
-		oauth2.NewClient(
-			ctx,
-			oauth2.StaticTokenSource(&token),
-		),
-	)
+	repository, githubResponse, err := client.Repositories.Get(ctx, owner, name)
+	if err := handleGitHubResponse(ctx, client, endpoint.clientName("entry"), githubResponse, err); err != nil {
+		return nil, err
+	}
 
-	if err := collectGitHubRateLimitMetrics(ctx, client, ""); err != nil {
-		return err
+	if _, ok := endpoint.owners[repository.GetOwner().GetLogin()]; !ok {
+		return nil, ErrNotInGitHubOwners
 	}
 
-	githubClient = client
-	githubClientExpiry = token.Expiry
-	return nil
-}
+	if private := repository.GetPrivate(); (private && endpoint.visibilities&repoPrivate == 0) || (!private && endpoint.visibilities&repoPublic == 0) {
+		return nil, errors.New("not in githubVisibilities")
+	}
 
-func getGitHubRepository(ctx context.Context, owner string, name string) (*github.Repository, error) {
-	key := fmt.Sprintf("%s/%s", owner, name)
+	endpoint.repositories.SetDefault(key, repository)
 
-	if repository, ok := githubRepositories.Get(key); ok {
-		metrics.IncGitHubCacheHitCollector("repository", true)
-		return repository.(*github.Repository), nil
+	return repository, nil
+}
+
+func getGitHubOrganization(ctx context.Context, endpoint *githubEndpoint, login string) (*github.Organization, error) {
+	if organization, ok := endpoint.organizations.Get(login); ok {
+		metrics.IncGitHubCacheHitCollector("organization", true)
+		return organization.(*github.Organization), nil
 	}
 
-	githubRepositoriesMutext.Lock()
-	defer githubRepositoriesMutext.Unlock()
+	endpoint.organizationsMutext.Lock()
+	defer endpoint.organizationsMutext.Unlock()
 
-	if repository, ok := githubRepositories.Get(key); ok {
-		metrics.IncGitHubCacheHitCollector("repository", true)
-		return repository.(*github.Repository), nil
+	if organization, ok := endpoint.organizations.Get(login); ok {
+		metrics.IncGitHubCacheHitCollector("organization", true)
+		return organization.(*github.Organization), nil
 	}
 
-	metrics.IncGitHubCacheHitCollector("repository", false)
+	metrics.IncGitHubCacheHitCollector("organization", false)
 
-	if githubClient == nil {
-		return nil, errors.New("githubClient == nil")
+	client := endpoint.currentClient()
+	if client == nil {
+		return nil, errors.New("client == nil")
 	}
 
-	repository, githubResponse, err := githubClient.Repositories.Get(ctx, owner, name)
-	if err := handleGitHubResponse(ctx, githubClient, "entry", githubResponse, err); err != nil {
+	organization, githubResponse, err := client.Organizations.Get(ctx, login)
+	if err := handleGitHubResponse(ctx, client, endpoint.clientName("entry"), githubResponse, err); err != nil {
 		return nil, err
 	}
 
-	if _, ok := githubOwners[repository.GetOwner().GetLogin()]; !ok {
-		return nil, errors.New("not in githubOwners")
+	if _, ok := endpoint.owners[organization.GetLogin()]; !ok {
+		return nil, ErrNotInGitHubOwners
 	}
 
-	if private := repository.GetPrivate(); (private && githubVisibilities&repoPrivate == 0) || (!private && githubVisibilities&repoPublic == 0) {
-		return nil, errors.New("not in githubVisibilities")
-	}
+	endpoint.organizations.SetDefault(login, organization)
+
+	return organization, nil
+}
 
-	githubRepositories.SetDefault(key, repository)
+// go-github has no enterprise runner endpoints, so they are requested by hand
+func createGitHubEnterpriseRunnerToken(ctx context.Context, client *github.Client, enterprise string, tokenType string, v interface{}) (*github.Response, error) {
+	u := fmt.Sprintf("enterprises/%v/actions/runners/%v", enterprise, tokenType)
 
-	return repository, nil
+	req, err := client.NewRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(ctx, req, v)
 }
 
 type registrationTokenContainer struct {
@@ -374,8 +354,8 @@ type registrationTokenContainer struct {
 	Rate  uint64
 }
 
-func tryGetGitHubRegistrationToken(key string) (*github.RegistrationToken, error) {
-	i, ok := githubRegistrationTokens.Get(key)
+func tryGetGitHubRegistrationToken(endpoint *githubEndpoint, key string) (*github.RegistrationToken, error) {
+	i, ok := endpoint.registrationTokens.Get(key)
 	if !ok {
 		return nil, errors.New("githubRegistrationTokens.Load(key) !ok")
 	}
@@ -393,67 +373,79 @@ func tryGetGitHubRegistrationToken(key string) (*github.RegistrationToken, error
 	return container.Token, nil
 }
 
-func getGitHubRegistrationToken(ctx context.Context, repository *github.Repository) (*github.RegistrationToken, error) {
-	if repository == nil {
-		return nil, errors.New("repository == nil")
+func createGitHubRegistrationToken(ctx context.Context, client *github.Client, gh *GitHub) (*github.RegistrationToken, *github.Response, error) {
+	switch gh.Scope {
+	case inlocov1alpha1.ActionsRunnerScopeOrganization:
+		return client.Actions.CreateOrganizationRegistrationToken(ctx, gh.Owner)
+
+	case inlocov1alpha1.ActionsRunnerScopeEnterprise:
+		var registrationToken github.RegistrationToken
+		githubResponse, err := createGitHubEnterpriseRunnerToken(ctx, client, gh.Owner, "registration-token", &registrationToken)
+		return &registrationToken, githubResponse, err
+
+	default:
+		return client.Actions.CreateRegistrationToken(ctx, gh.Repository.GetOwner().GetLogin(), gh.Repository.GetName())
+	}
+}
+
+func getGitHubRegistrationToken(ctx context.Context, gh *GitHub) (*github.RegistrationToken, error) {
+	if gh == nil {
+		return nil, errors.New("gh == nil")
 	}
 
-	key := fmt.Sprintf("%s/%s", repository.GetOwner().GetLogin(), repository.GetName())
+	key := gh.Key()
 
-	if registrationToken, err := tryGetGitHubRegistrationToken(key); err == nil {
+	if registrationToken, err := tryGetGitHubRegistrationToken(gh.endpoint, key); err == nil {
 		metrics.IncGitHubCacheHitCollector("registrationToken", true)
 		return registrationToken, nil
 	}
 
-	githubRegistrationTokensMutext.Lock()
-	defer githubRegistrationTokensMutext.Unlock()
+	gh.endpoint.registrationTokensMutext.Lock()
+	defer gh.endpoint.registrationTokensMutext.Unlock()
 
-	if registrationToken, err := tryGetGitHubRegistrationToken(key); err == nil {
+	if registrationToken, err := tryGetGitHubRegistrationToken(gh.endpoint, key); err == nil {
 		metrics.IncGitHubCacheHitCollector("registrationToken", true)
 		return registrationToken, nil
 	}
 
 	metrics.IncGitHubCacheHitCollector("registrationToken", false)
 
-	if githubClient == nil {
-		return nil, errors.New("githubClient == nil")
+	client := gh.endpoint.currentClient()
+	if client == nil {
+		return nil, errors.New("client == nil")
 	}
 
-	registrationToken, githubResponse, err := githubClient.Actions.CreateRegistrationToken(ctx, repository.GetOwner().GetLogin(), repository.GetName())
-	if err := handleGitHubResponse(ctx, githubClient, "entry", githubResponse, err); err != nil {
+	registrationToken, githubResponse, err := createGitHubRegistrationToken(ctx, client, gh)
+	if err := handleGitHubResponse(ctx, client, gh.endpoint.clientName("entry"), githubResponse, err); err != nil {
 		return nil, err
 	}
 
-	githubRegistrationTokens.SetDefault(key, &registrationTokenContainer{
+	gh.endpoint.registrationTokens.SetDefault(key, &registrationTokenContainer{
 		Token: registrationToken,
 	})
 
 	return registrationToken, nil
 }
 
-func newGitHubBridgeClientWithRegistrationToken(ctx context.Context, repository *github.Repository) (*github.Client, error) {
-	if repository == nil {
-		return nil, errors.New("repository == nil")
+func newGitHubBridgeClientWithRegistrationToken(ctx context.Context, gh *GitHub) (*github.Client, error) {
+	if gh == nil {
+		return nil, errors.New("gh == nil")
 	}
 
-	registrationToken, err := getGitHubRegistrationToken(ctx, repository)
+	registrationToken, err := getGitHubRegistrationToken(ctx, gh)
 	if err != nil {
 		return nil, err
 	}
 
-	bridgeClient := github.NewClient(
-		oauth2.NewClient(
-			ctx,
-			oauth2.StaticTokenSource(
-				&oauth2.Token{
-					AccessToken: registrationToken.GetToken(),
-					TokenType:   "RemoteAuth",
-				},
-			),
-		),
-	)
+	bridgeClient, err := gh.endpoint.newClient(ctx, &oauth2.Token{
+		AccessToken: registrationToken.GetToken(),
+		TokenType:   "RemoteAuth",
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	if err := collectGitHubRateLimitMetrics(ctx, bridgeClient, "bridge"); err != nil {
+	if err := collectGitHubRateLimitMetrics(ctx, bridgeClient, gh.endpoint.clientName("bridge")); err != nil {
 		return nil, err
 	}
 
@@ -465,8 +457,8 @@ type removeTokenContainer struct {
 	Rate  uint64
 }
 
-func tryGetGitHubRemoveToken(key string) (*github.RemoveToken, error) {
-	i, ok := githubRemoveTokens.Get(key)
+func tryGetGitHubRemoveToken(endpoint *githubEndpoint, key string) (*github.RemoveToken, error) {
+	i, ok := endpoint.removeTokens.Get(key)
 	if !ok {
 		return nil, errors.New("githubRemoveTokens.Get(key) !ok")
 	}
@@ -484,75 +476,87 @@ func tryGetGitHubRemoveToken(key string) (*github.RemoveToken, error) {
 	return container.Token, nil
 }
 
-func getGitHubRemoveToken(ctx context.Context, repository *github.Repository) (*github.RemoveToken, error) {
-	if repository == nil {
-		return nil, errors.New("repository == nil")
+func createGitHubRemoveToken(ctx context.Context, client *github.Client, gh *GitHub) (*github.RemoveToken, *github.Response, error) {
+	switch gh.Scope {
+	case inlocov1alpha1.ActionsRunnerScopeOrganization:
+		return client.Actions.CreateOrganizationRemoveToken(ctx, gh.Owner)
+
+	case inlocov1alpha1.ActionsRunnerScopeEnterprise:
+		var removeToken github.RemoveToken
+		githubResponse, err := createGitHubEnterpriseRunnerToken(ctx, client, gh.Owner, "remove-token", &removeToken)
+		return &removeToken, githubResponse, err
+
+	default:
+		return client.Actions.CreateRemoveToken(ctx, gh.Repository.GetOwner().GetLogin(), gh.Repository.GetName())
+	}
+}
+
+func getGitHubRemoveToken(ctx context.Context, gh *GitHub) (*github.RemoveToken, error) {
+	if gh == nil {
+		return nil, errors.New("gh == nil")
 	}
 
-	key := fmt.Sprintf("%s/%s", repository.GetOwner().GetLogin(), repository.GetName())
+	key := gh.Key()
 
-	if removeToken, err := tryGetGitHubRemoveToken(key); err == nil {
+	if removeToken, err := tryGetGitHubRemoveToken(gh.endpoint, key); err == nil {
 		metrics.IncGitHubCacheHitCollector("removeToken", true)
 		return removeToken, nil
 	}
 
-	githubRemoveTokensMutext.Lock()
-	defer githubRemoveTokensMutext.Unlock()
+	gh.endpoint.removeTokensMutext.Lock()
+	defer gh.endpoint.removeTokensMutext.Unlock()
 
-	if removeToken, err := tryGetGitHubRemoveToken(key); err == nil {
+	if removeToken, err := tryGetGitHubRemoveToken(gh.endpoint, key); err == nil {
 		metrics.IncGitHubCacheHitCollector("removeToken", true)
 		return removeToken, nil
 	}
 
 	metrics.IncGitHubCacheHitCollector("removeToken", false)
 
-	if githubClient == nil {
-		return nil, errors.New("githubClient == nil")
+	client := gh.endpoint.currentClient()
+	if client == nil {
+		return nil, errors.New("client == nil")
 	}
 
-	removeToken, githubResponse, err := githubClient.Actions.CreateRemoveToken(ctx, repository.GetOwner().GetLogin(), repository.GetName())
-	if err := handleGitHubResponse(ctx, githubClient, "entry", githubResponse, err); err != nil {
+	removeToken, githubResponse, err := createGitHubRemoveToken(ctx, client, gh)
+	if err := handleGitHubResponse(ctx, client, gh.endpoint.clientName("entry"), githubResponse, err); err != nil {
 		return nil, err
 	}
 
-	githubRemoveTokens.SetDefault(key, &removeTokenContainer{
+	gh.endpoint.removeTokens.SetDefault(key, &removeTokenContainer{
 		Token: removeToken,
 	})
 
 	return removeToken, nil
 }
 
-func newGitHubBridgeClientWithRemoveToken(ctx context.Context, repository *github.Repository) (*github.Client, error) {
-	if repository == nil {
-		return nil, errors.New("repository == nil")
+func newGitHubBridgeClientWithRemoveToken(ctx context.Context, gh *GitHub) (*github.Client, error) {
+	if gh == nil {
+		return nil, errors.New("gh == nil")
 	}
 
-	removeToken, err := getGitHubRemoveToken(ctx, repository)
+	removeToken, err := getGitHubRemoveToken(ctx, gh)
 	if err != nil {
 		return nil, err
 	}
 
-	bridgeClient := github.NewClient(
-		oauth2.NewClient(
-			ctx,
-			oauth2.StaticTokenSource(
-				&oauth2.Token{
-					AccessToken: removeToken.GetToken(),
-					TokenType:   "RemoteAuth",
-				},
-			),
-		),
-	)
+	bridgeClient, err := gh.endpoint.newClient(ctx, &oauth2.Token{
+		AccessToken: removeToken.GetToken(),
+		TokenType:   "RemoteAuth",
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	if err := collectGitHubRateLimitMetrics(ctx, bridgeClient, "bridge"); err != nil {
+	if err := collectGitHubRateLimitMetrics(ctx, bridgeClient, gh.endpoint.clientName("bridge")); err != nil {
 		return nil, err
 	}
 
 	return bridgeClient, nil
 }
 
-func tryGetGitHubTenantCredential(key string) (*github.TenantCredential, error) {
-	i, ok := githubTenantCredentials.Get(key)
+func tryGetGitHubTenantCredential(endpoint *githubEndpoint, key string) (*github.TenantCredential, error) {
+	i, ok := endpoint.tenantCredentials.Get(key)
 	if !ok {
 		return nil, errors.New("githubTenantCredentials.Load(key) !ok")
 	}
@@ -586,22 +590,22 @@ const (
 	RunnerEventRemove   RunnerEvent = "remove"
 )
 
-func GetGitHubTenantCredential(ctx context.Context, repository *github.Repository, runnerEvent RunnerEvent) (*github.TenantCredential, error) {
-	if repository == nil {
-		return nil, errors.New("repository == nil")
+func GetGitHubTenantCredential(ctx context.Context, gh *GitHub, runnerEvent RunnerEvent) (*github.TenantCredential, error) {
+	if gh == nil {
+		return nil, errors.New("gh == nil")
 	}
 
-	key := fmt.Sprintf("%s@%s/%s", string(runnerEvent), repository.GetOwner().GetLogin(), repository.GetName())
+	key := fmt.Sprintf("%s@%s", string(runnerEvent), gh.Key())
 
-	if tenantCredential, err := tryGetGitHubTenantCredential(key); err == nil {
+	if tenantCredential, err := tryGetGitHubTenantCredential(gh.endpoint, key); err == nil {
 		metrics.IncGitHubCacheHitCollector("tenantCredential", true)
 		return tenantCredential, nil
 	}
 
-	githubTenantCredentialsMutext.Lock()
-	defer githubTenantCredentialsMutext.Unlock()
+	gh.endpoint.tenantCredentialsMutext.Lock()
+	defer gh.endpoint.tenantCredentialsMutext.Unlock()
 
-	if tenantCredential, err := tryGetGitHubTenantCredential(key); err == nil {
+	if tenantCredential, err := tryGetGitHubTenantCredential(gh.endpoint, key); err == nil {
 		metrics.IncGitHubCacheHitCollector("tenantCredential", true)
 		return tenantCredential, nil
 	}
@@ -611,14 +615,14 @@ func GetGitHubTenantCredential(ctx context.Context, repository *github.Repositor
 	var bridgeClient *github.Client
 	switch runnerEvent {
 	case RunnerEventRegister:
-		client, err := newGitHubBridgeClientWithRegistrationToken(ctx, repository)
+		client, err := newGitHubBridgeClientWithRegistrationToken(ctx, gh)
 		if err != nil {
 			return nil, err
 		}
 		bridgeClient = client
 
 	case RunnerEventRemove:
-		client, err := newGitHubBridgeClientWithRemoveToken(ctx, repository)
+		client, err := newGitHubBridgeClientWithRemoveToken(ctx, gh)
 		if err != nil {
 			return nil, err
 		}
@@ -628,30 +632,113 @@ func GetGitHubTenantCredential(ctx context.Context, repository *github.Repositor
 		return nil, errors.New("unknown runnerEvent: " + string(runnerEvent))
 	}
 
-	tenantCredential, githubResponse, err := bridgeClient.Actions.CreateTenantCredential(ctx, string(runnerEvent), repository.GetHTMLURL())
-	if err := handleGitHubResponse(ctx, bridgeClient, "bridge-"+key, githubResponse, err); err != nil {
+	tenantCredential, githubResponse, err := bridgeClient.Actions.CreateTenantCredential(ctx, string(runnerEvent), gh.HTMLURL())
+	if err := handleGitHubResponse(ctx, bridgeClient, gh.endpoint.clientName("bridge-"+key), githubResponse, err); err != nil {
 		return nil, err
 	}
 
-	githubTenantCredentials.SetDefault(key, tenantCredential)
+	gh.endpoint.tenantCredentials.SetDefault(key, tenantCredential)
 
 	return tenantCredential, nil
 }
 
 type GitHub struct {
-	Repository *github.Repository
+	Endpoint *GitHubEndpoint // replaces the endpoint of the repositories pointing at it
+	endpoint *githubEndpoint
+
+	Scope        inlocov1alpha1.ActionsRunnerScope
+	Owner        string
+	Repository   *github.Repository
+	Organization *github.Organization
 }
 
-func (gh *GitHub) Init(ctx context.Context, repoOwner string, repoName string) error {
-	if err := initGitHubClient(ctx); err != nil {
-		return err
+func (gh *GitHub) getEndpoint(apiEndpoint string) (*githubEndpoint, error) {
+	if gh.Endpoint == nil {
+		return getGitHubEndpoint(apiEndpoint)
 	}
 
-	repository, err := getGitHubRepository(ctx, repoOwner, repoName)
+	endpoint, err := gh.Endpoint.get(apiEndpoint)
+	if err != nil || endpoint != nil {
+		return endpoint, err
+	}
+
+	return getGitHubEndpoint(apiEndpoint)
+}
+
+func (gh *GitHub) Init(ctx context.Context, repository inlocov1alpha1.ActionsRunnerRepository) error {
+	endpoint, err := gh.getEndpoint(repository.APIEndpoint)
 	if err != nil {
 		return err
 	}
-	gh.Repository = repository
+
+	if err := endpoint.initClient(ctx); err != nil {
+		return err
+	}
+
+	gh.endpoint = endpoint
+	gh.Scope = repository.GetScope()
+	gh.Owner = repository.Owner
+
+	switch gh.Scope {
+	case inlocov1alpha1.ActionsRunnerScopeRepository:
+		repository, err := getGitHubRepository(ctx, endpoint, repository.Owner, repository.Name)
+		if err != nil {
+			return err
+		}
+		gh.Repository = repository
+
+	case inlocov1alpha1.ActionsRunnerScopeOrganization:
+		organization, err := getGitHubOrganization(ctx, endpoint, repository.Owner)
+		if err != nil {
+			return err
+		}
+		gh.Organization = organization
+
+	case inlocov1alpha1.ActionsRunnerScopeEnterprise:
+		if _, ok := endpoint.owners[repository.Owner]; !ok {
+			return ErrNotInGitHubOwners
+		}
+
+	default:
+		return errors.New("unknown scope: " + string(gh.Scope))
+	}
 
 	return nil
 }
+
+// Key identifies the runner target in caches, e.g. "owner/name", "owner" or "enterprises/owner".
+func (gh *GitHub) Key() string {
+	switch gh.Scope {
+	case inlocov1alpha1.ActionsRunnerScopeOrganization:
+		return gh.Organization.GetLogin()
+
+	case inlocov1alpha1.ActionsRunnerScopeEnterprise:
+		return "enterprises/" + gh.Owner
+
+	default:
+		return fmt.Sprintf("%s/%s", gh.Repository.GetOwner().GetLogin(), gh.Repository.GetName())
+	}
+}
+
+// HTMLURL is the URL runners are registered against.
+func (gh *GitHub) HTMLURL() string {
+	switch gh.Scope {
+	case inlocov1alpha1.ActionsRunnerScopeOrganization:
+		return gh.Organization.GetHTMLURL()
+
+	case inlocov1alpha1.ActionsRunnerScopeEnterprise:
+		return fmt.Sprintf("%s/enterprises/%s", gh.endpoint.htmlURL, gh.Owner)
+
+	default:
+		return gh.Repository.GetHTMLURL()
+	}
+}
+
+// RunnerURL is the URL written to the .runner file.
+func (gh *GitHub) RunnerURL() string {
+	if gh.Repository != nil {
+		return gh.Repository.GetGitCommitsURL()
+	}
+
+	return gh.HTMLURL()
+}
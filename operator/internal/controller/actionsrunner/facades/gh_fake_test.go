@@ -0,0 +1,68 @@
+package facades_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades/fake"
+)
+
+func TestGitHubAgainstFake(t *testing.T) {
+	ctx := context.Background()
+
+	server, err := fake.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	endpoint := &facades.GitHubEndpoint{
+		APIEndpoint:  server.GitHubAPIEndpoint(),
+		PAT:          fake.GitHubPAT,
+		HTTPClient:   server.Client(),
+		Owners:       []string{"inloco"},
+		Visibilities: []string{"public", "private"},
+	}
+
+	server.AddRepository("inloco", "kube-actions")
+	server.AddOrganization("inloco")
+
+	for _, repository := range []inlocov1alpha1.ActionsRunnerRepository{
+		{Owner: "inloco", Name: "kube-actions", APIEndpoint: server.GitHubAPIEndpoint()},
+		{Scope: inlocov1alpha1.ActionsRunnerScopeOrganization, Owner: "inloco", APIEndpoint: server.GitHubAPIEndpoint()},
+	} {
+		gh := facades.GitHub{Endpoint: endpoint}
+		if err := gh.Init(ctx, repository); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, runnerEvent := range []facades.RunnerEvent{facades.RunnerEventRegister, facades.RunnerEventRemove} {
+			credential, err := facades.GetGitHubTenantCredential(ctx, &gh, runnerEvent)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if credential.GetURL() != server.URL || credential.GetToken() == "" {
+				t.Error(`credential.GetURL() != server.URL || credential.GetToken() == ""`)
+			}
+		}
+	}
+
+	if len(server.RunnerTokens()) != 4 {
+		t.Error(`len(server.RunnerTokens()) != 4`)
+	}
+
+	gh := facades.GitHub{Endpoint: endpoint}
+	err = gh.Init(ctx, inlocov1alpha1.ActionsRunnerRepository{Owner: "inloco", Name: "gone", APIEndpoint: server.GitHubAPIEndpoint()})
+
+	var errorResponse *github.ErrorResponse
+	if !errors.As(err, &errorResponse) || errorResponse.Response.StatusCode != http.StatusNotFound {
+		t.Error(`!errors.As(err, &errorResponse) || errorResponse.Response.StatusCode != http.StatusNotFound`)
+	}
+}
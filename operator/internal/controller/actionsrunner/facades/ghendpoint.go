@@ -0,0 +1,428 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package facades
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/patrickmn/go-cache"
+	"golang.org/x/oauth2"
+)
+
+const (
+	githubHost = "github.com"
+)
+
+var (
+	githubCABundle = os.Getenv("KUBEACTIONS_GITHUB_CA_BUNDLE")
+	githubProxy    = os.Getenv("KUBEACTIONS_GITHUB_PROXY")
+
+	// GitHub Enterprise Server hosts ActionsRunners may point at, github.com is always allowed
+	githubAllowedHosts = func() map[string]bool {
+		hosts := make(map[string]bool)
+		for _, endpoint := range strings.Split(os.Getenv("KUBEACTIONS_GITHUB_ENDPOINTS"), ",") {
+			endpoint = strings.TrimSpace(endpoint)
+			if endpoint == "" {
+				continue
+			}
+
+			if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+				endpoint = u.Host
+			}
+
+			hosts[strings.ToLower(endpoint)] = true
+		}
+
+		return hosts
+	}()
+
+	envSuffixReplacer = regexp.MustCompile(`[^A-Z0-9]+`)
+
+	githubEndpoints       = make(map[string]*githubEndpoint)
+	githubEndpointsMutext sync.Mutex
+)
+
+type githubCredentials struct {
+	PAT     string
+	AppId   string
+	AppPK   string
+	InstlId string
+}
+
+// the default variables only hold credentials for github.com, other hosts need their own ones, e.g.
+// KUBEACTIONS_GITHUB_PAT_GHE_EXAMPLE_COM, so they are never sent to a server picked by whoever creates an ActionsRunner
+func getGitHubCredentials(host string) (githubCredentials, error) {
+	suffix := ""
+	if host != githubHost {
+		suffix = "_" + strings.Trim(envSuffixReplacer.ReplaceAllString(strings.ToUpper(host), "_"), "_")
+	}
+
+	credentials := githubCredentials{
+		PAT:     os.Getenv("KUBEACTIONS_GITHUB_PAT" + suffix),
+		AppId:   os.Getenv("KUBEACTIONS_GITHUB_APP_ID" + suffix),
+		AppPK:   os.Getenv("KUBEACTIONS_GITHUB_APP_PK" + suffix),
+		InstlId: os.Getenv("KUBEACTIONS_GITHUB_INSTL_ID" + suffix),
+	}
+
+	if host != githubHost && credentials.PAT == "" && credentials.AppId == "" {
+		return credentials, fmt.Errorf("no credentials for %s, neither KUBEACTIONS_GITHUB_PAT%s nor KUBEACTIONS_GITHUB_APP_ID%s are set", host, suffix, suffix)
+	}
+
+	return credentials, nil
+}
+
+// parseGitHubAPIEndpoint returns the scheme and host of apiEndpoint, nil for github.com
+func parseGitHubAPIEndpoint(apiEndpoint string) (*url.URL, error) {
+	if apiEndpoint == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(apiEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid apiEndpoint: %s", apiEndpoint)
+	}
+
+	if host := strings.ToLower(u.Hostname()); host == githubHost || host == "api."+githubHost {
+		return nil, nil
+	}
+
+	return &url.URL{
+		Scheme: strings.ToLower(u.Scheme),
+		Host:   strings.ToLower(u.Host),
+	}, nil
+}
+
+// ValidateGitHubAPIEndpoint checks apiEndpoint is github.com or one of the hosts in KUBEACTIONS_GITHUB_ENDPOINTS
+func ValidateGitHubAPIEndpoint(apiEndpoint string) error {
+	u, err := parseGitHubAPIEndpoint(apiEndpoint)
+	if err != nil {
+		return err
+	}
+
+	if u != nil && !githubAllowedHosts[u.Host] {
+		return fmt.Errorf("%s is not in KUBEACTIONS_GITHUB_ENDPOINTS", u.Host)
+	}
+
+	return nil
+}
+
+func newGitHubHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if githubProxy != "" {
+		proxyURL, err := url.Parse(githubProxy)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if githubCABundle != "" {
+		pem, err := os.ReadFile(githubCABundle)
+		if err != nil {
+			return nil, err
+		}
+
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + githubCABundle)
+		}
+
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    rootCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}, nil
+}
+
+// githubEndpoint holds the client and caches of a single GitHub instance
+type githubEndpoint struct {
+	host    string
+	baseURL string // empty for github.com
+	htmlURL string
+
+	credentials githubCredentials
+	httpClient  *http.Client
+
+	owners       map[string]struct{}
+	visibilities repositoryVisibility
+
+	client       *github.Client
+	clientExpiry time.Time
+	clientMutext sync.Mutex
+
+	repositories       *cache.Cache
+	repositoriesMutext sync.Mutex
+
+	organizations       *cache.Cache
+	organizationsMutext sync.Mutex
+
+	registrationTokens       *cache.Cache
+	registrationTokensMutext sync.Mutex
+
+	removeTokens       *cache.Cache
+	removeTokensMutext sync.Mutex
+
+	tenantCredentials       *cache.Cache
+	tenantCredentialsMutext sync.Mutex
+
+	workflowJobs       *cache.Cache
+	workflowJobsMutext sync.Mutex
+
+	rate       github.Rate
+	rateMutext sync.RWMutex
+}
+
+// makeGitHubEndpoint returns the endpoint of u, github.com when nil, without credentials and with the owners and
+// visibilities of the environment
+func makeGitHubEndpoint(u *url.URL) *githubEndpoint {
+	endpoint := githubEndpoint{
+		host:    githubHost,
+		htmlURL: "https://" + githubHost,
+
+		owners:       githubOwners,
+		visibilities: githubVisibilities,
+
+		repositories:       cache.New(time.Hour, time.Hour),
+		organizations:      cache.New(time.Hour, time.Hour),
+		registrationTokens: cache.New(20*time.Minute, 20*time.Minute),
+		removeTokens:       cache.New(20*time.Minute, 20*time.Minute),
+		tenantCredentials:  cache.New(time.Hour, time.Hour),
+		workflowJobs:       cache.New(30*time.Second, time.Minute),
+	}
+
+	if u != nil {
+		endpoint.host = u.Host
+		endpoint.baseURL = u.String() + "/"
+		endpoint.htmlURL = u.String()
+	}
+
+	return &endpoint
+}
+
+func newGitHubEndpoint(apiEndpoint string) (*githubEndpoint, error) {
+	if err := ValidateGitHubAPIEndpoint(apiEndpoint); err != nil {
+		return nil, err
+	}
+
+	u, err := parseGitHubAPIEndpoint(apiEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := makeGitHubEndpoint(u)
+
+	credentials, err := getGitHubCredentials(endpoint.host)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := newGitHubHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.credentials = credentials
+	endpoint.httpClient = httpClient
+
+	return endpoint, nil
+}
+
+// getGitHubEndpoint shares endpoints by scheme and host, e.g. https://ghe.example.com and its /api/v3 are the same one
+func getGitHubEndpoint(apiEndpoint string) (*githubEndpoint, error) {
+	u, err := parseGitHubAPIEndpoint(apiEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ""
+	if u != nil {
+		key = u.String()
+	}
+
+	githubEndpointsMutext.Lock()
+	defer githubEndpointsMutext.Unlock()
+
+	if endpoint, ok := githubEndpoints[key]; ok {
+		return endpoint, nil
+	}
+
+	endpoint, err := newGitHubEndpoint(key)
+	if err != nil {
+		return nil, err
+	}
+
+	githubEndpoints[key] = endpoint
+
+	return endpoint, nil
+}
+
+// GitHubEndpoint is a GitHub instance reached with PAT over HTTPClient, rather than with the hosts and credentials
+// configured through the environment, by the ActionsRunners pointing at its APIEndpoint, e.g. a fake one in tests
+type GitHubEndpoint struct {
+	APIEndpoint  string
+	PAT          string
+	HTTPClient   *http.Client // http.DefaultClient when nil
+	Owners       []string     // replace KUBEACTIONS_GITHUB_OWNERS when not nil
+	Visibilities []string     // replace KUBEACTIONS_GITHUB_VISIBILITIES when not nil
+
+	endpoint     *githubEndpoint
+	endpointErr  error
+	endpointOnce sync.Once
+}
+
+// get returns the endpoint of e if it is the one of apiEndpoint, nil otherwise
+func (e *GitHubEndpoint) get(apiEndpoint string) (*githubEndpoint, error) {
+	e.endpointOnce.Do(func() {
+		u, err := parseGitHubAPIEndpoint(e.APIEndpoint)
+		if err != nil {
+			e.endpointErr = err
+			return
+		}
+
+		if u == nil {
+			e.endpointErr = errors.New("github.com can't be replaced")
+			return
+		}
+
+		httpClient := e.HTTPClient
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+
+		e.endpoint = makeGitHubEndpoint(u)
+		e.endpoint.credentials = githubCredentials{PAT: e.PAT}
+		e.endpoint.httpClient = httpClient
+
+		if e.Owners != nil {
+			e.endpoint.owners = parseGitHubOwners(e.Owners)
+		}
+
+		if e.Visibilities != nil {
+			e.endpoint.visibilities = parseGitHubVisibilities(e.Visibilities)
+		}
+	})
+	if e.endpointErr != nil {
+		return nil, e.endpointErr
+	}
+
+	u, err := parseGitHubAPIEndpoint(apiEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if u == nil || u.Host != e.endpoint.host {
+		return nil, nil
+	}
+
+	return e.endpoint, nil
+}
+
+// clientName keeps metric labels of github.com clients unchanged
+func (e *githubEndpoint) clientName(name string) string {
+	if e.host == githubHost {
+		return name
+	}
+
+	return fmt.Sprintf("%s/%s", e.host, name)
+}
+
+func (e *githubEndpoint) newClient(ctx context.Context, token *oauth2.Token) (*github.Client, error) {
+	httpClient := oauth2.NewClient(
+		context.WithValue(ctx, oauth2.HTTPClient, e.httpClient),
+		oauth2.StaticTokenSource(token),
+	)
+
+	if e.baseURL == "" {
+		return github.NewClient(httpClient), nil
+	}
+
+	return github.NewEnterpriseClient(e.baseURL, e.baseURL, httpClient)
+}
+
+func (e *githubEndpoint) initClient(ctx context.Context) error {
+	e.clientMutext.Lock()
+	defer e.clientMutext.Unlock()
+
+	if e.client != nil && (e.clientExpiry.IsZero() || e.clientExpiry.After(time.Now().Add(time.Minute))) {
+		return nil
+	}
+
+	token := oauth2.Token{
+		AccessToken: e.credentials.PAT,
+	}
+	if token.AccessToken == "" {
+		appClient, err := e.newAppClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		githubIAT, err := e.getInstallationToken(ctx, appClient)
+		if err != nil {
+			return err
+		}
+
+		token.AccessToken = githubIAT.GetToken()
+		token.Expiry = githubIAT.GetExpiresAt()
+	}
+
+	client, err := e.newClient(ctx, &token)
+	if err != nil {
+		return err
+	}
+
+	if err := collectGitHubRateLimitMetrics(ctx, client, e.clientName("")); err != nil {
+		return err
+	}
+
+	e.client = client
+	e.clientExpiry = token.Expiry
+	return nil
+}
+
+// currentClient returns the client of the last initClient, callers keep it rather than reading e.client again
+func (e *githubEndpoint) currentClient() *github.Client {
+	e.clientMutext.Lock()
+	defer e.clientMutext.Unlock()
+
+	return e.client
+}
@@ -0,0 +1,131 @@
+package facades
+
+import (
+	"testing"
+)
+
+func allowGitHubHosts(t *testing.T, hosts ...string) {
+	allowedHosts := githubAllowedHosts
+	t.Cleanup(func() {
+		githubAllowedHosts = allowedHosts
+	})
+
+	githubAllowedHosts = make(map[string]bool)
+	for _, host := range hosts {
+		githubAllowedHosts[host] = true
+	}
+}
+
+func TestNewGitHubEndpoint(t *testing.T) {
+	allowGitHubHosts(t, "ghe.example.com")
+	t.Setenv("KUBEACTIONS_GITHUB_PAT_GHE_EXAMPLE_COM", "ghes")
+
+	dotCom, err := newGitHubEndpoint("https://api.github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dotCom.baseURL != "" {
+		t.Error(`dotCom.baseURL != ""`)
+	}
+
+	if dotCom.htmlURL != "https://github.com" {
+		t.Error(`dotCom.htmlURL != "https://github.com"`)
+	}
+
+	if dotCom.clientName("entry") != "entry" {
+		t.Error(`dotCom.clientName("entry") != "entry"`)
+	}
+
+	ghes, err := newGitHubEndpoint("https://ghe.example.com/api/v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ghes.baseURL != "https://ghe.example.com/" {
+		t.Error(`ghes.baseURL != "https://ghe.example.com/"`)
+	}
+
+	if ghes.htmlURL != "https://ghe.example.com" {
+		t.Error(`ghes.htmlURL != "https://ghe.example.com"`)
+	}
+
+	if ghes.clientName("entry") != "ghe.example.com/entry" {
+		t.Error(`ghes.clientName("entry") != "ghe.example.com/entry"`)
+	}
+
+	if _, err := newGitHubEndpoint("ghe.example.com"); err == nil {
+		t.Error(`newGitHubEndpoint("ghe.example.com") == nil`)
+	}
+
+	if _, err := newGitHubEndpoint("https://evil.example.com/api/v3"); err == nil {
+		t.Error(`newGitHubEndpoint("https://evil.example.com/api/v3") == nil`)
+	}
+}
+
+func TestGetGitHubEndpoint(t *testing.T) {
+	allowGitHubHosts(t, "ghe.example.com")
+	t.Setenv("KUBEACTIONS_GITHUB_PAT_GHE_EXAMPLE_COM", "ghes")
+
+	root, err := getGitHubEndpoint("https://ghe.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := getGitHubEndpoint("https://GHE.example.com/api/v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root != api {
+		t.Error(`root != api`)
+	}
+
+	dotCom, err := getGitHubEndpoint("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err = getGitHubEndpoint("https://api.github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dotCom != api {
+		t.Error(`dotCom != api`)
+	}
+}
+
+func TestValidateGitHubAPIEndpoint(t *testing.T) {
+	allowGitHubHosts(t, "ghe.example.com:8443")
+
+	for _, apiEndpoint := range []string{"", "https://api.github.com", "https://ghe.example.com:8443/api/v3"} {
+		if err := ValidateGitHubAPIEndpoint(apiEndpoint); err != nil {
+			t.Errorf(`ValidateGitHubAPIEndpoint(%q) != nil`, apiEndpoint)
+		}
+	}
+
+	for _, apiEndpoint := range []string{"https://ghe.example.com/api/v3", "https://evil.example.com", "evil.example.com"} {
+		if err := ValidateGitHubAPIEndpoint(apiEndpoint); err == nil {
+			t.Errorf(`ValidateGitHubAPIEndpoint(%q) == nil`, apiEndpoint)
+		}
+	}
+}
+
+func TestGetGitHubCredentials(t *testing.T) {
+	t.Setenv("KUBEACTIONS_GITHUB_PAT", "default")
+	t.Setenv("KUBEACTIONS_GITHUB_PAT_GHE_EXAMPLE_COM_8443", "ghes")
+
+	if credentials, err := getGitHubCredentials("github.com"); err != nil || credentials.PAT != "default" {
+		t.Error(`err != nil || credentials.PAT != "default"`)
+	}
+
+	if credentials, err := getGitHubCredentials("ghe.example.com:8443"); err != nil || credentials.PAT != "ghes" {
+		t.Error(`err != nil || credentials.PAT != "ghes"`)
+	}
+
+	// the github.com credentials are never sent to other hosts
+	if credentials, err := getGitHubCredentials("other.example.com"); err == nil || credentials.PAT != "" {
+		t.Error(`err == nil || credentials.PAT != ""`)
+	}
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package facades
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/metrics"
+)
+
+const (
+	workflowJobStatusQueued     = "queued"
+	workflowJobStatusInProgress = "in_progress"
+
+	// keep some requests for registration and removal tokens
+	githubRateReserve = 200
+
+	// pages of 100 listed per repository and status, and per workflow run, before giving up on an exact count
+	maxWorkflowRunPages = 5
+	maxWorkflowJobPages = 5
+)
+
+// RateLimitedError is returned instead of calling GitHub when too few requests are left in the current window.
+type RateLimitedError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("GitHub rate limit reserve reached, resets at %s", e.Reset.Format(time.RFC3339))
+}
+
+func IsRateLimited(err error) (*RateLimitedError, bool) {
+	var rateLimitedError *RateLimitedError
+	if errors.As(err, &rateLimitedError) {
+		return rateLimitedError, true
+	}
+
+	return nil, false
+}
+
+// go-github does not expose the labels of workflow jobs
+type workflowJob struct {
+	Status *string  `json:"status,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+type workflowJobs struct {
+	TotalCount *int           `json:"total_count,omitempty"`
+	Jobs       []*workflowJob `json:"jobs,omitempty"`
+}
+
+type WorkflowJobCounts struct {
+	Queued     uint
+	InProgress uint
+
+	// Truncated is set when some repository had more runs or jobs than listed, so the counts are a lower bound
+	Truncated bool
+}
+
+type pendingWorkflowJobs struct {
+	jobs      []*workflowJob
+	truncated bool
+}
+
+func (e *githubEndpoint) observeRate(response *github.Response) {
+	if response == nil {
+		return
+	}
+
+	e.rateMutext.Lock()
+	defer e.rateMutext.Unlock()
+
+	e.rate = response.Rate
+}
+
+func (e *githubEndpoint) checkRate() error {
+	e.rateMutext.RLock()
+	defer e.rateMutext.RUnlock()
+
+	if e.rate.Limit == 0 || e.rate.Remaining > githubRateReserve {
+		return nil
+	}
+
+	reset := e.rate.Reset.Time
+	if !reset.After(time.Now()) {
+		return nil
+	}
+
+	return &RateLimitedError{
+		Reset: reset,
+	}
+}
+
+func listGitHubWorkflowJobs(ctx context.Context, endpoint *githubEndpoint, client *github.Client, owner string, name string, runId int64) ([]*workflowJob, bool, error) {
+	var all []*workflowJob
+	for page := 1; page <= maxWorkflowJobPages; page++ {
+		if err := endpoint.checkRate(); err != nil {
+			return nil, false, err
+		}
+
+		u := fmt.Sprintf("repos/%v/%v/actions/runs/%v/jobs?per_page=100&page=%v", owner, name, runId, page)
+
+		req, err := client.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, false, err
+		}
+
+		var jobs workflowJobs
+		githubResponse, err := client.Do(ctx, req, &jobs)
+		endpoint.observeRate(githubResponse)
+		if err := handleGitHubResponse(ctx, client, endpoint.clientName("entry"), githubResponse, err); err != nil {
+			return nil, false, err
+		}
+
+		all = append(all, jobs.Jobs...)
+		if githubResponse.NextPage == 0 {
+			return all, false, nil
+		}
+	}
+
+	return all, true, nil
+}
+
+// getGitHubPendingWorkflowJobs returns the queued and in progress jobs of a repository regardless of their labels
+func getGitHubPendingWorkflowJobs(ctx context.Context, endpoint *githubEndpoint, owner string, name string) (*pendingWorkflowJobs, error) {
+	key := fmt.Sprintf("%s/%s", owner, name)
+
+	if pending, ok := endpoint.workflowJobs.Get(key); ok {
+		metrics.IncGitHubCacheHitCollector("workflowJobs", true)
+		return pending.(*pendingWorkflowJobs), nil
+	}
+
+	endpoint.workflowJobsMutext.Lock()
+	defer endpoint.workflowJobsMutext.Unlock()
+
+	if pending, ok := endpoint.workflowJobs.Get(key); ok {
+		metrics.IncGitHubCacheHitCollector("workflowJobs", true)
+		return pending.(*pendingWorkflowJobs), nil
+	}
+
+	metrics.IncGitHubCacheHitCollector("workflowJobs", false)
+
+	client := endpoint.currentClient()
+	if client == nil {
+		return nil, errors.New("client == nil")
+	}
+
+	var pending pendingWorkflowJobs
+	for _, status := range []string{workflowJobStatusQueued, workflowJobStatusInProgress} {
+		opts := github.ListWorkflowRunsOptions{
+			Status: status,
+			ListOptions: github.ListOptions{
+				PerPage: 100,
+			},
+		}
+
+		for page := 1; ; page++ {
+			if page > maxWorkflowRunPages {
+				pending.truncated = true
+				break
+			}
+
+			if err := endpoint.checkRate(); err != nil {
+				return nil, err
+			}
+
+			opts.Page = page
+			runs, githubResponse, err := client.Actions.ListRepositoryWorkflowRuns(ctx, owner, name, &opts)
+			endpoint.observeRate(githubResponse)
+			if err := handleGitHubResponse(ctx, client, endpoint.clientName("entry"), githubResponse, err); err != nil {
+				return nil, err
+			}
+
+			for _, run := range runs.WorkflowRuns {
+				jobs, truncated, err := listGitHubWorkflowJobs(ctx, endpoint, client, owner, name, run.GetID())
+				if err != nil {
+					return nil, err
+				}
+				pending.truncated = pending.truncated || truncated
+
+				for _, job := range jobs {
+					if s := job.Status; s != nil && (*s == workflowJobStatusQueued || *s == workflowJobStatusInProgress) {
+						pending.jobs = append(pending.jobs, job)
+					}
+				}
+			}
+
+			if githubResponse.NextPage == 0 {
+				break
+			}
+		}
+	}
+
+	endpoint.workflowJobs.SetDefault(key, &pending)
+
+	return &pending, nil
+}
+
+func matchesLabels(job *workflowJob, labels map[string]struct{}) bool {
+	for _, label := range job.Labels {
+		if _, ok := labels[strings.ToLower(label)]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// authorizeWorkflowRepository only lets runners count jobs they are able to pick, from repositories the operator manages
+func (gh *GitHub) authorizeWorkflowRepository(ctx context.Context, repository string) (string, string, error) {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository: %s", repository)
+	}
+	owner, name := parts[0], parts[1]
+
+	if _, ok := gh.endpoint.owners[owner]; !ok {
+		return "", "", fmt.Errorf("%s not in githubOwners", repository)
+	}
+
+	switch gh.Scope {
+	case inlocov1alpha1.ActionsRunnerScopeRepository:
+		if !strings.EqualFold(repository, gh.Key()) {
+			return "", "", fmt.Errorf("%s is not the repository of the runners", repository)
+		}
+
+	case inlocov1alpha1.ActionsRunnerScopeOrganization:
+		if !strings.EqualFold(owner, gh.Owner) {
+			return "", "", fmt.Errorf("%s does not belong to %s", repository, gh.Owner)
+		}
+	}
+
+	// also checks githubOwners against the actual owner and githubVisibilities
+	if _, err := getGitHubRepository(ctx, gh.endpoint, owner, name); err != nil {
+		return "", "", fmt.Errorf("%s: %w", repository, err)
+	}
+
+	return owner, name, nil
+}
+
+// CountWorkflowJobs counts the pending jobs of repositories that runners with the given labels are able to pick.
+func (gh *GitHub) CountWorkflowJobs(ctx context.Context, repositories []string, labels []string) (*WorkflowJobCounts, error) {
+	if gh.endpoint == nil {
+		return nil, errors.New("gh.endpoint == nil")
+	}
+
+	runnerLabels := make(map[string]struct{})
+	for _, label := range AgentLabels(labels) {
+		runnerLabels[strings.ToLower(label)] = struct{}{}
+	}
+
+	var counts WorkflowJobCounts
+	for _, repository := range repositories {
+		owner, name, err := gh.authorizeWorkflowRepository(ctx, repository)
+		if err != nil {
+			return nil, err
+		}
+
+		pending, err := getGitHubPendingWorkflowJobs(ctx, gh.endpoint, owner, name)
+		if err != nil {
+			return nil, err
+		}
+		counts.Truncated = counts.Truncated || pending.truncated
+
+		for _, job := range pending.jobs {
+			if !matchesLabels(job, runnerLabels) {
+				continue
+			}
+
+			if *job.Status == workflowJobStatusQueued {
+				counts.Queued++
+			} else {
+				counts.InProgress++
+			}
+		}
+	}
+
+	return &counts, nil
+}
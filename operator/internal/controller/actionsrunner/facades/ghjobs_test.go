@@ -0,0 +1,98 @@
+package facades
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/patrickmn/go-cache"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestAuthorizeWorkflowRepository(t *testing.T) {
+	endpoint := githubEndpoint{
+		owners: map[string]struct{}{"inloco": {}, "incognia": {}},
+	}
+
+	repositoryScoped := GitHub{
+		endpoint:   &endpoint,
+		Scope:      inlocov1alpha1.ActionsRunnerScopeRepository,
+		Owner:      "inloco",
+		Repository: &github.Repository{Owner: &github.User{Login: github.String("inloco")}, Name: github.String("kube-actions")},
+	}
+	organizationScoped := GitHub{
+		endpoint: &endpoint,
+		Scope:    inlocov1alpha1.ActionsRunnerScopeOrganization,
+		Owner:    "inloco",
+	}
+
+	for _, c := range []struct {
+		gh         GitHub
+		repository string
+	}{
+		{repositoryScoped, "kube-actions"},
+		{repositoryScoped, "torvalds/linux"},
+		{repositoryScoped, "inloco/other"},
+		{organizationScoped, "incognia/other"},
+	} {
+		if _, _, err := c.gh.authorizeWorkflowRepository(context.Background(), c.repository); err == nil {
+			t.Errorf(`c.gh.authorizeWorkflowRepository(context.Background(), %q) == nil`, c.repository)
+		}
+	}
+}
+
+func TestGetGitHubPendingWorkflowJobsPaginates(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/inloco/kube-actions/actions/runs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("status") != workflowJobStatusQueued {
+			fmt.Fprint(w, `{"total_count": 0, "workflow_runs": []}`)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 2 {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/api/v3/repos/inloco/kube-actions/actions/runs?page=2>; rel="next"`, r.Host))
+		}
+		fmt.Fprintf(w, `{"total_count": 2, "workflow_runs": [{"id": %d}]}`, page)
+	})
+	mux.HandleFunc("/api/v3/repos/inloco/kube-actions/actions/runs/", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < maxWorkflowJobPages+1 {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d>; rel="next"`, r.Host, r.URL.Path, page+1))
+		}
+		fmt.Fprint(w, `{"total_count": 100, "jobs": [{"status": "queued", "labels": ["self-hosted"]}, {"status": "completed"}]}`)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/v3/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := githubEndpoint{
+		client:       client,
+		workflowJobs: cache.New(time.Minute, time.Minute),
+	}
+
+	pending, err := getGitHubPendingWorkflowJobs(context.Background(), &endpoint, "inloco", "kube-actions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two runs with maxWorkflowJobPages pages of a single queued job each
+	if len(pending.jobs) != 2*maxWorkflowJobPages {
+		t.Error(`len(pending.jobs) != 2*maxWorkflowJobPages`)
+	}
+
+	if !pending.truncated {
+		t.Error(`!pending.truncated`)
+	}
+}
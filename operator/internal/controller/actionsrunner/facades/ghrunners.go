@@ -0,0 +1,131 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package facades
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v32/github"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+const (
+	RunnerStatusOnline  = "online"
+	RunnerStatusOffline = "offline"
+)
+
+func listGitHubRunnersPage(ctx context.Context, client *github.Client, gh *GitHub, opts *github.ListOptions) (*github.Runners, *github.Response, error) {
+	switch gh.Scope {
+	case inlocov1alpha1.ActionsRunnerScopeOrganization:
+		return client.Actions.ListOrganizationRunners(ctx, gh.Owner, opts)
+
+	case inlocov1alpha1.ActionsRunnerScopeEnterprise:
+		u := fmt.Sprintf("enterprises/%v/actions/runners?per_page=%v&page=%v", gh.Owner, opts.PerPage, opts.Page)
+
+		req, err := client.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var runners github.Runners
+		githubResponse, err := client.Do(ctx, req, &runners)
+		return &runners, githubResponse, err
+
+	default:
+		return client.Actions.ListRunners(ctx, gh.Repository.GetOwner().GetLogin(), gh.Repository.GetName(), opts)
+	}
+}
+
+// ListRunners lists every self-hosted runner registered in the runner target, e.g. a repository or an organization.
+func (gh *GitHub) ListRunners(ctx context.Context) ([]*github.Runner, error) {
+	if gh.endpoint == nil {
+		return nil, errors.New("gh.endpoint == nil")
+	}
+
+	client := gh.endpoint.currentClient()
+	if client == nil {
+		return nil, errors.New("client == nil")
+	}
+
+	opts := github.ListOptions{
+		PerPage: 100,
+	}
+
+	var runners []*github.Runner
+	for {
+		if err := gh.endpoint.checkRate(); err != nil {
+			return nil, err
+		}
+
+		page, githubResponse, err := listGitHubRunnersPage(ctx, client, gh, &opts)
+		gh.endpoint.observeRate(githubResponse)
+		if err := handleGitHubResponse(ctx, client, gh.endpoint.clientName("entry"), githubResponse, err); err != nil {
+			return nil, err
+		}
+
+		runners = append(runners, page.Runners...)
+
+		if githubResponse.NextPage == 0 {
+			return runners, nil
+		}
+		opts.Page = githubResponse.NextPage
+	}
+}
+
+func removeGitHubRunner(ctx context.Context, client *github.Client, gh *GitHub, runnerId int64) (*github.Response, error) {
+	switch gh.Scope {
+	case inlocov1alpha1.ActionsRunnerScopeOrganization:
+		return client.Actions.RemoveOrganizationRunner(ctx, gh.Owner, runnerId)
+
+	case inlocov1alpha1.ActionsRunnerScopeEnterprise:
+		u := fmt.Sprintf("enterprises/%v/actions/runners/%v", gh.Owner, runnerId)
+
+		req, err := client.NewRequest(http.MethodDelete, u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return client.Do(ctx, req, nil)
+
+	default:
+		return client.Actions.RemoveRunner(ctx, gh.Repository.GetOwner().GetLogin(), gh.Repository.GetName(), runnerId)
+	}
+}
+
+// RemoveRunner forcibly removes a self-hosted runner from the runner target.
+func (gh *GitHub) RemoveRunner(ctx context.Context, runnerId int64) error {
+	if gh.endpoint == nil {
+		return errors.New("gh.endpoint == nil")
+	}
+
+	client := gh.endpoint.currentClient()
+	if client == nil {
+		return errors.New("client == nil")
+	}
+
+	if err := gh.endpoint.checkRate(); err != nil {
+		return err
+	}
+
+	githubResponse, err := removeGitHubRunner(ctx, client, gh, runnerId)
+	gh.endpoint.observeRate(githubResponse)
+	return handleGitHubResponse(ctx, client, gh.endpoint.clientName("entry"), githubResponse, err)
+}
@@ -22,32 +22,48 @@ import (
 	"os/signal"
 	"reflect"
 	"syscall"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
 	controllers "github.com/inloco/kube-actions/operator/internal/controller"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
 	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/util"
 	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/wire"
 	"github.com/inloco/kube-actions/operator/metrics"
 )
 
+const (
+	// keeps the ActionsRunner around until its agent is removed from GitHub
+	deregistrationFinalizer = "kube-actions.inloco.com.br/deregistration"
+
+	// reason set by the kubelet when activeDeadlineSeconds is exceeded
+	podReasonDeadlineExceeded = "DeadlineExceeded"
+)
+
 // Reconciler reconciles an actionsRunner object
 type Reconciler struct {
 	client.Client
 	Log                     logr.Logger
 	Scheme                  *runtime.Scheme
 	MaxConcurrentReconciles int
+	Recorder                record.EventRecorder
+	MessageCapture          *wire.MessageCapture
+	GitHubEndpoint          *facades.GitHubEndpoint // the one of each ActionsRunner when nil
+	DeregistrationTimeout   time.Duration           // past it the finalizer is removed anyway, never when 0
 
 	gone  bool
 	wires wire.Collection
@@ -55,14 +71,17 @@ type Reconciler struct {
 
 // +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunners,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunners/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunners/finalizers,verbs=update
 // +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerjobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerjobs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerpolicies,verbs=get;list;watch
 // +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
-	r.wires.Init()
+	r.wires.Init(mgr.GetClient(), r.Recorder, r.MessageCapture, r.GitHubEndpoint)
 
 	go func() {
 		stop := make(chan os.Signal, 1)
@@ -109,11 +128,11 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, nil
 	}
 
-	// TODO: add finalizer to AR
 	var actionsRunner inlocov1alpha1.ActionsRunner
 	switch err := r.Get(ctx, req.NamespacedName, &actionsRunner); {
 	case apierrors.IsNotFound(err):
 		logger.Info("ActionsRunner not found")
+		r.MessageCapture.Forget(req.NamespacedName)
 		return ctrl.Result{}, r.wires.TryDestroy(ctx, req.NamespacedName)
 	case err != nil:
 		logger.Error(err, "Failed to get ActionsRunner")
@@ -123,7 +142,17 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 	if controllers.IsBeingDeleted(&actionsRunner) {
 		logger.Info("ActionsRunner is being deleted")
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.finalize(ctx, logger, &actionsRunner)
+	}
+
+	if !controllerutil.ContainsFinalizer(&actionsRunner, deregistrationFinalizer) {
+		logger.Info("ActionsRunner finalizer needs to be added")
+
+		controllerutil.AddFinalizer(&actionsRunner, deregistrationFinalizer)
+		if err := r.Update(ctx, &actionsRunner, controllers.UpdateOpts...); err != nil {
+			logger.Error(err, "Failed to add ActionsRunner finalizer")
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
 	}
 
 	var configMap corev1.ConfigMap
@@ -152,10 +181,16 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	if err != nil {
 		logger.Error(err, "Failed to get Wire")
 
+		if err := r.updateStatus(ctx, logger, &actionsRunner, statusForWireError(&actionsRunner, err)); err != nil {
+			return ctrl.Result{}, err
+		}
+
 		if !wire.IsUnrecoverable(err) {
 			return ctrl.Result{}, err
 		}
 
+		r.Recorder.Eventf(&actionsRunner, corev1.EventTypeWarning, reasonUnrecoverable, "Runner credentials or registration are no longer valid, registering it again: %v", err)
+
 		logger.Info("ConfigMap needs to be deleted")
 		if err := r.Delete(ctx, &configMap, controllers.DeleteOpts...); client.IgnoreNotFound(err) != nil {
 			logger.Error(err, "Failed to delete ConfigMap")
@@ -268,29 +303,67 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	switch err := r.Get(ctx, req.NamespacedName, &actionsRunnerJob); {
 	case apierrors.IsNotFound(err):
 		select {
-		case <-w.JobRequests():
-			logger.Info("ActionsRunnerJob needs to be created")
+		case jobSpec := <-w.JobRequests():
+			logger.Info("ActionsRunnerJob needs to be created", "workflow", jobSpec.Workflow, "job", jobSpec.JobDisplayName)
 
-			desiredActionsRunnerJob, err := util.ToActionsRunnerJob(&actionsRunner, r.Scheme)
+			desiredActionsRunnerJob, err := util.ToActionsRunnerJob(&actionsRunner, jobSpec, r.Scheme)
 			if err != nil {
 				logger.Error(err, "Failed to build desired ActionsRunnerJob")
 				return ctrl.Result{}, err
 			}
-			if err := r.Create(ctx, desiredActionsRunnerJob, controllers.CreateOpts...); controllers.IgnoreAlreadyExists(err) != nil {
+			switch err := r.Create(ctx, desiredActionsRunnerJob, controllers.CreateOpts...); {
+			case err == nil:
+				r.createJobConnectionSecret(ctx, logger, w, desiredActionsRunnerJob)
+			case controllers.IgnoreAlreadyExists(err) != nil:
 				logger.Error(err, "Failed to create ActionsRunnerJob")
 				return ctrl.Result{}, err
 			}
 
-			metrics.SetGitHubActionsJobAlive(actionsRunner.Spec.Repository.Name, desiredActionsRunnerJob.Name)
-			return ctrl.Result{}, nil
+			repository := actionsRunner.Spec.Repository
+			metrics.SetGitHubActionsJobAlive(repository.String(), desiredActionsRunnerJob.Name, string(repository.GetScope()))
+			return ctrl.Result{}, r.updateStatus(ctx, logger, &actionsRunner, statusForWire(&actionsRunner, w, desiredActionsRunnerJob))
 
 		default:
+			if actionsRunner.IsDraining() {
+				if w.Listening() {
+					logger.Info("Wire needs to stop listening, ActionsRunner is draining")
+					if err := w.Close(); err != nil {
+						logger.Error(err, "Failed to close wire")
+					}
+				}
+
+				return ctrl.Result{}, r.updateStatus(ctx, logger, &actionsRunner, statusForWire(&actionsRunner, w, nil))
+			}
+
+			if w.LabelsDrifted(actionsRunner.Spec.Labels) {
+				if w.Listening() {
+					logger.Info("Wire needs to stop listening before agent labels are updated")
+					if err := w.Close(); err != nil {
+						logger.Error(err, "Failed to close wire")
+					}
+
+					// the listener only stops after its pending GetMessage returns, then it triggers a reconciliation
+					return ctrl.Result{}, r.updateStatus(ctx, logger, &actionsRunner, statusForWire(&actionsRunner, w, nil))
+				}
+
+				logger.Info("Agent labels need to be updated", "labels", actionsRunner.Spec.Labels)
+				if err := w.UpdateLabels(ctx, actionsRunner.Spec.Labels); err != nil {
+					logger.Error(err, "Failed to update agent labels")
+
+					if err := r.updateStatus(ctx, logger, &actionsRunner, statusForLabelsError(&actionsRunner, err)); err != nil {
+						return ctrl.Result{}, err
+					}
+
+					return ctrl.Result{}, err
+				}
+			}
+
 			logger.Info("Wire needs to start listening")
 			if !w.Listening() {
 				w.Listen()
 			}
 
-			return ctrl.Result{}, nil
+			return ctrl.Result{}, r.updateStatus(ctx, logger, &actionsRunner, statusForWire(&actionsRunner, w, nil))
 		}
 
 	case err != nil:
@@ -298,6 +371,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, err
 	}
 
+	if err := r.updateStatus(ctx, logger, &actionsRunner, statusForWire(&actionsRunner, w, &actionsRunnerJob)); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	if controllers.IsBeingDeleted(&actionsRunnerJob) {
 		logger.Info("ActionsRunnerJob is being deleted")
 		return ctrl.Result{}, nil
@@ -322,12 +399,116 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, nil
 	}
 
+	if podPhase == corev1.PodFailed && actionsRunnerJob.Status.PodReason == podReasonDeadlineExceeded {
+		logger.Info("Pod exceeded its deadline, failing job")
+		if err := r.failJobOnDeadline(ctx, w, &actionsRunnerJob); err != nil {
+			logger.Error(err, "Failed to report deadline exceeded")
+		}
+	}
+
 	logger.Info("ActionsRunnerJob needs to be deleted")
 	if err := r.Delete(ctx, &actionsRunnerJob, controllers.DeleteOpts...); client.IgnoreNotFound(err) != nil {
 		logger.Error(err, "Failed to delete ActionsRunnerJob")
 		return ctrl.Result{}, err
 	}
 
-	metrics.SetGitHubActionsJobDone(actionsRunner.Spec.Repository.Name, actionsRunnerJob.Name)
+	repository := actionsRunner.Spec.Repository
+	metrics.SetGitHubActionsJobDone(repository.String(), actionsRunnerJob.Name, string(repository.GetScope()))
 	return ctrl.Result{}, nil
 }
+
+// createJobConnectionSecret keeps the connection of the job along with its ActionsRunnerJob, failing to do so only
+// prevents the job from being failed with a proper message if its Pod exceeds the deadline
+func (r *Reconciler) createJobConnectionSecret(ctx context.Context, logger logr.Logger, w *wire.Wire, actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob) {
+	serviceEndpoint, err := w.JobConnection(actionsRunnerJob.Spec.JobId)
+	if err != nil {
+		logger.Error(err, "Failed to get job connection")
+		return
+	}
+
+	desiredSecret, err := util.ToJobConnectionSecret(serviceEndpoint, actionsRunnerJob, r.Scheme)
+	if err != nil {
+		logger.Error(err, "Failed to build desired job connection Secret")
+		return
+	}
+
+	if err := r.Create(ctx, desiredSecret, controllers.CreateOpts...); controllers.IgnoreAlreadyExists(err) != nil {
+		logger.Error(err, "Failed to create job connection Secret")
+	}
+}
+
+func (r *Reconciler) failJobOnDeadline(ctx context.Context, w *wire.Wire, actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob) error {
+	var secret corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: actionsRunnerJob.GetNamespace(), Name: util.JobConnectionSecretName(actionsRunnerJob)}, &secret); err != nil {
+		return err
+	}
+
+	serviceEndpoint, err := util.ToJobConnection(&secret)
+	if err != nil {
+		return err
+	}
+
+	return w.OnJobDeadlineExceeded(ctx, actionsRunnerJob, serviceEndpoint)
+}
+
+// finalize removes the agent from GitHub before letting the ActionsRunner go, errors are retried with backoff until
+// DeregistrationTimeout if set
+func (r *Reconciler) finalize(ctx context.Context, logger logr.Logger, actionsRunner *inlocov1alpha1.ActionsRunner) error {
+	if !controllerutil.ContainsFinalizer(actionsRunner, deregistrationFinalizer) {
+		return nil
+	}
+
+	namespacedName := client.ObjectKeyFromObject(actionsRunner)
+
+	var configMap corev1.ConfigMap
+	if err := r.Get(ctx, namespacedName, &configMap); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to get ConfigMap")
+		return err
+	}
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, namespacedName, &secret); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to get Secret")
+		return err
+	}
+
+	if actionsRunner.SkipsDeregistration() {
+		logger.Info("Runner deregistration skipped")
+		r.wires.Forget(ctx, actionsRunner)
+	} else if err := r.deregister(ctx, logger, actionsRunner, &configMap, &secret); err != nil {
+		return err
+	}
+
+	logger.Info("ActionsRunner finalizer needs to be removed")
+	controllerutil.RemoveFinalizer(actionsRunner, deregistrationFinalizer)
+	if err := r.Update(ctx, actionsRunner, controllers.UpdateOpts...); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to remove ActionsRunner finalizer")
+		return err
+	}
+
+	return nil
+}
+
+// deregister removes the agent from GitHub, failures are only returned while they may be retried
+func (r *Reconciler) deregister(ctx context.Context, logger logr.Logger, actionsRunner *inlocov1alpha1.ActionsRunner, configMap *corev1.ConfigMap, secret *corev1.Secret) error {
+	logger.Info("Runner needs to be deregistered")
+	err := r.wires.Deregister(ctx, actionsRunner, util.ToDotFiles(configMap, secret))
+	if err == nil {
+		return nil
+	}
+
+	if wire.IsAlreadyDeregistered(err) {
+		logger.Info("Runner is already deregistered", "reason", err.Error())
+		return nil
+	}
+
+	logger.Error(err, "Failed to deregister runner")
+
+	if deletionTimestamp := actionsRunner.GetDeletionTimestamp(); r.DeregistrationTimeout > 0 && deletionTimestamp != nil && time.Since(deletionTimestamp.Time) > r.DeregistrationTimeout {
+		r.Recorder.Eventf(actionsRunner, corev1.EventTypeWarning, reasonDeregistrationFailed, "Giving up on deregistering runner after %v, its agent may have to be removed from GitHub by hand: %v", r.DeregistrationTimeout, err)
+		return nil
+	}
+
+	r.Recorder.Eventf(actionsRunner, corev1.EventTypeWarning, reasonDeregistrationFailed, "Failed to deregister runner, annotate it with %s=true to skip: %v", inlocov1alpha1.ActionsRunnerSkipDeregistrationAnnotation, err)
+	return err
+}
@@ -0,0 +1,222 @@
+package actionsrunner
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/dot"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades/fake"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/wire"
+)
+
+// finalizeTest is an ActionsRunner of inloco/kube-actions deleted a while ago, reconciled against a fake server
+type finalizeTest struct {
+	server        *fake.Server
+	actionsRunner *inlocov1alpha1.ActionsRunner
+	reconciler    *Reconciler
+	recorder      *record.FakeRecorder
+}
+
+func newFinalizeTest(t *testing.T, deletedAgo time.Duration, annotations map[string]string) *finalizeTest {
+	server, err := fake.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+
+	deletionTimestamp := metav1.NewTime(time.Now().Add(-deletedAgo))
+	actionsRunner := &inlocov1alpha1.ActionsRunner{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              "runner",
+			Annotations:       annotations,
+			Finalizers:        []string{deregistrationFinalizer},
+			DeletionTimestamp: &deletionTimestamp,
+		},
+		Spec: inlocov1alpha1.ActionsRunnerSpec{
+			Repository: inlocov1alpha1.ActionsRunnerRepository{
+				Owner:       "inloco",
+				Name:        "kube-actions",
+				APIEndpoint: server.GitHubAPIEndpoint(),
+			},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := inlocov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := record.NewFakeRecorder(16)
+
+	r := &Reconciler{
+		Client:   fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(actionsRunner).Build(),
+		Scheme:   scheme,
+		Recorder: recorder,
+		GitHubEndpoint: &facades.GitHubEndpoint{
+			APIEndpoint:  server.GitHubAPIEndpoint(),
+			PAT:          fake.GitHubPAT,
+			HTTPClient:   server.Client(),
+			Owners:       []string{"inloco"},
+			Visibilities: []string{"public", "private"},
+		},
+	}
+	r.wires.Init(r.Client, r.Recorder, r.MessageCapture, r.GitHubEndpoint)
+
+	return &finalizeTest{
+		server:        server,
+		actionsRunner: actionsRunner,
+		reconciler:    r,
+		recorder:      recorder,
+	}
+}
+
+// reconcile tells whether the ActionsRunner is gone afterwards
+func (f *finalizeTest) reconcile(t *testing.T) (bool, error) {
+	key := client.ObjectKeyFromObject(f.actionsRunner)
+	_, err := f.reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+
+	var current inlocov1alpha1.ActionsRunner
+	switch getErr := f.reconciler.Get(context.Background(), key, &current); {
+	case apierrors.IsNotFound(getErr):
+		return true, err
+
+	case getErr != nil:
+		t.Fatal(getErr)
+	}
+
+	return false, err
+}
+
+func (f *finalizeTest) events() []string {
+	var events []string
+	for {
+		select {
+		case event := <-f.recorder.Events:
+			events = append(events, event)
+
+		default:
+			return events
+		}
+	}
+}
+
+func TestFinalizeRepositoryGone(t *testing.T) {
+	f := newFinalizeTest(t, time.Minute, nil)
+
+	// the repository was never added to the fake, so GitHub answers 404
+	gone, err := f.reconcile(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !gone {
+		t.Error(`!gone`)
+	}
+}
+
+func TestFinalizeRemovesAgent(t *testing.T) {
+	f := newFinalizeTest(t, time.Minute, nil)
+	f.server.AddRepository("inloco", "kube-actions")
+
+	rsaParameters, err := dot.NewRSAParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dotFiles := &dot.Files{RSAParameters: *rsaParameters}
+	dotFiles.Runner.AgentName = wire.AgentName(f.actionsRunner)
+	if err := f.server.Register(dotFiles); err != nil {
+		t.Fatal(err)
+	}
+
+	gone, err := f.reconcile(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !gone {
+		t.Error(`!gone`)
+	}
+
+	if f.server.Agents() != 0 {
+		t.Error(`f.server.Agents() != 0`)
+	}
+}
+
+func TestFinalizeRetriesDeregistrationFailures(t *testing.T) {
+	// without a DeregistrationTimeout it is retried no matter how long ago the ActionsRunner was deleted
+	f := newFinalizeTest(t, 24*time.Hour, nil)
+	f.server.AddRepository("inloco", "kube-actions")
+	f.server.FailGitHub(http.StatusInternalServerError)
+
+	gone, err := f.reconcile(t)
+	if err == nil {
+		t.Error(`err == nil`)
+	}
+
+	if gone {
+		t.Error(`gone`)
+	}
+
+	events := f.events()
+	if len(events) != 1 || !strings.HasPrefix(events[0], corev1.EventTypeWarning+" "+reasonDeregistrationFailed) {
+		t.Error(`len(events) != 1 || !strings.HasPrefix(events[0], corev1.EventTypeWarning+" "+reasonDeregistrationFailed)`)
+	}
+}
+
+func TestFinalizeGivesUp(t *testing.T) {
+	f := newFinalizeTest(t, 2*time.Hour, nil)
+	f.server.AddRepository("inloco", "kube-actions")
+	f.server.FailGitHub(http.StatusInternalServerError)
+	f.reconciler.DeregistrationTimeout = time.Hour
+
+	gone, err := f.reconcile(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !gone {
+		t.Error(`!gone`)
+	}
+
+	if len(f.events()) != 1 {
+		t.Error(`len(f.events()) != 1`)
+	}
+}
+
+func TestFinalizeSkipsDeregistration(t *testing.T) {
+	f := newFinalizeTest(t, time.Minute, map[string]string{inlocov1alpha1.ActionsRunnerSkipDeregistrationAnnotation: "true"})
+	f.server.FailGitHub(http.StatusInternalServerError)
+
+	gone, err := f.reconcile(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !gone {
+		t.Error(`!gone`)
+	}
+
+	if len(f.server.RunnerTokens()) != 0 {
+		t.Error(`len(f.server.RunnerTokens()) != 0`)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsrunner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/wire"
+)
+
+const (
+	reasonAgentRegistered   = "AgentRegistered"
+	reasonWireFailed        = "WireFailed"
+	reasonUnrecoverable     = "UnrecoverableError"
+	reasonSessionOpen       = "SessionOpen"
+	reasonSessionClosed     = "SessionClosed"
+	reasonDraining          = "Draining"
+	reasonJobAccepted       = "JobAccepted"
+	reasonWaitingForJob     = "WaitingForJob"
+	reasonAsExpected        = "AsExpected"
+	reasonJobBeingCompleted = "JobBeingCompleted"
+	reasonLabelsSynced      = "LabelsSynced"
+	reasonLabelsDeferred    = "LabelsUpdateDeferred"
+	reasonLabelsPending     = "LabelsUpdatePending"
+	reasonLabelsFailed      = "LabelsUpdateFailed"
+
+	reasonDeregistrationFailed = "DeregistrationFailed"
+)
+
+func setCondition(status *inlocov1alpha1.ActionsRunnerStatus, actionsRunner *inlocov1alpha1.ActionsRunner, conditionType inlocov1alpha1.ActionsRunnerConditionType, conditionStatus metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               string(conditionType),
+		Status:             conditionStatus,
+		ObservedGeneration: actionsRunner.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func statusForWireError(actionsRunner *inlocov1alpha1.ActionsRunner, err error) *inlocov1alpha1.ActionsRunnerStatus {
+	status := actionsRunner.Status.DeepCopy()
+
+	reason := reasonWireFailed
+	if wire.IsUnrecoverable(err) {
+		reason = reasonUnrecoverable
+		status.LastUnrecoverableError = err.Error()
+	}
+
+	setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionRegistered, metav1.ConditionFalse, reason, err.Error())
+	setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionListening, metav1.ConditionFalse, reason, "Wire is not available")
+	setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionDegraded, metav1.ConditionTrue, reason, err.Error())
+
+	return status
+}
+
+func statusForWire(actionsRunner *inlocov1alpha1.ActionsRunner, w *wire.Wire, actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob) *inlocov1alpha1.ActionsRunnerStatus {
+	status := actionsRunner.Status.DeepCopy()
+
+	if w.DotFiles != nil {
+		runner := w.DotFiles.Runner
+		status.AgentId = runner.AgentId
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionRegistered, metav1.ConditionTrue, reasonAgentRegistered, fmt.Sprintf("Agent %q registered with id %d", runner.AgentName, runner.AgentId))
+	}
+
+	if messageType, messageTime := w.LastMessage(); !messageTime.IsZero() {
+		lastMessageTime := metav1.NewTime(messageTime.Truncate(time.Second))
+		status.LastMessageType = string(messageType)
+		status.LastMessageTime = &lastMessageTime
+	}
+
+	switch {
+	case w.Listening():
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionListening, metav1.ConditionTrue, reasonSessionOpen, "Wire is listening for job requests")
+	case actionsRunner.IsDraining():
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionListening, metav1.ConditionFalse, reasonDraining, "ActionsRunner is draining, no more job requests are accepted")
+	default:
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionListening, metav1.ConditionFalse, reasonSessionClosed, "Wire is not listening for job requests")
+	}
+
+	switch {
+	case actionsRunnerJob == nil:
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionJobAssigned, metav1.ConditionFalse, reasonWaitingForJob, "No ActionsRunnerJob in flight")
+	case actionsRunnerJob.GetDeletionTimestamp() != nil:
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionJobAssigned, metav1.ConditionTrue, reasonJobBeingCompleted, fmt.Sprintf("ActionsRunnerJob %q is being deleted", actionsRunnerJob.GetName()))
+	default:
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionJobAssigned, metav1.ConditionTrue, reasonJobAccepted, fmt.Sprintf("ActionsRunnerJob %q is in flight", actionsRunnerJob.GetName()))
+	}
+
+	status.Labels = w.Labels()
+	switch {
+	case !w.LabelsDrifted(actionsRunner.Spec.Labels):
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionLabelsSynced, metav1.ConditionTrue, reasonLabelsSynced, "Agent labels match .spec.labels")
+	case actionsRunnerJob != nil:
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionLabelsSynced, metav1.ConditionFalse, reasonLabelsDeferred, fmt.Sprintf("Agent labels will be updated once ActionsRunnerJob %q completes", actionsRunnerJob.GetName()))
+	default:
+		setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionLabelsSynced, metav1.ConditionFalse, reasonLabelsPending, "Agent labels need to be updated")
+	}
+
+	setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionDegraded, metav1.ConditionFalse, reasonAsExpected, "")
+
+	return status
+}
+
+func statusForLabelsError(actionsRunner *inlocov1alpha1.ActionsRunner, err error) *inlocov1alpha1.ActionsRunnerStatus {
+	status := actionsRunner.Status.DeepCopy()
+
+	setCondition(status, actionsRunner, inlocov1alpha1.ActionsRunnerConditionLabelsSynced, metav1.ConditionFalse, reasonLabelsFailed, err.Error())
+
+	return status
+}
+
+func (r *Reconciler) updateStatus(ctx context.Context, logger logr.Logger, actionsRunner *inlocov1alpha1.ActionsRunner, status *inlocov1alpha1.ActionsRunnerStatus) error {
+	if equality.Semantic.DeepEqual(actionsRunner.Status, *status) {
+		return nil
+	}
+
+	logger.Info("ActionsRunnerStatus needs to be updated")
+	actionsRunner.Status = *status
+
+	if err := r.Status().Update(ctx, actionsRunner); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to update ActionsRunnerStatus")
+		return err
+	}
+
+	return nil
+}
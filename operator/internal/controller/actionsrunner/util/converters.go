@@ -20,12 +20,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/microsoft/azure-devops-go-api/azuredevops/serviceendpoint"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 
@@ -44,10 +50,23 @@ var (
 	dindImageVersion = getEnv("KUBEACTIONS_DIND_IMAGE_VERSION", constants.Ver())
 	dindImageVariant = getEnv("KUBEACTIONS_DIND_IMAGE_VARIANT", "-dind")
 
+	// JSON encoded defaults merged with the ActionsRunner spec, e.g. {"node-role.example.com/ci":"true"}
+	runnerNodeSelector = getEnv("KUBEACTIONS_RUNNER_NODE_SELECTOR", "")
+	runnerTolerations  = getEnv("KUBEACTIONS_RUNNER_TOLERATIONS", "")
+
+	// runnerNodeSelector and runnerTolerations as parsed by ParseRunnerScheduling
+	defaultNodeSelector map[string]string
+	defaultTolerations  []corev1.Toleration
+
+	// time to schedule the Pod, pull images and register before the job starts
+	runnerSetupPadding = 10 * time.Minute
+	// GitHub-hosted runners stop jobs after 6h too
+	defaultMaxJobDuration = 6 * time.Hour
+
 	runnerContainerName = "runner"
-	runnerResourcesKey  = "runner"
+	runnerResourcesKey  = inlocov1alpha1.ActionsRunnerResourcesRunner
 	dindContainerName   = "dind"
-	dindResourcesKey    = "docker"
+	dindResourcesKey    = inlocov1alpha1.ActionsRunnerResourcesDocker
 )
 
 func ToDotFiles(configMap *corev1.ConfigMap, secret *corev1.Secret) *dot.Files {
@@ -220,11 +239,15 @@ func ToPodDisruptionBudget(actionsRunner *inlocov1alpha1.ActionsRunner, scheme *
 	return &podDisruptionBudget, nil
 }
 
-func ToActionsRunnerJob(actionsRunner *inlocov1alpha1.ActionsRunner, scheme *runtime.Scheme) (*inlocov1alpha1.ActionsRunnerJob, error) {
+func ToActionsRunnerJob(actionsRunner *inlocov1alpha1.ActionsRunner, jobSpec *inlocov1alpha1.ActionsRunnerJobSpec, scheme *runtime.Scheme) (*inlocov1alpha1.ActionsRunnerJob, error) {
 	if actionsRunner == nil {
 		return nil, errors.New("actionsRunner == nil")
 	}
 
+	if jobSpec == nil {
+		return nil, errors.New("jobSpec == nil")
+	}
+
 	if scheme == nil {
 		return nil, errors.New("scheme == nil")
 	}
@@ -241,6 +264,7 @@ func ToActionsRunnerJob(actionsRunner *inlocov1alpha1.ActionsRunner, scheme *run
 				"kube-actions.inloco.com.br/actions-runner": actionsRunner.GetName(),
 			},
 		},
+		Spec: *jobSpec,
 	}
 
 	if err := ctrl.SetControllerReference(actionsRunner, &actionsRunnerJob, scheme); err != nil {
@@ -250,6 +274,69 @@ func ToActionsRunnerJob(actionsRunner *inlocov1alpha1.ActionsRunner, scheme *run
 	return &actionsRunnerJob, nil
 }
 
+// JobConnectionSecretName is the Secret holding the SystemVssConnection of the job of an ActionsRunnerJob
+func JobConnectionSecretName(actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob) string {
+	return actionsRunnerJob.GetName() + "-job"
+}
+
+// ToJobConnectionSecret keeps the SystemVssConnection of a job, its access token is needed to fail it later on
+func ToJobConnectionSecret(serviceEndpoint *serviceendpoint.ServiceEndpoint, actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob, scheme *runtime.Scheme) (*corev1.Secret, error) {
+	if serviceEndpoint == nil {
+		return nil, errors.New("serviceEndpoint == nil")
+	}
+
+	if actionsRunnerJob == nil {
+		return nil, errors.New("actionsRunnerJob == nil")
+	}
+
+	if scheme == nil {
+		return nil, errors.New("scheme == nil")
+	}
+
+	endpoint, err := json.Marshal(serviceEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      JobConnectionSecretName(actionsRunnerJob),
+			Namespace: actionsRunnerJob.GetNamespace(),
+		},
+		Data: map[string][]byte{
+			string(WellKnownServiceEndpointNameSystemVssConnection): endpoint,
+		},
+	}
+
+	if err := ctrl.SetControllerReference(actionsRunnerJob, &secret, scheme); err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+func ToJobConnection(secret *corev1.Secret) (*serviceendpoint.ServiceEndpoint, error) {
+	if secret == nil {
+		return nil, errors.New("secret == nil")
+	}
+
+	endpoint, ok := secret.Data[string(WellKnownServiceEndpointNameSystemVssConnection)]
+	if !ok {
+		return nil, fmt.Errorf("secret.Data[%q] == nil", WellKnownServiceEndpointNameSystemVssConnection)
+	}
+
+	var serviceEndpoint serviceendpoint.ServiceEndpoint
+	if err := json.Unmarshal(endpoint, &serviceEndpoint); err != nil {
+		return nil, err
+	}
+
+	return &serviceEndpoint, nil
+}
+
 func ToPersistentVolumeClaim(actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob, scheme *runtime.Scheme) (*corev1.PersistentVolumeClaim, error) {
 	if actionsRunner == nil {
 		return nil, errors.New("actionsRunner == nil")
@@ -335,13 +422,13 @@ func ToPod(actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJob *inloco
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        actionsRunnerJob.GetName(),
 			Namespace:   actionsRunner.GetNamespace(),
-			Annotations: actionsRunner.Spec.Annotations,
-			Labels: map[string]string{
+			Annotations: withJobAnnotations(actionsRunner.Spec.Annotations, &actionsRunnerJob.Spec),
+			Labels: withJobLabels(map[string]string{
 				"kube-actions.inloco.com.br/actions-runner": actionsRunner.GetName(),
-			},
+			}, &actionsRunnerJob.Spec),
 		},
 		Spec: corev1.PodSpec{
-			ActiveDeadlineSeconds: pointer.Int64(3000),
+			ActiveDeadlineSeconds: activeDeadlineSeconds(actionsRunner, actionsRunnerJob),
 			Volumes:               withVolumes(actionsRunner),
 			Containers: []corev1.Container{
 				{
@@ -358,6 +445,10 @@ func ToPod(actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJob *inloco
 							Name:  "KUBEACTIONS_ACTIONSRUNNER_NAME",
 							Value: actionsRunner.GetName(),
 						},
+						corev1.EnvVar{
+							Name:  "KUBEACTIONS_ACTIONSRUNNER_REPOSITORY_SCOPE",
+							Value: string(actionsRunner.Spec.Repository.GetScope()),
+						},
 						corev1.EnvVar{
 							Name:  "KUBEACTIONS_ACTIONSRUNNER_REPOSITORY_OWNER",
 							Value: actionsRunner.Spec.Repository.Owner,
@@ -366,6 +457,10 @@ func ToPod(actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJob *inloco
 							Name:  "KUBEACTIONS_ACTIONSRUNNER_REPOSITORY_NAME",
 							Value: actionsRunner.Spec.Repository.Name,
 						},
+						corev1.EnvVar{
+							Name:  "KUBEACTIONS_ACTIONSRUNNER_REPOSITORY_RUNNER_GROUP",
+							Value: actionsRunner.Spec.Repository.RunnerGroup,
+						},
 						corev1.EnvVar{
 							Name:  "KUBEACTIONS_ACTIONSRUNNERJOB_NAME",
 							Value: actionsRunnerJob.GetName(),
@@ -386,18 +481,9 @@ func ToPod(actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJob *inloco
 					{Name: "net.ipv4.ping_group_range", Value: "0 2147483647"},
 				},
 			},
-			Affinity: withRuntimeAffinity(actionsRunner.Spec.Affinity),
-			Tolerations: []corev1.Toleration{
-				{
-					Key:      "node-role.incognia.com/ci",
-					Operator: corev1.TolerationOpEqual,
-					Value:    "true",
-					Effect:   corev1.TaintEffectNoSchedule,
-				},
-			},
-			NodeSelector: map[string]string{
-				"node-role.incognia.com/ci": "true",
-			},
+			Affinity:     withRuntimeAffinity(actionsRunner.Spec.Affinity),
+			Tolerations:  withTolerations(actionsRunner.Spec.Tolerations),
+			NodeSelector: withNodeSelector(actionsRunner.Spec.NodeSelector),
 		},
 	}
 
@@ -414,6 +500,10 @@ func ToPod(actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJob *inloco
 		}
 	}
 
+	if err := applyPodTemplate(&pod, actionsRunner.Spec.PodTemplate); err != nil {
+		return nil, err
+	}
+
 	if err := ctrl.SetControllerReference(actionsRunnerJob, &pod, scheme); err != nil {
 		return nil, err
 	}
@@ -421,6 +511,215 @@ func ToPod(actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJob *inloco
 	return &pod, nil
 }
 
+// PodTemplateError is returned when .Spec.PodTemplate cannot be applied to the generated Pod
+type PodTemplateError struct {
+	err error
+}
+
+func (e *PodTemplateError) Error() string {
+	return fmt.Sprintf("unable to apply .Spec.PodTemplate: %v", e.err)
+}
+
+func (e *PodTemplateError) Unwrap() error {
+	return e.err
+}
+
+func applyPodTemplate(pod *corev1.Pod, podTemplate *runtime.RawExtension) error {
+	if podTemplate == nil || len(podTemplate.Raw) == 0 {
+		return nil
+	}
+
+	original, err := json.Marshal(pod)
+	if err != nil {
+		return err
+	}
+
+	patched, err := strategicpatch.StrategicMergePatch(original, podTemplate.Raw, corev1.Pod{})
+	if err != nil {
+		return &PodTemplateError{err}
+	}
+
+	var patchedPod corev1.Pod
+	if err := json.Unmarshal(patched, &patchedPod); err != nil {
+		return &PodTemplateError{err}
+	}
+
+	// identity is owned by the operator
+	patchedPod.TypeMeta = pod.TypeMeta
+	patchedPod.SetName(pod.GetName())
+	patchedPod.SetNamespace(pod.GetNamespace())
+	patchedPod.SetOwnerReferences(pod.GetOwnerReferences())
+
+	restoreGeneratedFields(&patchedPod, pod)
+
+	*pod = patchedPod
+	return nil
+}
+
+// restoreGeneratedFields lets a patch add to, but not override, the labels, annotations, volumes, mounts, env and
+// security contexts the operator generated, as the runner relies on them to find its job and credentials
+func restoreGeneratedFields(patchedPod *corev1.Pod, pod *corev1.Pod) {
+	patchedPod.SetLabels(withGeneratedEntries(patchedPod.GetLabels(), pod.GetLabels()))
+	patchedPod.SetAnnotations(withGeneratedEntries(patchedPod.GetAnnotations(), pod.GetAnnotations()))
+
+	patchedPod.Spec.SecurityContext = pod.Spec.SecurityContext
+	patchedPod.Spec.AutomountServiceAccountToken = pod.Spec.AutomountServiceAccountToken
+	patchedPod.Spec.Volumes = withGeneratedVolumes(patchedPod.Spec.Volumes, pod.Spec.Volumes)
+
+	for _, container := range pod.Spec.Containers {
+		patchedContainer := findContainer(patchedPod.Spec.Containers, container.Name)
+		if patchedContainer == nil {
+			patchedPod.Spec.Containers = append(patchedPod.Spec.Containers, container)
+			continue
+		}
+
+		patchedContainer.SecurityContext = container.SecurityContext
+		patchedContainer.Env = withGeneratedEnvVars(patchedContainer.Env, container.Env)
+		patchedContainer.VolumeMounts = withGeneratedVolumeMounts(patchedContainer.VolumeMounts, container.VolumeMounts)
+	}
+}
+
+func withGeneratedEntries(patched map[string]string, generated map[string]string) map[string]string {
+	out := make(map[string]string, len(patched)+len(generated))
+	for key, value := range patched {
+		out[key] = value
+	}
+	for key, value := range generated {
+		out[key] = value
+	}
+
+	return out
+}
+
+func findContainer(containers []corev1.Container, name string) *corev1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+
+	return nil
+}
+
+func withGeneratedVolumes(patched []corev1.Volume, generated []corev1.Volume) []corev1.Volume {
+	names := make(map[string]struct{}, len(generated))
+	for _, volume := range generated {
+		names[volume.Name] = struct{}{}
+	}
+
+	volumes := append([]corev1.Volume{}, generated...)
+	for _, volume := range patched {
+		if _, ok := names[volume.Name]; !ok {
+			volumes = append(volumes, volume)
+		}
+	}
+
+	return volumes
+}
+
+// generated env vars come first, so added ones may refer to them
+func withGeneratedEnvVars(patched []corev1.EnvVar, generated []corev1.EnvVar) []corev1.EnvVar {
+	names := make(map[string]struct{}, len(generated))
+	for _, envVar := range generated {
+		names[envVar.Name] = struct{}{}
+	}
+
+	envVars := append([]corev1.EnvVar{}, generated...)
+	for _, envVar := range patched {
+		if _, ok := names[envVar.Name]; !ok {
+			envVars = append(envVars, envVar)
+		}
+	}
+
+	return envVars
+}
+
+func withGeneratedVolumeMounts(patched []corev1.VolumeMount, generated []corev1.VolumeMount) []corev1.VolumeMount {
+	mountPaths := make(map[string]struct{}, len(generated))
+	for _, volumeMount := range generated {
+		mountPaths[volumeMount.MountPath] = struct{}{}
+	}
+
+	volumeMounts := append([]corev1.VolumeMount{}, generated...)
+	for _, volumeMount := range patched {
+		if _, ok := mountPaths[volumeMount.MountPath]; !ok {
+			volumeMounts = append(volumeMounts, volumeMount)
+		}
+	}
+
+	return volumeMounts
+}
+
+func withJobLabels(labels map[string]string, jobSpec *inlocov1alpha1.ActionsRunnerJobSpec) map[string]string {
+	out := make(map[string]string, len(labels))
+	for key, val := range labels {
+		out[key] = val
+	}
+
+	for key, val := range map[string]string{
+		"kube-actions.inloco.com.br/workflow-run-id":      jobSpec.RunId,
+		"kube-actions.inloco.com.br/workflow-run-attempt": jobSpec.RunAttempt,
+		"kube-actions.inloco.com.br/workflow-sha":         jobSpec.Sha,
+		"kube-actions.inloco.com.br/workflow-actor":       jobSpec.Actor,
+		"kube-actions.inloco.com.br/workflow-event":       jobSpec.Event,
+	} {
+		if labelValue := toLabelValue(val); labelValue != "" {
+			out[key] = labelValue
+		}
+	}
+
+	return out
+}
+
+func withJobAnnotations(annotations map[string]string, jobSpec *inlocov1alpha1.ActionsRunnerJobSpec) map[string]string {
+	out := make(map[string]string, len(annotations))
+	for key, val := range annotations {
+		out[key] = val
+	}
+
+	var requestId string
+	if jobSpec.RequestId != 0 {
+		requestId = strconv.FormatUint(jobSpec.RequestId, 10)
+	}
+
+	for key, val := range map[string]string{
+		"kube-actions.inloco.com.br/job-id":               jobSpec.JobId,
+		"kube-actions.inloco.com.br/request-id":           requestId,
+		"kube-actions.inloco.com.br/plan-id":              jobSpec.Plan.PlanId,
+		"kube-actions.inloco.com.br/workflow":             jobSpec.Workflow,
+		"kube-actions.inloco.com.br/job-name":             jobSpec.JobName,
+		"kube-actions.inloco.com.br/job-display-name":     jobSpec.JobDisplayName,
+		"kube-actions.inloco.com.br/workflow-run-id":      jobSpec.RunId,
+		"kube-actions.inloco.com.br/workflow-run-attempt": jobSpec.RunAttempt,
+		"kube-actions.inloco.com.br/workflow-ref":         jobSpec.Ref,
+		"kube-actions.inloco.com.br/workflow-sha":         jobSpec.Sha,
+		"kube-actions.inloco.com.br/workflow-actor":       jobSpec.Actor,
+		"kube-actions.inloco.com.br/workflow-event":       jobSpec.Event,
+	} {
+		if val != "" {
+			out[key] = val
+		}
+	}
+
+	return out
+}
+
+// toLabelValue replaces characters not allowed in label values, e.g. "dependabot[bot]"
+func toLabelValue(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			b[i] = '_'
+		}
+	}
+
+	if len(b) > validation.LabelValueMaxLength {
+		b = b[:validation.LabelValueMaxLength]
+	}
+
+	return strings.Trim(string(b), "-_.")
+}
+
 func withVolumes(actionsRunner *inlocov1alpha1.ActionsRunner) []corev1.Volume {
 	volumeByName := make(map[string]corev1.Volume, len(actionsRunner.Spec.Volumes))
 
@@ -531,6 +830,68 @@ func withVolumeMounts(actionsRunner *inlocov1alpha1.ActionsRunner) []corev1.Volu
 	return volumeMounts
 }
 
+// ParseRunnerScheduling parses the node selector and tolerations of every runner Pod once, so misconfigurations fail on
+// startup instead of on every Pod.
+func ParseRunnerScheduling() error {
+	var nodeSelector map[string]string
+	if runnerNodeSelector != "" {
+		if err := json.Unmarshal([]byte(runnerNodeSelector), &nodeSelector); err != nil {
+			return fmt.Errorf("unable to parse KUBEACTIONS_RUNNER_NODE_SELECTOR: %w", err)
+		}
+	}
+
+	var tolerations []corev1.Toleration
+	if runnerTolerations != "" {
+		if err := json.Unmarshal([]byte(runnerTolerations), &tolerations); err != nil {
+			return fmt.Errorf("unable to parse KUBEACTIONS_RUNNER_TOLERATIONS: %w", err)
+		}
+	}
+
+	defaultNodeSelector = nodeSelector
+	defaultTolerations = tolerations
+	return nil
+}
+
+func withNodeSelector(nodeSelector map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaultNodeSelector)+len(nodeSelector))
+
+	for key, value := range defaultNodeSelector {
+		merged[key] = value
+	}
+
+	for key, value := range nodeSelector {
+		merged[key] = value
+	}
+
+	if len(merged) == 0 {
+		return nil
+	}
+
+	return merged
+}
+
+func withTolerations(tolerations []corev1.Toleration) []corev1.Toleration {
+	merged := append([]corev1.Toleration(nil), defaultTolerations...)
+
+	for _, toleration := range tolerations {
+		if !containsToleration(merged, toleration) {
+			merged = append(merged, toleration)
+		}
+	}
+
+	return merged
+}
+
+func containsToleration(tolerations []corev1.Toleration, toleration corev1.Toleration) bool {
+	for _, t := range tolerations {
+		if t.MatchToleration(&toleration) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func withRuntimeAffinity(affinity *corev1.Affinity) *corev1.Affinity {
 	if affinity == nil {
 		affinity = &corev1.Affinity{}
@@ -666,3 +1027,17 @@ func addDockerCapability(pod *corev1.Pod, actionsRunner *inlocov1alpha1.ActionsR
 
 	return nil
 }
+
+// activeDeadlineSeconds bounds the job timeout by the maxJobDuration of the ActionsRunner, using the latter if the former is unknown
+func activeDeadlineSeconds(actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob) *int64 {
+	duration := defaultMaxJobDuration
+	if maxJobDuration := actionsRunner.Spec.MaxJobDuration; maxJobDuration != nil && maxJobDuration.Duration > 0 {
+		duration = maxJobDuration.Duration
+	}
+
+	if timeout := time.Duration(actionsRunnerJob.Spec.TimeoutMinutes) * time.Minute; timeout > 0 && timeout < duration {
+		duration = timeout
+	}
+
+	return pointer.Int64(int64((duration + runnerSetupPadding).Seconds()))
+}
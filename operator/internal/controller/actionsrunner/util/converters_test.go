@@ -0,0 +1,194 @@
+package util
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestWithNodeSelector(t *testing.T) {
+	runnerNodeSelector = `{"node-role.example.com/ci":"true","disk":"hdd"}`
+	defer func() {
+		runnerNodeSelector = ""
+		defaultNodeSelector = nil
+	}()
+
+	if err := ParseRunnerScheduling(); err != nil {
+		t.Fatal(err)
+	}
+
+	nodeSelector := withNodeSelector(map[string]string{"disk": "ssd"})
+
+	if nodeSelector["node-role.example.com/ci"] != "true" {
+		t.Error(`nodeSelector["node-role.example.com/ci"] != "true"`)
+	}
+
+	if nodeSelector["disk"] != "ssd" {
+		t.Error(`nodeSelector["disk"] != "ssd"`)
+	}
+
+	if defaultNodeSelector["disk"] != "hdd" {
+		t.Error(`defaultNodeSelector["disk"] != "hdd"`)
+	}
+}
+
+func TestWithTolerations(t *testing.T) {
+	runnerTolerations = `[{"key":"node-role.example.com/ci","operator":"Equal","value":"true","effect":"NoSchedule"}]`
+	defer func() {
+		runnerTolerations = ""
+		defaultTolerations = nil
+	}()
+
+	if err := ParseRunnerScheduling(); err != nil {
+		t.Fatal(err)
+	}
+
+	tolerations := withTolerations([]corev1.Toleration{
+		{Key: "node-role.example.com/ci", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "gpu", Operator: corev1.TolerationOpExists},
+	})
+
+	if len(tolerations) != 2 {
+		t.Error(`len(tolerations) != 2`)
+	}
+
+	if len(defaultTolerations) != 1 {
+		t.Error(`len(defaultTolerations) != 1`)
+	}
+}
+
+func TestParseRunnerSchedulingRejectsInvalidJSON(t *testing.T) {
+	defer func() {
+		runnerNodeSelector = ""
+		runnerTolerations = ""
+	}()
+
+	runnerNodeSelector = `disk=ssd`
+	if err := ParseRunnerScheduling(); err == nil {
+		t.Error(`err := ParseRunnerScheduling(); err == nil`)
+	}
+
+	runnerNodeSelector = ""
+	runnerTolerations = `{"key":"gpu"}`
+	if err := ParseRunnerScheduling(); err == nil {
+		t.Error(`err := ParseRunnerScheduling(); err == nil`)
+	}
+}
+
+func TestApplyPodTemplate(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "runner",
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: runnerContainerName, Image: "runner"},
+			},
+		},
+	}
+
+	err := applyPodTemplate(&pod, &runtime.RawExtension{
+		Raw: []byte(`{"metadata":{"name":"other"},"spec":{"priorityClassName":"ci","containers":[{"name":"sidecar","image":"sidecar"}]}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pod.GetName() != "runner" {
+		t.Error(`pod.GetName() != "runner"`)
+	}
+
+	if pod.Spec.PriorityClassName != "ci" {
+		t.Error(`pod.Spec.PriorityClassName != "ci"`)
+	}
+
+	if len(pod.Spec.Containers) != 2 {
+		t.Error(`len(pod.Spec.Containers) != 2`)
+	}
+
+	var podTemplateError *PodTemplateError
+	if err := applyPodTemplate(&pod, &runtime.RawExtension{Raw: []byte(`{"spec":[]}`)}); !errors.As(err, &podTemplateError) {
+		t.Error(`!errors.As(err, &podTemplateError)`)
+	}
+}
+
+func TestApplyPodTemplateRestoresGeneratedFields(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "runner",
+			Labels: map[string]string{"kube-actions.inloco.com.br/actions-runner": "runner"},
+		},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{Name: "config-map", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "runner"}}}},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:         runnerContainerName,
+					Image:        "runner",
+					Env:          []corev1.EnvVar{{Name: "KUBEACTIONS_ACTIONSRUNNER_NAME", Value: "runner"}},
+					VolumeMounts: []corev1.VolumeMount{{Name: "config-map", MountPath: "/opt/actions-runner/.runner", SubPath: ".runner"}},
+				},
+			},
+			SecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: pointer.Bool(true)},
+		},
+	}
+
+	err := applyPodTemplate(&pod, &runtime.RawExtension{
+		Raw: []byte(`{"metadata":{"labels":{"kube-actions.inloco.com.br/actions-runner":"other","team":"ci"}},"spec":{"securityContext":{"runAsNonRoot":false},"volumes":[{"name":"config-map","emptyDir":{}}],"containers":[{"name":"runner","image":"other","securityContext":{"privileged":true},"env":[{"name":"KUBEACTIONS_ACTIONSRUNNER_NAME","value":"other"},{"name":"CI","value":"true"}],"volumeMounts":[{"name":"cache","mountPath":"/opt/actions-runner/.runner"}]}]}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if labels := pod.GetLabels(); labels["kube-actions.inloco.com.br/actions-runner"] != "runner" || labels["team"] != "ci" {
+		t.Error(`labels["kube-actions.inloco.com.br/actions-runner"] != "runner" || labels["team"] != "ci"`)
+	}
+
+	if !*pod.Spec.SecurityContext.RunAsNonRoot {
+		t.Error(`!*pod.Spec.SecurityContext.RunAsNonRoot`)
+	}
+
+	if len(pod.Spec.Volumes) != 1 || pod.Spec.Volumes[0].ConfigMap == nil {
+		t.Error(`len(pod.Spec.Volumes) != 1 || pod.Spec.Volumes[0].ConfigMap == nil`)
+	}
+
+	container := pod.Spec.Containers[0]
+	if container.Image != "other" || container.SecurityContext != nil {
+		t.Error(`container.Image != "other" || container.SecurityContext != nil`)
+	}
+
+	if len(container.Env) != 2 || container.Env[0].Value != "runner" || container.Env[1].Name != "CI" {
+		t.Error(`len(container.Env) != 2 || container.Env[0].Value != "runner" || container.Env[1].Name != "CI"`)
+	}
+
+	if len(container.VolumeMounts) != 1 || container.VolumeMounts[0].Name != "config-map" {
+		t.Error(`len(container.VolumeMounts) != 1 || container.VolumeMounts[0].Name != "config-map"`)
+	}
+}
+
+func TestActiveDeadlineSeconds(t *testing.T) {
+	var actionsRunner inlocov1alpha1.ActionsRunner
+	var actionsRunnerJob inlocov1alpha1.ActionsRunnerJob
+
+	if *activeDeadlineSeconds(&actionsRunner, &actionsRunnerJob) != 6*60*60+10*60 {
+		t.Error(`*activeDeadlineSeconds(&actionsRunner, &actionsRunnerJob) != 6*60*60+10*60`)
+	}
+
+	actionsRunnerJob.Spec.TimeoutMinutes = 30
+	if *activeDeadlineSeconds(&actionsRunner, &actionsRunnerJob) != 40*60 {
+		t.Error(`*activeDeadlineSeconds(&actionsRunner, &actionsRunnerJob) != 40*60`)
+	}
+
+	actionsRunner.Spec.MaxJobDuration = &metav1.Duration{Duration: 20 * time.Minute}
+	if *activeDeadlineSeconds(&actionsRunner, &actionsRunnerJob) != 30*60 {
+		t.Error(`*activeDeadlineSeconds(&actionsRunner, &actionsRunnerJob) != 30*60`)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+var (
+	// comma separated, e.g. docker,secret
+	runnerDefaultCapabilities = getEnv("KUBEACTIONS_RUNNER_DEFAULT_CAPABILITIES", "")
+	runnerDefaultLabels       = getEnv("KUBEACTIONS_RUNNER_DEFAULT_LABELS", "")
+	// JSON encoded requirements per resources key, e.g. {"runner":{"requests":{"cpu":"1"}}}
+	runnerDefaultResources          = getEnv("KUBEACTIONS_RUNNER_DEFAULT_RESOURCES", "")
+	runnerDefaultServiceAccountName = getEnv("KUBEACTIONS_RUNNER_DEFAULT_SERVICE_ACCOUNT_NAME", "default")
+)
+
+// actionsRunnerSpecDefaults are only applied to unset fields
+type actionsRunnerSpecDefaults struct {
+	version            string
+	capabilities       []inlocov1alpha1.ActionsRunnerCapability
+	labels             []string
+	resources          map[string]corev1.ResourceRequirements
+	serviceAccountName string
+}
+
+// NewActionsRunnerSpecDefaulter parses the operator defaults once, so misconfigurations fail on startup instead of on
+// admission.
+func NewActionsRunnerSpecDefaulter() (func(spec *inlocov1alpha1.ActionsRunnerSpec), error) {
+	defaults := actionsRunnerSpecDefaults{
+		labels:             splitList(runnerDefaultLabels),
+		serviceAccountName: runnerDefaultServiceAccountName,
+	}
+
+	// .Spec.Version is used by both images, it can't be defaulted if they are configured apart
+	if runnerImageVersion == dindImageVersion {
+		defaults.version = runnerImageVersion
+	}
+
+	for _, capability := range splitList(runnerDefaultCapabilities) {
+		switch capability := inlocov1alpha1.ActionsRunnerCapability(capability); capability {
+		case inlocov1alpha1.ActionsRunnerCapabilitySecret, inlocov1alpha1.ActionsRunnerCapabilityDocker:
+			defaults.capabilities = append(defaults.capabilities, capability)
+
+		default:
+			return nil, fmt.Errorf("unknown capability in KUBEACTIONS_RUNNER_DEFAULT_CAPABILITIES: %s", capability)
+		}
+	}
+
+	if runnerDefaultResources != "" {
+		if err := json.Unmarshal([]byte(runnerDefaultResources), &defaults.resources); err != nil {
+			return nil, fmt.Errorf("unable to parse KUBEACTIONS_RUNNER_DEFAULT_RESOURCES: %w", err)
+		}
+	}
+
+	for key := range defaults.resources {
+		switch key {
+		case runnerResourcesKey, dindResourcesKey:
+
+		default:
+			return nil, fmt.Errorf("unknown key in KUBEACTIONS_RUNNER_DEFAULT_RESOURCES: %s", key)
+		}
+	}
+
+	return defaults.apply, nil
+}
+
+func (d *actionsRunnerSpecDefaults) apply(spec *inlocov1alpha1.ActionsRunnerSpec) {
+	if spec.Version == "" {
+		spec.Version = d.version
+	}
+
+	if spec.Capabilities == nil && len(d.capabilities) > 0 {
+		spec.Capabilities = append([]inlocov1alpha1.ActionsRunnerCapability(nil), d.capabilities...)
+	}
+
+	if len(spec.Labels) == 0 && len(d.labels) > 0 {
+		spec.Labels = append([]string(nil), d.labels...)
+	}
+
+	if spec.ServiceAccountName == "" {
+		spec.ServiceAccountName = d.serviceAccountName
+	}
+
+	for key, requirements := range d.resources {
+		if key == dindResourcesKey && !hasCapability(spec, inlocov1alpha1.ActionsRunnerCapabilityDocker) {
+			continue
+		}
+
+		if _, ok := spec.Resources[key]; ok {
+			continue
+		}
+
+		if spec.Resources == nil {
+			spec.Resources = make(map[string]corev1.ResourceRequirements)
+		}
+		spec.Resources[key] = *requirements.DeepCopy()
+	}
+}
+
+func hasCapability(spec *inlocov1alpha1.ActionsRunnerSpec, capability inlocov1alpha1.ActionsRunnerCapability) bool {
+	for _, c := range spec.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestActionsRunnerSpecDefaults(t *testing.T) {
+	defaults := actionsRunnerSpecDefaults{
+		version:            "1.2.3",
+		capabilities:       []inlocov1alpha1.ActionsRunnerCapability{inlocov1alpha1.ActionsRunnerCapabilityDocker},
+		labels:             []string{"kube-actions"},
+		serviceAccountName: "default",
+		resources: map[string]corev1.ResourceRequirements{
+			runnerResourcesKey: {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			dindResourcesKey:   {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}},
+		},
+	}
+
+	var spec inlocov1alpha1.ActionsRunnerSpec
+	defaults.apply(&spec)
+
+	if spec.Version != "1.2.3" {
+		t.Error(`spec.Version != "1.2.3"`)
+	}
+
+	if len(spec.Capabilities) != 1 || spec.Capabilities[0] != inlocov1alpha1.ActionsRunnerCapabilityDocker {
+		t.Error(`len(spec.Capabilities) != 1 || spec.Capabilities[0] != inlocov1alpha1.ActionsRunnerCapabilityDocker`)
+	}
+
+	if len(spec.Labels) != 1 || spec.Labels[0] != "kube-actions" {
+		t.Error(`len(spec.Labels) != 1 || spec.Labels[0] != "kube-actions"`)
+	}
+
+	if _, ok := spec.Resources[dindResourcesKey]; !ok {
+		t.Error(`_, ok := spec.Resources[dindResourcesKey]; !ok`)
+	}
+
+	spec = inlocov1alpha1.ActionsRunnerSpec{
+		Version:      "debug",
+		Capabilities: []inlocov1alpha1.ActionsRunnerCapability{},
+		Labels:       []string{"gpu"},
+		Resources: map[string]corev1.ResourceRequirements{
+			runnerResourcesKey: {},
+		},
+	}
+	defaults.apply(&spec)
+
+	if spec.Version != "debug" {
+		t.Error(`spec.Version != "debug"`)
+	}
+
+	if len(spec.Capabilities) != 0 {
+		t.Error(`len(spec.Capabilities) != 0`)
+	}
+
+	if len(spec.Labels) != 1 || spec.Labels[0] != "gpu" {
+		t.Error(`len(spec.Labels) != 1 || spec.Labels[0] != "gpu"`)
+	}
+
+	if len(spec.Resources[runnerResourcesKey].Requests) != 0 {
+		t.Error(`len(spec.Resources[runnerResourcesKey].Requests) != 0`)
+	}
+
+	if _, ok := spec.Resources[dindResourcesKey]; ok {
+		t.Error(`_, ok := spec.Resources[dindResourcesKey]; ok`)
+	}
+}
+
+func TestNewActionsRunnerSpecDefaulterRejectsUnknownResources(t *testing.T) {
+	resources := runnerDefaultResources
+	defer func() {
+		runnerDefaultResources = resources
+	}()
+
+	runnerDefaultResources = `{"runner":{"requests":{"cpu":"1"}},"dind":{"requests":{"cpu":"1"}}}`
+	if _, err := NewActionsRunnerSpecDefaulter(); err == nil {
+		t.Error(`_, err := NewActionsRunnerSpecDefaulter(); err == nil`)
+	}
+
+	runnerDefaultResources = `{"runner":{"requests":{"cpu":"1"}},"docker":{"requests":{"cpu":"1"}}}`
+	if _, err := NewActionsRunnerSpecDefaulter(); err != nil {
+		t.Error(`_, err := NewActionsRunnerSpecDefaulter(); err != nil`)
+	}
+}
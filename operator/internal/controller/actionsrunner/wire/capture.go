@@ -0,0 +1,260 @@
+package wire
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+const (
+	// ActionsRunners annotated with "true" keep their last job messages for debugging
+	captureMessagesAnnotation = "kube-actions.inloco.com.br/capture-messages"
+
+	capturedMessagesPerRunner = 10
+
+	redacted = "***"
+)
+
+// CapturedMessage is a job message as received by the listener, Body is redacted and dropped if it can't be
+type CapturedMessage struct {
+	Time  time.Time       `json:"time"`
+	Id    MessageId       `json:"id"`
+	Type  MessageType     `json:"type"`
+	Error string          `json:"error,omitempty"` // why the listener failed to handle the message
+	Body  json.RawMessage `json:"body,omitempty"`
+}
+
+// MessageCapture keeps the last job messages of the ActionsRunners that opted in, so messages the listener failed on
+// can be replayed. It is served as JSON at /debug/kube-actions/messages/<namespace>/<name> on localhost only.
+type MessageCapture struct {
+	reader client.Reader
+
+	messages     map[types.NamespacedName][]CapturedMessage
+	messagesLock sync.RWMutex
+}
+
+func NewMessageCapture(reader client.Reader) *MessageCapture {
+	return &MessageCapture{
+		reader:   reader,
+		messages: make(map[types.NamespacedName][]CapturedMessage),
+	}
+}
+
+// Capture stores message if actionsRunner is annotated, cause is the error the listener got handling it, if any
+func (mc *MessageCapture) Capture(ctx context.Context, actionsRunner *inlocov1alpha1.ActionsRunner, message *Message, cause error) {
+	if mc == nil || message == nil || !mc.enabled(ctx, actionsRunner) {
+		return
+	}
+
+	captured := CapturedMessage{
+		Time: time.Now(),
+		Id:   message.Id,
+		Type: message.Type,
+	}
+
+	if cause != nil {
+		captured.Error = cause.Error()
+	}
+
+	body, err := redactMessageBody(message.String())
+	if err != nil {
+		logger := log.FromContext(ctx)
+		logger.Error(err, "Failed to redact captured message, dropping its body")
+	} else {
+		captured.Body = body
+	}
+
+	key := client.ObjectKeyFromObject(actionsRunner)
+
+	mc.messagesLock.Lock()
+	defer mc.messagesLock.Unlock()
+
+	messages := append(mc.messages[key], captured)
+	if len(messages) > capturedMessagesPerRunner {
+		messages = append([]CapturedMessage{}, messages[len(messages)-capturedMessagesPerRunner:]...)
+	}
+	mc.messages[key] = messages
+}
+
+// enabled reads the annotation through reader when possible, as Wires keep the ActionsRunner they were made for
+func (mc *MessageCapture) enabled(ctx context.Context, actionsRunner *inlocov1alpha1.ActionsRunner) bool {
+	if mc.reader != nil {
+		var current inlocov1alpha1.ActionsRunner
+		if err := mc.reader.Get(ctx, client.ObjectKeyFromObject(actionsRunner), &current); err == nil {
+			actionsRunner = &current
+		}
+	}
+
+	return actionsRunner.GetAnnotations()[captureMessagesAnnotation] == "true"
+}
+
+// Messages returns the captured messages of an ActionsRunner, oldest first
+func (mc *MessageCapture) Messages(key types.NamespacedName) []CapturedMessage {
+	mc.messagesLock.RLock()
+	defer mc.messagesLock.RUnlock()
+
+	return append([]CapturedMessage{}, mc.messages[key]...)
+}
+
+// Forget drops the captured messages of an ActionsRunner, e.g. once it is deleted
+func (mc *MessageCapture) Forget(key types.NamespacedName) {
+	if mc == nil {
+		return
+	}
+
+	mc.messagesLock.Lock()
+	defer mc.messagesLock.Unlock()
+
+	delete(mc.messages, key)
+}
+
+func (mc *MessageCapture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	key := types.NamespacedName{
+		Namespace: parts[len(parts)-2],
+		Name:      parts[len(parts)-1],
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(mc.Messages(key)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Replay feeds a captured message through the conversions and policies of the listener, without answering the job
+func Replay(ctx context.Context, validator *PolicyValidator, actionsRunner *inlocov1alpha1.ActionsRunner, captured CapturedMessage) (*JobRequestOutcome, error) {
+	if len(captured.Body) == 0 {
+		return nil, errors.New("captured.Body is empty")
+	}
+
+	message := Message{
+		Id:   captured.Id,
+		Type: captured.Type,
+		body: string(captured.Body),
+	}
+
+	return processJobRequest(ctx, validator, actionsRunner, &message)
+}
+
+// redactMessageBody replaces the values of secret variables and the matches of mask hints, endpoint authorizations are
+// left out
+func redactMessageBody(body string) (json.RawMessage, error) {
+	// numbers are kept as written, ids would lose precision as float64s
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+
+	var request map[string]interface{}
+	if err := decoder.Decode(&request); err != nil {
+		return nil, err
+	}
+
+	var (
+		secrets  []string
+		patterns []*regexp.Regexp
+	)
+
+	variables, _ := request["variables"].(map[string]interface{})
+	for _, v := range variables {
+		variable, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if isSecret, _ := variable["isSecret"].(bool); isSecret {
+			if value, ok := variable["value"].(string); ok && value != "" {
+				secrets = append(secrets, value)
+			}
+			variable["value"] = redacted
+		}
+	}
+
+	masks, _ := request["mask"].([]interface{})
+	for _, m := range masks {
+		mask, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		value, _ := mask["value"].(string)
+		if value == "" {
+			continue
+		}
+
+		switch mask["type"] {
+		case "regex":
+			pattern, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, pattern)
+
+		default:
+			// hints of the variable type name the variable holding the secret
+			if variable, ok := variables[value].(map[string]interface{}); ok {
+				if value, ok := variable["value"].(string); ok && value != "" && value != redacted {
+					secrets = append(secrets, value)
+				}
+				variable["value"] = redacted
+			}
+		}
+	}
+
+	if resources, ok := request["resources"].(map[string]interface{}); ok {
+		if endpoints, ok := resources["endpoints"].([]interface{}); ok {
+			for _, endpoint := range endpoints {
+				if endpoint, ok := endpoint.(map[string]interface{}); ok {
+					delete(endpoint, "authorization")
+				}
+			}
+		}
+	}
+
+	redactStrings(request, func(s string) string {
+		for _, secret := range secrets {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+
+		for _, pattern := range patterns {
+			s = pattern.ReplaceAllString(s, redacted)
+		}
+
+		return s
+	})
+
+	return json.Marshal(request)
+}
+
+func redactStrings(v interface{}, redact func(string) string) interface{} {
+	switch t := v.(type) {
+	case string:
+		return redact(t)
+
+	case map[string]interface{}:
+		for key, value := range t {
+			t[key] = redactStrings(value, redact)
+		}
+
+	case []interface{}:
+		for i, value := range t {
+			t[i] = redactStrings(value, redact)
+		}
+	}
+
+	return v
+}
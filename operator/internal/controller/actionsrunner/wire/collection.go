@@ -21,6 +21,7 @@ import (
 	"errors"
 	"sync"
 
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -28,15 +29,27 @@ import (
 
 	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
 	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/dot"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
 )
 
 type Collection struct {
 	eventChannel chan event.GenericEvent
 	wireRegistry sync.Map // map[client.ObjectKey]*Wire
+	validator    *PolicyValidator
+	recorder     record.EventRecorder
+	capture      *MessageCapture
+	github       *facades.GitHubEndpoint
 }
 
-func (c *Collection) Init() {
+// Init prepares the Collection, reader is used to resolve the ActionsRunnerPolicies referenced by runners and recorder
+// to report the agent, listener and policy decisions on them, job messages are captured into capture unless it is nil
+// and GitHub is reached through github unless it is nil or the runners point elsewhere
+func (c *Collection) Init(reader client.Reader, recorder record.EventRecorder, capture *MessageCapture, github *facades.GitHubEndpoint) {
 	c.eventChannel = make(chan event.GenericEvent)
+	c.validator = NewPolicyValidator(reader)
+	c.recorder = recorder
+	c.capture = capture
+	c.github = github
 }
 
 func (c *Collection) Deinit(ctx context.Context) {
@@ -105,6 +118,10 @@ func (c *Collection) MakeWire(ctx context.Context, actionsRunner *inlocov1alpha1
 		operatorNotifier: c.eventChannel,
 		actionsRunner:    actionsRunner,
 		DotFiles:         dotFiles,
+		ghFacade:         facades.GitHub{Endpoint: c.github},
+		validator:        c.validator,
+		recorder:         c.recorder,
+		capture:          c.capture,
 	}
 
 	logger.Info("Initializing Wire")
@@ -150,3 +167,42 @@ func (c *Collection) TryDestroy(ctx context.Context, namespacedName client.Objec
 	wire := i.(*Wire)
 	return c.Destroy(ctx, wire)
 }
+
+// Forget closes the Wire of an ActionsRunner, if cached, leaving its agent registered
+func (c *Collection) Forget(ctx context.Context, actionsRunner *inlocov1alpha1.ActionsRunner) {
+	namespacedName := client.ObjectKeyFromObject(actionsRunner)
+
+	i, ok := c.wireRegistry.LoadAndDelete(namespacedName)
+	if !ok {
+		return
+	}
+
+	wire := i.(*Wire)
+	if err := wire.Close(); err != nil {
+		logger := log.FromContext(ctx, "runner", wire.GetRunnerName())
+		logger.Error(err, "Error closing wire on Forget")
+	}
+}
+
+// Deregister removes the agent of an ActionsRunner whether or not its Wire is cached, e.g. after the operator restarted
+func (c *Collection) Deregister(ctx context.Context, actionsRunner *inlocov1alpha1.ActionsRunner, dotFiles *dot.Files) error {
+	if actionsRunner == nil {
+		return errors.New("ActionsRunner == nil")
+	}
+
+	namespacedName := client.ObjectKey{
+		Namespace: actionsRunner.GetNamespace(),
+		Name:      actionsRunner.GetName(),
+	}
+
+	if i, ok := c.wireRegistry.LoadAndDelete(namespacedName); ok {
+		return c.Destroy(ctx, i.(*Wire))
+	}
+
+	wire := &Wire{
+		actionsRunner: actionsRunner,
+		DotFiles:      dotFiles,
+		ghFacade:      facades.GitHub{Endpoint: c.github},
+	}
+	return wire.Destroy()
+}
@@ -2,16 +2,27 @@ package wire
 
 import (
 	"errors"
+	"net/http"
 
+	"github.com/google/go-github/v32/github"
 	"github.com/microsoft/azure-devops-go-api/azuredevops"
 
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
 	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/util"
 )
 
+// ErrListening is returned when the agent can't be changed because its listener is still running
+var ErrListening = errors.New("wire is listening")
+
 func IsUnrecoverable(err error) bool {
 	return isErrOAuth2InvalidClient(err) || isTaskAgentNotFoundException(err)
 }
 
+// IsAlreadyDeregistered tells whether err means there is no agent left to remove, e.g. its repository is gone
+func IsAlreadyDeregistered(err error) bool {
+	return errors.Is(err, facades.ErrNotInGitHubOwners) || isTaskAgentNotFoundException(err) || isNotFound(err)
+}
+
 func isErrOAuth2InvalidClient(err error) bool {
 	return errors.Is(err, util.ErrOAuth2InvalidClient)
 }
@@ -25,3 +36,16 @@ func isTaskAgentNotFoundException(err error) bool {
 	typeKey := wrappedError.TypeKey
 	return typeKey != nil && *typeKey == "TaskAgentNotFoundException"
 }
+
+func isNotFound(err error) bool {
+	if wrappedError, ok := err.(azuredevops.WrappedError); ok {
+		return wrappedError.StatusCode != nil && *wrappedError.StatusCode == http.StatusNotFound
+	}
+
+	var errorResponse *github.ErrorResponse
+	if errors.As(err, &errorResponse) {
+		return errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusNotFound
+	}
+
+	return false
+}
@@ -0,0 +1,40 @@
+package wire
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/microsoft/azure-devops-go-api/azuredevops"
+
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
+)
+
+func TestIsAlreadyDeregistered(t *testing.T) {
+	notFound := http.StatusNotFound
+	internalServerError := http.StatusInternalServerError
+	typeKey := "TaskAgentNotFoundException"
+
+	for _, err := range []error{
+		facades.ErrNotInGitHubOwners,
+		azuredevops.WrappedError{TypeKey: &typeKey},
+		azuredevops.WrappedError{StatusCode: &notFound},
+		fmt.Errorf("get repository: %w", &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}),
+	} {
+		if !IsAlreadyDeregistered(err) {
+			t.Errorf(`!IsAlreadyDeregistered(%v)`, err)
+		}
+	}
+
+	for _, err := range []error{
+		errors.New("connection refused"),
+		azuredevops.WrappedError{StatusCode: &internalServerError},
+		&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}},
+	} {
+		if IsAlreadyDeregistered(err) {
+			t.Errorf(`IsAlreadyDeregistered(%v)`, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package wire
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/dot"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades/fake"
+)
+
+const (
+	testTimeout = 10 * time.Second
+)
+
+// newTestClient is a fake client of the core and kube-actions types holding objects
+func newTestClient(t *testing.T, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := inlocov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+// newTestPAJR is a job request carrying contextData
+func newTestPAJR(t *testing.T) *PipelineAgentJobRequest {
+	var cd map[string]PipelineContextData
+	if err := json.Unmarshal([]byte(contextData), &cd); err != nil {
+		t.Fatal(err)
+	}
+
+	return &PipelineAgentJobRequest{
+		ContextData: &cd,
+	}
+}
+
+// newTestWire is the wire of an agent of inloco/kube-actions registered in a fake server, ready to Listen
+func newTestWire(t *testing.T, policy inlocov1alpha1.ActionsRunnerPolicyRules) (*Wire, *fake.Server) {
+	ctx := context.Background()
+
+	server, err := fake.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+
+	server.AddRepository("inloco", "kube-actions")
+
+	rsaParameters, err := dot.NewRSAParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dotFiles := &dot.Files{RSAParameters: *rsaParameters}
+	dotFiles.Runner.AgentName = "runner"
+	if err := server.Register(dotFiles); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Wire{
+		operatorNotifier: make(chan event.GenericEvent, 16),
+		actionsRunner: &inlocov1alpha1.ActionsRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "runner",
+			},
+			Spec: inlocov1alpha1.ActionsRunnerSpec{
+				Repository: inlocov1alpha1.ActionsRunnerRepository{
+					Owner:       "inloco",
+					Name:        "kube-actions",
+					APIEndpoint: server.GitHubAPIEndpoint(),
+				},
+				Policy: policy,
+			},
+		},
+		DotFiles: dotFiles,
+		ghFacade: facades.GitHub{
+			Endpoint: &facades.GitHubEndpoint{
+				APIEndpoint:  server.GitHubAPIEndpoint(),
+				PAT:          fake.GitHubPAT,
+				HTTPClient:   server.Client(),
+				Owners:       []string{"inloco"},
+				Visibilities: []string{"public", "private"},
+			},
+		},
+		jobRequests: make(chan *inlocov1alpha1.ActionsRunnerJobSpec, 1),
+		validator:   NewPolicyValidator(nil),
+		recorder:    record.NewFakeRecorder(16),
+	}
+
+	if err := w.initGH(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.adoFacade.InitForRun(ctx, dotFiles, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return w, server
+}
+
+// newTestJobRequest is the body of a job request message for ref, as the broker of server sends it
+func newTestJobRequest(t *testing.T, server *fake.Server, ref string) string {
+	systemVssConnection, err := server.SystemVssConnection(uuid.New().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"plan": map[string]interface{}{
+			"planId":          uuid.New(),
+			"planType":        "Build",
+			"scopeIdentifier": uuid.New(),
+		},
+		"timeline":       map[string]interface{}{"id": uuid.New()},
+		"jobId":          uuid.New(),
+		"requestId":      42,
+		"jobName":        "build",
+		"jobDisplayName": "Build",
+		"resources": map[string]interface{}{
+			"endpoints": []interface{}{systemVssConnection},
+		},
+		"contextData": map[string]interface{}{
+			"github": map[string]interface{}{
+				"t": 2,
+				"d": []interface{}{
+					map[string]interface{}{"k": "ref", "v": ref},
+					map[string]interface{}{"k": "workflow", "v": "CI"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(body)
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(`time.Now().After(deadline)`)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wire
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/serviceendpoint"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/task"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/util"
+)
+
+// jobReference is what it takes to fail a job, either taken from its request or from its ActionsRunnerJob
+type jobReference struct {
+	JobId           uuid.UUID
+	JobName         *string
+	JobDisplayName  *string
+	RequestId       uint64
+	Plan            *task.TaskOrchestrationPlanReference
+	Timeline        *build.TimelineReference
+	OrchestrationId string
+	Endpoints       []serviceendpoint.ServiceEndpoint
+}
+
+func jobReferenceForRequest(pajr *PipelineAgentJobRequest) (*jobReference, error) {
+	if pajr == nil {
+		return nil, errors.New("pajr == nil")
+	}
+
+	if pajr.JobId == nil {
+		return nil, errors.New("pajr.JobId == nil")
+	}
+
+	if pajr.RequestId == nil {
+		return nil, errors.New("pajr.RequestId == nil")
+	}
+
+	if pajr.Resources == nil {
+		return nil, errors.New("pajr.Resources == nil")
+	}
+
+	if pajr.Resources.Endpoints == nil {
+		return nil, errors.New("pajr.Resources.Endpoints == nil")
+	}
+
+	orchestrationId, err := util.GetOrchestrationId(*pajr.Resources.Endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jobReference{
+		JobId:           *pajr.JobId,
+		JobName:         pajr.JobName,
+		JobDisplayName:  pajr.JobDisplayName,
+		RequestId:       *pajr.RequestId,
+		Plan:            pajr.Plan,
+		Timeline:        pajr.Timeline,
+		OrchestrationId: orchestrationId,
+		Endpoints:       *pajr.Resources.Endpoints,
+	}, nil
+}
+
+// jobReferenceForActionsRunnerJob works without the job request, which is gone once the operator restarts
+func jobReferenceForActionsRunnerJob(actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob, serviceEndpoint *serviceendpoint.ServiceEndpoint) (*jobReference, error) {
+	if actionsRunnerJob == nil {
+		return nil, errors.New("actionsRunnerJob == nil")
+	}
+
+	if serviceEndpoint == nil {
+		return nil, errors.New("serviceEndpoint == nil")
+	}
+
+	spec := actionsRunnerJob.Spec
+
+	if spec.RequestId == 0 {
+		return nil, errors.New("spec.RequestId == 0")
+	}
+
+	if spec.OrchestrationId == "" {
+		return nil, errors.New(`spec.OrchestrationId == ""`)
+	}
+
+	jobId, err := uuid.Parse(spec.JobId)
+	if err != nil {
+		return nil, err
+	}
+
+	planId, err := uuid.Parse(spec.Plan.PlanId)
+	if err != nil {
+		return nil, err
+	}
+
+	scopeIdentifier, err := uuid.Parse(spec.Plan.ScopeIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	timelineId, err := uuid.Parse(spec.TimelineId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jobReference{
+		JobId:          jobId,
+		JobName:        &spec.JobName,
+		JobDisplayName: &spec.JobDisplayName,
+		RequestId:      spec.RequestId,
+		Plan: &task.TaskOrchestrationPlanReference{
+			PlanId:          &planId,
+			PlanType:        &spec.Plan.PlanType,
+			ScopeIdentifier: &scopeIdentifier,
+		},
+		Timeline: &build.TimelineReference{
+			Id: &timelineId,
+		},
+		OrchestrationId: spec.OrchestrationId,
+		Endpoints:       []serviceendpoint.ServiceEndpoint{*serviceEndpoint},
+	}, nil
+}
@@ -0,0 +1,52 @@
+package wire
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/serviceendpoint"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestJobReferenceForActionsRunnerJob(t *testing.T) {
+	var pajr PipelineAgentJobRequest
+	if err := json.Unmarshal([]byte(jobRequest), &pajr); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := toActionsRunnerJobSpec(&pajr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actionsRunnerJob := inlocov1alpha1.ActionsRunnerJob{Spec: *spec}
+	serviceEndpoint := serviceendpoint.ServiceEndpoint{}
+
+	if _, err := jobReferenceForActionsRunnerJob(&actionsRunnerJob, &serviceEndpoint); err == nil {
+		t.Error(`err == nil`)
+	}
+
+	actionsRunnerJob.Spec.OrchestrationId = "6f5fb7e5-0f0e-4ad4-9f36-5a8f7b0b4a11.build.__default"
+
+	job, err := jobReferenceForActionsRunnerJob(&actionsRunnerJob, &serviceEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.JobId != *pajr.JobId || job.RequestId != *pajr.RequestId || *job.JobDisplayName != *pajr.JobDisplayName {
+		t.Error(`job.JobId != *pajr.JobId || job.RequestId != *pajr.RequestId || *job.JobDisplayName != *pajr.JobDisplayName`)
+	}
+
+	if *job.Plan.PlanId != *pajr.Plan.PlanId || *job.Plan.ScopeIdentifier != *pajr.Plan.ScopeIdentifier || *job.Plan.PlanType != *pajr.Plan.PlanType {
+		t.Error(`*job.Plan.PlanId != *pajr.Plan.PlanId || *job.Plan.ScopeIdentifier != *pajr.Plan.ScopeIdentifier || *job.Plan.PlanType != *pajr.Plan.PlanType`)
+	}
+
+	if *job.Timeline.Id != *pajr.Timeline.Id {
+		t.Error(`*job.Timeline.Id != *pajr.Timeline.Id`)
+	}
+
+	if len(job.Endpoints) != 1 {
+		t.Error(`len(job.Endpoints) != 1`)
+	}
+}
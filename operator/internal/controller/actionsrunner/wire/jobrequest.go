@@ -0,0 +1,192 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wire
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/util"
+)
+
+const (
+	// job variable holding its timeout-minutes
+	jobTimeoutVariable = "system.jobTimeout"
+
+	// GitHub default for jobs without timeout-minutes
+	defaultJobTimeoutMinutes = 360
+)
+
+// JobRequestOutcome is what the listener makes of a job request, Spec is only set when the job is accepted
+type JobRequestOutcome struct {
+	JobRequest  *PipelineAgentJobRequest
+	Violations  []*PolicyViolation
+	PolicyError error // the job is failed, not the listener
+	Spec        *inlocov1alpha1.ActionsRunnerJobSpec
+}
+
+// processJobRequest converts and validates the job request of message, errors are about the message itself
+func processJobRequest(ctx context.Context, validator *PolicyValidator, actionsRunner *inlocov1alpha1.ActionsRunner, message *Message) (*JobRequestOutcome, error) {
+	pajr, err := toPipelineAgentJobRequest(message)
+	if err != nil {
+		return nil, err
+	}
+
+	outcome := JobRequestOutcome{
+		JobRequest: pajr,
+	}
+
+	outcome.Violations, outcome.PolicyError = validator.Validate(ctx, actionsRunner, pajr)
+	if outcome.PolicyError != nil || DeniedBy(outcome.Violations) != nil {
+		return &outcome, nil
+	}
+
+	spec, err := toActionsRunnerJobSpec(pajr)
+	if err != nil {
+		return nil, err
+	}
+	outcome.Spec = spec
+
+	return &outcome, nil
+}
+
+func toActionsRunnerJobSpec(pajr *PipelineAgentJobRequest) (*inlocov1alpha1.ActionsRunnerJobSpec, error) {
+	if pajr == nil {
+		return nil, errors.New("pajr == nil")
+	}
+
+	var spec inlocov1alpha1.ActionsRunnerJobSpec
+
+	if pajr.JobId != nil {
+		spec.JobId = pajr.JobId.String()
+	}
+
+	if pajr.RequestId != nil {
+		spec.RequestId = *pajr.RequestId
+	}
+
+	if plan := pajr.Plan; plan != nil {
+		if plan.PlanId != nil {
+			spec.Plan.PlanId = plan.PlanId.String()
+		}
+
+		if plan.PlanType != nil {
+			spec.Plan.PlanType = *plan.PlanType
+		}
+
+		if plan.ScopeIdentifier != nil {
+			spec.Plan.ScopeIdentifier = plan.ScopeIdentifier.String()
+		}
+	}
+
+	if timeline := pajr.Timeline; timeline != nil && timeline.Id != nil {
+		spec.TimelineId = timeline.Id.String()
+	}
+
+	// captured messages have their access tokens redacted, the job is only failed by the operator if it has one
+	if resources := pajr.Resources; resources != nil && resources.Endpoints != nil {
+		if orchestrationId, err := util.GetOrchestrationId(*resources.Endpoints); err == nil {
+			spec.OrchestrationId = orchestrationId
+		}
+	}
+
+	if pajr.JobName != nil {
+		spec.JobName = *pajr.JobName
+	}
+
+	if pajr.JobDisplayName != nil {
+		spec.JobDisplayName = *pajr.JobDisplayName
+	}
+
+	github, err := githubContext(pajr)
+	if err != nil {
+		return nil, err
+	}
+
+	spec.Workflow = contextString(github, "workflow")
+	spec.RunId = contextString(github, "run_id")
+	spec.RunAttempt = contextString(github, "run_attempt")
+	spec.Ref = contextString(github, "ref")
+	spec.Sha = contextString(github, "sha")
+	spec.Actor = contextString(github, "actor")
+	spec.Event = contextString(github, "event_name")
+
+	spec.TimeoutMinutes = timeoutMinutes(pajr)
+
+	return &spec, nil
+}
+
+func githubContext(pajr *PipelineAgentJobRequest) (map[string]interface{}, error) {
+	if pajr.ContextData == nil {
+		return nil, nil
+	}
+
+	github, ok := (*pajr.ContextData)["github"]
+	if !ok {
+		return nil, nil
+	}
+
+	flattened, err := github.Flattened()
+	if err != nil {
+		return nil, err
+	}
+
+	msi, ok := flattened.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`contextData["github"] is not a dictionary`)
+	}
+
+	return msi, nil
+}
+
+func contextString(msi map[string]interface{}, key string) string {
+	switch v := msi[key].(type) {
+	case string:
+		return v
+
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+
+	case bool:
+		return strconv.FormatBool(v)
+
+	default:
+		return ""
+	}
+}
+
+// timeoutMinutes is the timeout-minutes of the job, which bounds the ones of its steps and defaults to 360
+func timeoutMinutes(pajr *PipelineAgentJobRequest) uint {
+	if pajr.Variables == nil {
+		return defaultJobTimeoutMinutes
+	}
+
+	variable, ok := (*pajr.Variables)[jobTimeoutVariable]
+	if !ok || variable.Value == nil {
+		return defaultJobTimeoutMinutes
+	}
+
+	minutes, err := strconv.ParseFloat(*variable.Value, 64)
+	if err != nil || minutes <= 0 {
+		return defaultJobTimeoutMinutes
+	}
+
+	return uint(math.Ceil(minutes))
+}
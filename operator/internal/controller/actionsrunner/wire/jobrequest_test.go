@@ -0,0 +1,162 @@
+package wire
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const (
+	jobRequest = `
+		{
+			"messageType": "PipelineAgentJobRequest",
+			"plan": {
+				"planId": "6f5fb7e5-0f0e-4ad4-9f36-5a8f7b0b4a11",
+				"planType": "actions",
+				"scopeIdentifier": "00000000-0000-0000-0000-000000000000"
+			},
+			"timeline": {
+				"id": "0b3b2f7e-7d0c-4b9a-8c55-2f5d1e0c9a77"
+			},
+			"jobId": "d2c6a9a4-38b4-5d6e-a6c1-0e6b9c7a1f00",
+			"jobDisplayName": "build (ubuntu-latest)",
+			"jobName": "build",
+			"requestId": 1234,
+			"steps": [
+				{
+					"timeoutInMinutes": {
+						"type": 2,
+						"num": 10
+					}
+				},
+				{
+					"timeoutInMinutes": {
+						"type": 0,
+						"lit": "25"
+					}
+				}
+			],
+			"variables": {
+				"system.jobTimeout": {
+					"value": "35"
+				}
+			},
+			"contextData": {
+				"github": {
+					"t": 2,
+					"d": [
+						{
+							"k": "workflow",
+							"v": "CI"
+						},
+						{
+							"k": "run_id",
+							"v": "9876543210"
+						},
+						{
+							"k": "run_attempt",
+							"v": 2.0
+						},
+						{
+							"k": "ref",
+							"v": "refs/heads/master"
+						},
+						{
+							"k": "sha",
+							"v": "0123456789abcdef0123456789abcdef01234567"
+						},
+						{
+							"k": "actor",
+							"v": "dependabot[bot]"
+						},
+						{
+							"k": "event_name",
+							"v": "push"
+						}
+					]
+				}
+			}
+		}
+	`
+)
+
+func TestToActionsRunnerJobSpec(t *testing.T) {
+	var pajr PipelineAgentJobRequest
+	if err := json.Unmarshal([]byte(jobRequest), &pajr); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := toActionsRunnerJobSpec(&pajr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.JobId != "d2c6a9a4-38b4-5d6e-a6c1-0e6b9c7a1f00" {
+		t.Error(`spec.JobId != "d2c6a9a4-38b4-5d6e-a6c1-0e6b9c7a1f00"`)
+	}
+
+	if spec.RequestId != 1234 {
+		t.Error(`spec.RequestId != 1234`)
+	}
+
+	if spec.TimelineId != "0b3b2f7e-7d0c-4b9a-8c55-2f5d1e0c9a77" {
+		t.Error(`spec.TimelineId != "0b3b2f7e-7d0c-4b9a-8c55-2f5d1e0c9a77"`)
+	}
+
+	if spec.Plan.PlanType != "actions" {
+		t.Error(`spec.Plan.PlanType != "actions"`)
+	}
+
+	if spec.JobDisplayName != "build (ubuntu-latest)" {
+		t.Error(`spec.JobDisplayName != "build (ubuntu-latest)"`)
+	}
+
+	if spec.Workflow != "CI" {
+		t.Error(`spec.Workflow != "CI"`)
+	}
+
+	if spec.RunId != "9876543210" {
+		t.Error(`spec.RunId != "9876543210"`)
+	}
+
+	if spec.RunAttempt != "2" {
+		t.Error(`spec.RunAttempt != "2"`)
+	}
+
+	if spec.Ref != "refs/heads/master" {
+		t.Error(`spec.Ref != "refs/heads/master"`)
+	}
+
+	if spec.Actor != "dependabot[bot]" {
+		t.Error(`spec.Actor != "dependabot[bot]"`)
+	}
+
+	if spec.Event != "push" {
+		t.Error(`spec.Event != "push"`)
+	}
+
+	if spec.TimeoutMinutes != 35 {
+		t.Error(`spec.TimeoutMinutes != 35`)
+	}
+}
+
+func TestTimeoutMinutes(t *testing.T) {
+	var pajr PipelineAgentJobRequest
+	if err := json.Unmarshal([]byte(jobRequest), &pajr); err != nil {
+		t.Fatal(err)
+	}
+
+	if timeoutMinutes(&pajr) != 35 {
+		t.Error(`timeoutMinutes(&pajr) != 35`)
+	}
+
+	// step timeouts are bounded by the one of the job
+	delete(*pajr.Variables, jobTimeoutVariable)
+
+	if timeoutMinutes(&pajr) != defaultJobTimeoutMinutes {
+		t.Error(`timeoutMinutes(&pajr) != defaultJobTimeoutMinutes`)
+	}
+
+	if timeoutMinutes(&PipelineAgentJobRequest{}) != defaultJobTimeoutMinutes {
+		t.Error(`timeoutMinutes(&PipelineAgentJobRequest{}) != defaultJobTimeoutMinutes`)
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wire
+
+import (
+	"strings"
+)
+
+// sameLabels compares labels as case insensitive sets, like GitHub matches them
+func sameLabels(a []string, b []string) bool {
+	set := make(map[string]struct{}, len(a))
+	for _, label := range a {
+		set[strings.ToLower(label)] = struct{}{}
+	}
+
+	other := make(map[string]struct{}, len(b))
+	for _, label := range b {
+		label = strings.ToLower(label)
+		if _, ok := set[label]; !ok {
+			return false
+		}
+		other[label] = struct{}{}
+	}
+
+	return len(set) == len(other)
+}
@@ -0,0 +1,23 @@
+package wire
+
+import (
+	"testing"
+)
+
+func TestSameLabels(t *testing.T) {
+	if !sameLabels([]string{"gpu", "Linux"}, []string{"linux", "gpu"}) {
+		t.Error(`!sameLabels([]string{"gpu", "Linux"}, []string{"linux", "gpu"})`)
+	}
+
+	if !sameLabels(nil, []string{}) {
+		t.Error(`!sameLabels(nil, []string{})`)
+	}
+
+	if sameLabels([]string{"gpu"}, []string{"gpu", "large"}) {
+		t.Error(`sameLabels([]string{"gpu"}, []string{"gpu", "large"})`)
+	}
+
+	if sameLabels([]string{"gpu", "large"}, []string{"gpu"}) {
+		t.Error(`sameLabels([]string{"gpu", "large"}, []string{"gpu"})`)
+	}
+}
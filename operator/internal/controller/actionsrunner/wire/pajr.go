@@ -36,10 +36,28 @@ type WorkspaceOptions struct {
 	Clean *string `json:"clean,omitempty"`
 }
 
+type ActionStepReference struct {
+	Type           *string `json:"type,omitempty"` // repository, containerRegistry or script
+	Name           *string `json:"name,omitempty"`
+	Ref            *string `json:"ref,omitempty"`
+	RepositoryType *string `json:"repositoryType,omitempty"`
+	Path           *string `json:"path,omitempty"`
+	Image          *string `json:"image,omitempty"`
+}
+
 type JobStep struct {
-	Condition        *string             `json:"condition,omitempty"`
-	ContinueOnError  *task.TemplateToken `json:"continueOnError,omitempty"`
-	TimeoutInMinutes *task.TemplateToken `json:"timeoutInMinutes,omitempty"`
+	Type             *string              `json:"type,omitempty"`
+	Reference        *ActionStepReference `json:"reference,omitempty"`
+	Id               *string              `json:"id,omitempty"`
+	Name             *string              `json:"name,omitempty"`
+	DisplayName      *string              `json:"displayName,omitempty"`
+	DisplayNameToken *task.TemplateToken  `json:"displayNameToken,omitempty"`
+	ContextName      *string              `json:"contextName,omitempty"`
+	Condition        *string              `json:"condition,omitempty"`
+	ContinueOnError  *task.TemplateToken  `json:"continueOnError,omitempty"`
+	TimeoutInMinutes *task.TemplateToken  `json:"timeoutInMinutes,omitempty"`
+	Inputs           *task.TemplateToken  `json:"inputs,omitempty"`
+	Environment      *task.TemplateToken  `json:"environment,omitempty"`
 }
 
 type PipelineAgentJobRequest struct {
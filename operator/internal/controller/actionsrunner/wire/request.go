@@ -0,0 +1,199 @@
+package wire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/task"
+)
+
+// types of the TemplateTokens written without items
+const (
+	templateTokenSequence = 1
+	templateTokenMapping  = 2
+)
+
+// PolicyRequest is the normalized view of pajr that policy rules see as $request, next to the flattened contextData
+// they run against. It mirrors the job request the runner receives:
+//
+//	{
+//	  "messageType": "PipelineAgentJobRequest",
+//	  "jobId": "d2c6a9a4-38b4-5d6e-a6c1-0e6b9c7a1f00",
+//	  "jobName": "build",
+//	  "jobDisplayName": "build (ubuntu-latest)",
+//	  "requestId": 1234,
+//	  "jobContainer": {"image": "node:20", "options": "--cpus 1"},
+//	  "jobServiceContainers": {"redis": {"image": "docker.io/redis:7", "ports": ["6379:6379"]}},
+//	  "jobOutputs": {"version": "${{ steps.version.outputs.version }}"},
+//	  "environmentVariables": [{"CI": "true"}],
+//	  "defaults": [{"run": {"shell": "bash"}}],
+//	  "resources": {
+//	    "containers": [{"image": "node:20", "environment": {}, "options": "", "ports": [], "volumes": []}],
+//	    "repositories": [{"id": "self", "type": "GitHub", "url": "...", "version": "0123abc"}],
+//	    "endpoints": [{"name": "SystemVssConnection", "url": "...", "data": {}}]
+//	  },
+//	  "workspace": {"clean": "..."},
+//	  "steps": [{
+//	    "type": "action",
+//	    "reference": {"type": "repository", "name": "actions/checkout", "ref": "v4", "repositoryType": "GitHub"},
+//	    "id": "...", "name": "__actions_checkout", "displayName": "...", "contextName": "...",
+//	    "condition": "success()", "continueOnError": false, "timeoutInMinutes": 10,
+//	    "inputs": {"fetch-depth": 0}, "environment": {"TOKEN": "${{ secrets.TOKEN }}"}
+//	  }],
+//	  "variables": {"system.github.job": "build", "system.github.token": null}
+//	}
+//
+// TemplateTokens are decoded into plain JSON, expressions the runner is yet to evaluate are kept as "${{ expr }}"
+// strings. Values of secret variables are null and endpoint authorizations and mask hints are left out, contextData
+// is left out too as it is the input of the rules already.
+func PolicyRequest(pajr *PipelineAgentJobRequest) (map[string]interface{}, error) {
+	if pajr == nil {
+		return nil, errors.New("pajr == nil")
+	}
+
+	var request map[string]interface{}
+	if err := toJSONValue(pajr, &request); err != nil {
+		return nil, fmt.Errorf("unable to normalize job request: %w", err)
+	}
+
+	delete(request, "contextData")
+	delete(request, "mask")
+
+	for _, key := range []string{"jobContainer", "jobServiceContainers", "jobOutputs"} {
+		if token, ok := request[key]; ok {
+			request[key] = decodeTemplateToken(token)
+		}
+	}
+
+	for _, key := range []string{"environmentVariables", "defaults"} {
+		if tokens, ok := request[key].([]interface{}); ok {
+			for i, token := range tokens {
+				tokens[i] = decodeTemplateToken(token)
+			}
+		}
+	}
+
+	if steps, ok := request["steps"].([]interface{}); ok {
+		for _, step := range steps {
+			step, ok := step.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			for _, key := range []string{"displayNameToken", "continueOnError", "timeoutInMinutes", "inputs", "environment"} {
+				if token, ok := step[key]; ok {
+					step[key] = decodeTemplateToken(token)
+				}
+			}
+		}
+	}
+
+	if resources, ok := request["resources"].(map[string]interface{}); ok {
+		if endpoints, ok := resources["endpoints"].([]interface{}); ok {
+			for _, endpoint := range endpoints {
+				if endpoint, ok := endpoint.(map[string]interface{}); ok {
+					delete(endpoint, "authorization")
+				}
+			}
+		}
+	}
+
+	if pajr.Variables != nil {
+		variables := make(map[string]interface{}, len(*pajr.Variables))
+		for name, variable := range *pajr.Variables {
+			variables[name] = variableValue(variable)
+		}
+
+		request["variables"] = variables
+	}
+
+	return request, nil
+}
+
+func variableValue(variable task.VariableValue) interface{} {
+	if variable.IsSecret != nil && *variable.IsSecret {
+		return nil
+	}
+
+	if variable.Value == nil {
+		return nil
+	}
+
+	return *variable.Value
+}
+
+// decodeTemplateToken turns the JSON of a TemplateToken into the value it stands for, literal strings are written by
+// the runner as plain JSON strings already
+func decodeTemplateToken(token interface{}) interface{} {
+	msi, ok := token.(map[string]interface{})
+	if !ok {
+		return token
+	}
+
+	if lit, ok := msi["lit"]; ok {
+		return lit
+	}
+
+	if b, ok := msi["bool"]; ok {
+		return b
+	}
+
+	if num, ok := msi["num"]; ok {
+		return num
+	}
+
+	if expr, ok := msi["expr"].(string); ok {
+		return "${{ " + expr + " }}"
+	}
+
+	if directive, ok := msi["directive"].(string); ok {
+		return "${{ " + directive + " }}"
+	}
+
+	if seq, ok := msi["seq"].([]interface{}); ok {
+		values := make([]interface{}, 0, len(seq))
+		for _, item := range seq {
+			values = append(values, decodeTemplateToken(item))
+		}
+
+		return values
+	}
+
+	if pairs, ok := msi["map"].([]interface{}); ok {
+		values := make(map[string]interface{}, len(pairs))
+		for _, pair := range pairs {
+			pair, ok := pair.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			key := fmt.Sprint(decodeTemplateToken(pair["Key"]))
+			values[key] = decodeTemplateToken(pair["Value"])
+		}
+
+		return values
+	}
+
+	// empty sequences and mappings are written without items
+	switch msi["type"] {
+	case float64(templateTokenSequence):
+		return []interface{}{}
+
+	case float64(templateTokenMapping):
+		return map[string]interface{}{}
+
+	default:
+		return nil
+	}
+}
+
+// toJSONValue round trips v through JSON so gojq gets maps, slices and float64s only
+func toJSONValue(v interface{}, out interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, out)
+}
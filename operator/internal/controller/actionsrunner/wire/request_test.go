@@ -0,0 +1,158 @@
+package wire
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const (
+	policyJobRequest = `
+		{
+			"messageType": "PipelineAgentJobRequest",
+			"jobId": "d2c6a9a4-38b4-5d6e-a6c1-0e6b9c7a1f00",
+			"jobName": "build",
+			"jobContainer": {
+				"type": 2,
+				"map": [
+					{
+						"Key": {"type": 0, "lit": "image"},
+						"Value": {"type": 0, "lit": "node:20"}
+					}
+				]
+			},
+			"jobServiceContainers": {
+				"type": 2,
+				"map": [
+					{
+						"Key": {"type": 0, "lit": "redis"},
+						"Value": {
+							"type": 2,
+							"map": [
+								{
+									"Key": {"type": 0, "lit": "image"},
+									"Value": {"type": 0, "lit": "docker.io/redis:7"}
+								},
+								{
+									"Key": {"type": 0, "lit": "ports"},
+									"Value": {"type": 1, "seq": [{"type": 0, "lit": "6379:6379"}]}
+								}
+							]
+						}
+					}
+				]
+			},
+			"resources": {
+				"endpoints": [
+					{
+						"name": "SystemVssConnection",
+						"url": "https://pipelines.actions.githubusercontent.com/",
+						"authorization": {"scheme": "OAuth", "parameters": {"AccessToken": "secret"}}
+					}
+				]
+			},
+			"steps": [
+				{
+					"type": "action",
+					"reference": {"type": "repository", "name": "actions/checkout", "ref": "v4"},
+					"continueOnError": {"type": 5, "bool": true},
+					"timeoutInMinutes": {"type": 3, "expr": "inputs.timeout"},
+					"inputs": {"type": 2}
+				}
+			],
+			"variables": {
+				"system.github.job": {"value": "build"},
+				"system.github.token": {"value": "secret", "isSecret": true}
+			},
+			"mask": [
+				{"type": "regex", "value": "secret"}
+			]
+		}
+	`
+)
+
+func TestDecodeTemplateToken(t *testing.T) {
+	var token interface{}
+	if err := json.Unmarshal([]byte(`
+		{
+			"type": 2,
+			"map": [
+				{"Key": "name", "Value": "build"},
+				{"Key": "retries", "Value": {"type": 6, "num": 3}},
+				{"Key": "steps", "Value": {"type": 1}},
+				{"Key": "token", "Value": {"type": 3, "expr": "secrets.TOKEN"}},
+				{"Key": "nothing", "Value": {"type": 7}}
+			]
+		}
+	`), &token); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"name":    "build",
+		"retries": float64(3),
+		"steps":   []interface{}{},
+		"token":   "${{ secrets.TOKEN }}",
+		"nothing": nil,
+	}
+	if value := decodeTemplateToken(token); !reflect.DeepEqual(value, expected) {
+		t.Error(`!reflect.DeepEqual(value, expected)`)
+	}
+}
+
+func TestPolicyRequest(t *testing.T) {
+	var pajr PipelineAgentJobRequest
+	if err := json.Unmarshal([]byte(policyJobRequest), &pajr); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := PolicyRequest(&pajr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(request["jobContainer"], map[string]interface{}{"image": "node:20"}) {
+		t.Error(`!reflect.DeepEqual(request["jobContainer"], map[string]interface{}{"image": "node:20"})`)
+	}
+
+	services := map[string]interface{}{
+		"redis": map[string]interface{}{
+			"image": "docker.io/redis:7",
+			"ports": []interface{}{"6379:6379"},
+		},
+	}
+	if !reflect.DeepEqual(request["jobServiceContainers"], services) {
+		t.Error(`!reflect.DeepEqual(request["jobServiceContainers"], services)`)
+	}
+
+	step := request["steps"].([]interface{})[0].(map[string]interface{})
+	if step["continueOnError"] != true {
+		t.Error(`step["continueOnError"] != true`)
+	}
+	if step["timeoutInMinutes"] != "${{ inputs.timeout }}" {
+		t.Error(`step["timeoutInMinutes"] != "${{ inputs.timeout }}"`)
+	}
+	if !reflect.DeepEqual(step["inputs"], map[string]interface{}{}) {
+		t.Error(`!reflect.DeepEqual(step["inputs"], map[string]interface{}{})`)
+	}
+	if step["reference"].(map[string]interface{})["name"] != "actions/checkout" {
+		t.Error(`step["reference"].(map[string]interface{})["name"] != "actions/checkout"`)
+	}
+
+	endpoint := request["resources"].(map[string]interface{})["endpoints"].([]interface{})[0].(map[string]interface{})
+	if _, ok := endpoint["authorization"]; ok {
+		t.Error(`_, ok := endpoint["authorization"]; ok`)
+	}
+
+	variables := map[string]interface{}{
+		"system.github.job":   "build",
+		"system.github.token": nil,
+	}
+	if !reflect.DeepEqual(request["variables"], variables) {
+		t.Error(`!reflect.DeepEqual(request["variables"], variables)`)
+	}
+
+	if _, ok := request["mask"]; ok {
+		t.Error(`_, ok := request["mask"]; ok`)
+	}
+}
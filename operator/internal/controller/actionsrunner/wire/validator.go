@@ -2,23 +2,181 @@ package wire
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
 
-	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
 	"github.com/itchyny/gojq"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/jq"
 )
 
+// comma separated ActionsRunnerPolicies enforced on the ActionsRunners of a namespace that reference none
+const defaultPoliciesAnnotation = "kube-actions.inloco.com.br/default-policies"
+
+// namespace/name of the ConfigMap whose keys are the lists read by in_list
+var policyListsConfigMap = os.Getenv("KUBEACTIONS_POLICY_LISTS")
+
+// PolicyViolation tells which rule a job violated, Policy is empty for the inline rules of the ActionsRunner
+type PolicyViolation struct {
+	Policy           string
+	Name             string
+	Description      string
+	Message          string // rendered with the context of the job
+	DocumentationURL string
+	Rule             inlocov1alpha1.ActionsRunnerPolicyRule
+	Action           inlocov1alpha1.ActionsRunnerPolicyEnforcementAction
+}
+
+// String prefers the message of the rule, then its description, the rule itself is the last resort
+func (pv *PolicyViolation) String() string {
+	text := pv.Message
+	if text == "" {
+		text = pv.Description
+	}
+	if text == "" {
+		text = fmt.Sprintf("`%s`", pv.Rule)
+	}
+
+	text = fmt.Sprintf("[%s] %s", RuleID(pv.Policy, pv.Name), text)
+
+	if pv.DocumentationURL != "" {
+		text = fmt.Sprintf("%s (see %s)", text, pv.DocumentationURL)
+	}
+
+	return text
+}
+
+// RuleID identifies the rule name of policy, inline rules of ActionsRunners are named alone
+func RuleID(policy, name string) string {
+	if policy == "" {
+		return name
+	}
+
+	return policy + "/" + name
+}
+
+// DeniedBy returns the violation that fails the job, if any
+func DeniedBy(violations []*PolicyViolation) *PolicyViolation {
+	for _, violation := range violations {
+		if violation.Action == inlocov1alpha1.ActionsRunnerPolicyEnforcementActionDeny {
+			return violation
+		}
+	}
+
+	return nil
+}
+
+func enforcementActionOrDefault(actions ...inlocov1alpha1.ActionsRunnerPolicyEnforcementAction) inlocov1alpha1.ActionsRunnerPolicyEnforcementAction {
+	for _, action := range actions {
+		if action != "" {
+			return action
+		}
+	}
+
+	return inlocov1alpha1.ActionsRunnerPolicyEnforcementActionDeny
+}
+
+type compiledRule struct {
+	name             string
+	description      string
+	documentationURL string
+	rule             inlocov1alpha1.ActionsRunnerPolicyRule
+	action           inlocov1alpha1.ActionsRunnerPolicyEnforcementAction
+	code             *gojq.Code
+	message          *template.Template
+}
+
+// render executes the message template, errors fall back to the description
+func (cr *compiledRule) render(contextData map[string]interface{}) string {
+	if cr.message == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	if err := cr.message.Execute(&b, contextData); err != nil {
+		return ""
+	}
+
+	return b.String()
+}
+
+type compiledPolicy struct {
+	name            string
+	resourceVersion string
+	must            []compiledRule
+	mustNot         []compiledRule
+}
+
+// PolicyValidator is shared by every Wire, referenced ActionsRunnerPolicies are read through reader on each job so
+// changes apply to listening runners, their compiled rules are cached until their resourceVersion changes.
 type PolicyValidator struct {
-	ruleCache map[inlocov1alpha1.ActionsRunnerPolicyRule]*gojq.Code
+	reader client.Reader
+
+	ruleCache   map[inlocov1alpha1.ActionsRunnerPolicyRule]*gojq.Code
+	policyCache map[string]*compiledPolicy
+	cacheLock   sync.Mutex
 }
 
-func NewPolicyValidator() *PolicyValidator {
+func NewPolicyValidator(reader client.Reader) *PolicyValidator {
 	return &PolicyValidator{
-		ruleCache: make(map[inlocov1alpha1.ActionsRunnerPolicyRule]*gojq.Code),
+		reader:      reader,
+		ruleCache:   make(map[inlocov1alpha1.ActionsRunnerPolicyRule]*gojq.Code),
+		policyCache: make(map[string]*compiledPolicy),
 	}
 }
 
-func (pv *PolicyValidator) Validate(ctx context.Context, policy *inlocov1alpha1.ActionsRunnerPolicy, pajr *PipelineAgentJobRequest) (*inlocov1alpha1.ActionsRunnerPolicyRule, error) {
+// RuleEvaluation is the decision on a single rule, Output is its first output, nil if it had none
+type RuleEvaluation struct {
+	Policy    string
+	Name      string
+	MustNot   bool
+	Rule      inlocov1alpha1.ActionsRunnerPolicyRule
+	Action    inlocov1alpha1.ActionsRunnerPolicyEnforcementAction
+	Output    interface{}
+	Violation *PolicyViolation
+}
+
+// PolicyEvaluation holds the inputs rules ran against and their decisions, in the order they ran
+type PolicyEvaluation struct {
+	ContextData map[string]interface{}
+	Request     map[string]interface{}
+	Rules       []RuleEvaluation
+}
+
+// Validate returns the rules violated by pajr, evaluation stops at the first one that denies it
+func (pv *PolicyValidator) Validate(ctx context.Context, actionsRunner *inlocov1alpha1.ActionsRunner, pajr *PipelineAgentJobRequest) ([]*PolicyViolation, error) {
+	evaluation, err := pv.evaluate(ctx, actionsRunner, pajr, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []*PolicyViolation
+	for _, rule := range evaluation.Rules {
+		if rule.Violation != nil {
+			violations = append(violations, rule.Violation)
+		}
+	}
+
+	return violations, nil
+}
+
+// Evaluate runs every rule against pajr, even after one denies it, so they can be tested offline
+func (pv *PolicyValidator) Evaluate(ctx context.Context, actionsRunner *inlocov1alpha1.ActionsRunner, pajr *PipelineAgentJobRequest) (*PolicyEvaluation, error) {
+	return pv.evaluate(ctx, actionsRunner, pajr, false)
+}
+
+func (pv *PolicyValidator) evaluate(ctx context.Context, actionsRunner *inlocov1alpha1.ActionsRunner, pajr *PipelineAgentJobRequest, stopAtDeny bool) (*PolicyEvaluation, error) {
+	if pajr.ContextData == nil {
+		return nil, errors.New("pajr.ContextData == nil")
+	}
 	contextData := *pajr.ContextData
 
 	cd := make(map[string]interface{}, len(contextData))
@@ -31,93 +189,319 @@ func (pv *PolicyValidator) Validate(ctx context.Context, policy *inlocov1alpha1.
 		cd[k] = flattened
 	}
 
-	must, err := pv.validateMust(ctx, policy.Must, cd)
+	request, err := PolicyRequest(pajr)
 	if err != nil {
 		return nil, err
 	}
-	if must != nil {
-		return must, nil
+
+	// Wires keep the ActionsRunner they were made for, so edits of its policies are read here
+	if pv.reader != nil {
+		var current inlocov1alpha1.ActionsRunner
+		switch err := pv.reader.Get(ctx, client.ObjectKeyFromObject(actionsRunner), &current); {
+		case err == nil:
+			actionsRunner = &current
+		case !apierrors.IsNotFound(err):
+			return nil, err
+		}
 	}
 
-	mustNot, err := pv.validateMustNot(ctx, policy.MustNot, cd)
+	policies, err := pv.resolvePolicies(ctx, actionsRunner)
 	if err != nil {
 		return nil, err
 	}
-	if mustNot != nil {
-		return mustNot, nil
+
+	lists, err := pv.policyLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation := PolicyEvaluation{
+		ContextData: cd,
+		Request:     request,
+	}
+
+	for _, policy := range policies {
+		rules := make([]compiledRule, 0, len(policy.must)+len(policy.mustNot))
+		rules = append(rules, policy.must...)
+		rules = append(rules, policy.mustNot...)
+
+		for i, rule := range rules {
+			mustNot := i >= len(policy.must)
+			output := pv.run(ctx, rule, cd, request, lists)
+
+			ruleEvaluation := RuleEvaluation{
+				Policy:  policy.name,
+				Name:    rule.name,
+				MustNot: mustNot,
+				Rule:    rule.rule,
+				Action:  rule.action,
+				Output:  output,
+			}
+			// must rules are violated when they do not hold, mustNot rules when they do
+			if holds := output == true; holds == mustNot {
+				ruleEvaluation.Violation = policy.violation(rule, cd)
+			}
+			evaluation.Rules = append(evaluation.Rules, ruleEvaluation)
+
+			if stopAtDeny && ruleEvaluation.Violation != nil && rule.action == inlocov1alpha1.ActionsRunnerPolicyEnforcementActionDeny {
+				return &evaluation, nil
+			}
+		}
 	}
 
-	return nil, nil
+	return &evaluation, nil
 }
 
-func (pv *PolicyValidator) validateMust(ctx context.Context, rules []inlocov1alpha1.ActionsRunnerPolicyRule, contextData map[string]interface{}) (*inlocov1alpha1.ActionsRunnerPolicyRule, error) {
-	for _, rule := range rules {
-		code, err := pv.compileRule(rule)
+// run returns the first output of rule, a rule holds when it is true
+func (pv *PolicyValidator) run(ctx context.Context, rule compiledRule, contextData, request, lists map[string]interface{}) interface{} {
+	it := rule.code.RunWithContext(ctx, contextData, request, lists)
+
+	el, ok := it.Next()
+	if !ok {
+		return nil
+	}
+
+	return el
+}
+
+func (cp *compiledPolicy) violation(rule compiledRule, contextData map[string]interface{}) *PolicyViolation {
+	return &PolicyViolation{
+		Policy:           cp.name,
+		Name:             rule.name,
+		Description:      rule.description,
+		Message:          rule.render(contextData),
+		DocumentationURL: rule.documentationURL,
+		Rule:             rule.rule,
+		Action:           rule.action,
+	}
+}
+
+// resolvePolicies returns the inline policy of actionsRunner followed by the ones it references, or the defaults of
+// its namespace, missing ActionsRunnerPolicies are errors so jobs are not accepted unchecked
+func (pv *PolicyValidator) resolvePolicies(ctx context.Context, actionsRunner *inlocov1alpha1.ActionsRunner) ([]*compiledPolicy, error) {
+	inline, err := pv.compileInlinePolicy(&actionsRunner.Spec.Policy)
+	if err != nil {
+		return nil, err
+	}
+	policies := []*compiledPolicy{inline}
+
+	names, err := pv.policyNames(ctx, actionsRunner)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		var policy inlocov1alpha1.ActionsRunnerPolicy
+		switch err := pv.reader.Get(ctx, client.ObjectKey{Name: name}, &policy); {
+		case apierrors.IsNotFound(err):
+			return nil, fmt.Errorf("ActionsRunnerPolicy %q not found", name)
+		case err != nil:
+			return nil, err
+		}
+
+		compiled, err := pv.compilePolicy(&policy)
 		if err != nil {
 			return nil, err
 		}
+		policies = append(policies, compiled)
+	}
+
+	return policies, nil
+}
+
+// policyLists reads the ConfigMap of KUBEACTIONS_POLICY_LISTS on each job, so lists change without editing rules
+func (pv *PolicyValidator) policyLists(ctx context.Context) (map[string]interface{}, error) {
+	if policyListsConfigMap == "" {
+		return nil, nil
+	}
+
+	if pv.reader == nil {
+		return nil, fmt.Errorf("unable to read policy lists %q without a reader", policyListsConfigMap)
+	}
+
+	parts := strings.SplitN(policyListsConfigMap, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("policy lists %q are not namespace/name", policyListsConfigMap)
+	}
+
+	var configMap corev1.ConfigMap
+	if err := pv.reader.Get(ctx, client.ObjectKey{Namespace: parts[0], Name: parts[1]}, &configMap); err != nil {
+		return nil, err
+	}
 
-		it := code.RunWithContext(ctx, contextData)
+	return jq.Lists(configMap.Data), nil
+}
 
-		el, ok := it.Next()
-		if !ok {
-			return &rule, nil
+func (pv *PolicyValidator) policyNames(ctx context.Context, actionsRunner *inlocov1alpha1.ActionsRunner) ([]string, error) {
+	if len(actionsRunner.Spec.PolicyRefs) > 0 {
+		names := make([]string, 0, len(actionsRunner.Spec.PolicyRefs))
+		for _, policyRef := range actionsRunner.Spec.PolicyRefs {
+			names = append(names, policyRef.Name)
 		}
 
-		b, ok := el.(bool)
-		if !ok {
-			return &rule, nil
+		if pv.reader == nil {
+			return nil, fmt.Errorf("unable to resolve ActionsRunnerPolicies %v without a reader", names)
 		}
-		if !b {
-			return &rule, nil
+
+		return names, nil
+	}
+
+	if pv.reader == nil {
+		return nil, nil
+	}
+
+	var namespace corev1.Namespace
+	if err := pv.reader.Get(ctx, client.ObjectKey{Name: actionsRunner.GetNamespace()}, &namespace); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range strings.Split(namespace.GetAnnotations()[defaultPoliciesAnnotation], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
 		}
 	}
 
-	return nil, nil
+	return names, nil
 }
 
-func (pv *PolicyValidator) validateMustNot(ctx context.Context, rules []inlocov1alpha1.ActionsRunnerPolicyRule, contextData map[string]interface{}) (*inlocov1alpha1.ActionsRunnerPolicyRule, error) {
-	for _, rule := range rules {
-		code, err := pv.compileRule(rule)
+func (pv *PolicyValidator) compileInlinePolicy(policy *inlocov1alpha1.ActionsRunnerPolicyRules) (*compiledPolicy, error) {
+	pv.cacheLock.Lock()
+	defer pv.cacheLock.Unlock()
+
+	compiled := compiledPolicy{}
+
+	// inline rules without an id are named after their position
+	for i, inlineRule := range policy.Must {
+		name := inlineRule.ID
+		if name == "" {
+			name = fmt.Sprintf("must[%d]", i)
+		}
+
+		rule, err := pv.compileRuleSpec(name, inlineRule.ActionsRunnerPolicyRuleSpec, "")
 		if err != nil {
 			return nil, err
 		}
 
-		it := code.RunWithContext(ctx, contextData)
+		compiled.must = append(compiled.must, rule)
+	}
 
-		el, ok := it.Next()
-		if !ok {
-			continue
+	for i, inlineRule := range policy.MustNot {
+		name := inlineRule.ID
+		if name == "" {
+			name = fmt.Sprintf("mustNot[%d]", i)
 		}
 
-		b, ok := el.(bool)
-		if !ok {
-			continue
+		rule, err := pv.compileRuleSpec(name, inlineRule.ActionsRunnerPolicyRuleSpec, "")
+		if err != nil {
+			return nil, err
 		}
-		if b {
-			return &rule, nil
+
+		compiled.mustNot = append(compiled.mustNot, rule)
+	}
+
+	return &compiled, nil
+}
+
+func (pv *PolicyValidator) compilePolicy(policy *inlocov1alpha1.ActionsRunnerPolicy) (*compiledPolicy, error) {
+	pv.cacheLock.Lock()
+	defer pv.cacheLock.Unlock()
+
+	if compiled, ok := pv.policyCache[policy.GetName()]; ok && compiled.resourceVersion == policy.GetResourceVersion() {
+		return compiled, nil
+	}
+
+	compiled := compiledPolicy{
+		name:            policy.GetName(),
+		resourceVersion: policy.GetResourceVersion(),
+	}
+
+	for _, namedRule := range policy.Spec.Must {
+		rule, err := pv.compileRuleSpec(namedRule.Name, namedRule.ActionsRunnerPolicyRuleSpec, policy.Spec.EnforcementAction)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ActionsRunnerPolicy %q: %w", policy.GetName(), err)
+		}
+
+		compiled.must = append(compiled.must, rule)
+	}
+
+	for _, namedRule := range policy.Spec.MustNot {
+		rule, err := pv.compileRuleSpec(namedRule.Name, namedRule.ActionsRunnerPolicyRuleSpec, policy.Spec.EnforcementAction)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ActionsRunnerPolicy %q: %w", policy.GetName(), err)
 		}
+
+		compiled.mustNot = append(compiled.mustNot, rule)
 	}
 
-	return nil, nil
+	pv.policyCache[policy.GetName()] = &compiled
+	return &compiled, nil
 }
 
+// compileRuleSpec must be called with cacheLock held
+func (pv *PolicyValidator) compileRuleSpec(name string, ruleSpec inlocov1alpha1.ActionsRunnerPolicyRuleSpec, policyAction inlocov1alpha1.ActionsRunnerPolicyEnforcementAction) (compiledRule, error) {
+	code, err := pv.compileRule(ruleSpec.Rule)
+	if err != nil {
+		return compiledRule{}, err
+	}
+
+	message, err := CompilePolicyMessage(ruleSpec)
+	if err != nil {
+		return compiledRule{}, err
+	}
+
+	return compiledRule{
+		name:             name,
+		description:      ruleSpec.Description,
+		documentationURL: ruleSpec.DocumentationURL,
+		rule:             ruleSpec.Rule,
+		action:           enforcementActionOrDefault(ruleSpec.EnforcementAction, policyAction),
+		code:             code,
+		message:          message,
+	}, nil
+}
+
+// compileRule must be called with cacheLock held
 func (pv *PolicyValidator) compileRule(rule inlocov1alpha1.ActionsRunnerPolicyRule) (*gojq.Code, error) {
 	code, ok := pv.ruleCache[rule]
 	if ok {
 		return code, nil
 	}
 
-	q, err := gojq.Parse(string(rule))
+	c, err := CompilePolicyRule(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	pv.ruleCache[rule] = c
+	return c, nil
+}
+
+// CompilePolicyRule parses and compiles rule the same way jobs are validated
+func CompilePolicyRule(rule inlocov1alpha1.ActionsRunnerPolicyRule) (*gojq.Code, error) {
+	q, err := jq.Parse(string(rule))
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse policy rule `%s`: %w", rule, err)
 	}
 
-	c, err := gojq.Compile(q)
+	c, err := gojq.Compile(q, jq.CompilerOptions(inlocov1alpha1.ActionsRunnerPolicyRequestVariable)...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to compile policy rule `%s`: %w", rule, err)
 	}
 
-	pv.ruleCache[rule] = c
 	return c, nil
 }
+
+// CompilePolicyMessage parses the message template of ruleSpec, it returns nil when there is none
+func CompilePolicyMessage(ruleSpec inlocov1alpha1.ActionsRunnerPolicyRuleSpec) (*template.Template, error) {
+	if ruleSpec.Message == "" {
+		return nil, nil
+	}
+
+	t, err := template.New("message").Parse(ruleSpec.Message)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse message of policy rule `%s`: %w", ruleSpec.Rule, err)
+	}
+
+	return t, nil
+}
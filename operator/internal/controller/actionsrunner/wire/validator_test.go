@@ -0,0 +1,304 @@
+package wire
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/task"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestPolicyValidatorInline(t *testing.T) {
+	pv := NewPolicyValidator(nil)
+	actionsRunner := &inlocov1alpha1.ActionsRunner{
+		Spec: inlocov1alpha1.ActionsRunnerSpec{
+			Policy: inlocov1alpha1.ActionsRunnerPolicyRules{
+				Must: []inlocov1alpha1.ActionsRunnerPolicyInlineRule{
+					{ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.ref == "refs/heads/master"`}},
+				},
+			},
+		},
+	}
+
+	violations, err := pv.Validate(context.Background(), actionsRunner, newTestPAJR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 0 {
+		t.Error(`len(violations) != 0`)
+	}
+
+	actionsRunner.Spec.Policy.MustNot = []inlocov1alpha1.ActionsRunnerPolicyInlineRule{
+		{ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.event.repository.archived | not`}},
+	}
+
+	violations, err = pv.Validate(context.Background(), actionsRunner, newTestPAJR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	violation := DeniedBy(violations)
+	if violation == nil || violation.Policy != "" || violation.Name != "mustNot[0]" {
+		t.Fatal(`violation == nil || violation.Policy != "" || violation.Name != "mustNot[0]"`)
+	}
+	if violation.String() != "[mustNot[0]] `.github.event.repository.archived | not`" {
+		t.Error("violation.String() != \"[mustNot[0]] `.github.event.repository.archived | not`\"")
+	}
+}
+
+func TestPolicyValidatorReferences(t *testing.T) {
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "default",
+			Annotations: map[string]string{
+				defaultPoliciesAnnotation: "baseline",
+			},
+		},
+	}
+	baseline := &inlocov1alpha1.ActionsRunnerPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "baseline",
+		},
+		Spec: inlocov1alpha1.ActionsRunnerPolicySpec{
+			MustNot: []inlocov1alpha1.ActionsRunnerPolicyNamedRule{
+				{
+					Name: "master",
+					ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{
+						Rule:             `.github.ref == "refs/heads/master"`,
+						Description:      "no master builds",
+						Message:          `{{ .github.ref }} of {{ .github.event.organization.login }} must not be built here`,
+						DocumentationURL: "https://example.com/policies#master",
+					},
+				},
+			},
+		},
+	}
+	actionsRunner := &inlocov1alpha1.ActionsRunner{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "runner",
+		},
+	}
+
+	c := newTestClient(t, namespace, baseline, actionsRunner)
+	pv := NewPolicyValidator(c)
+	ctx := context.Background()
+
+	violations, err := pv.Validate(ctx, actionsRunner, newTestPAJR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	violation := DeniedBy(violations)
+	if violation == nil || violation.Policy != "baseline" || violation.Name != "master" {
+		t.Fatal(`violation == nil || violation.Policy != "baseline" || violation.Name != "master"`)
+	}
+	if violation.String() != "[baseline/master] refs/heads/master of inloco must not be built here (see https://example.com/policies#master)" {
+		t.Error(`violation.String() != "[baseline/master] refs/heads/master of inloco must not be built here (see https://example.com/policies#master)"`)
+	}
+
+	baseline.Spec.MustNot[0].Rule = `.github.ref == "refs/heads/main"`
+	if err := c.Update(ctx, baseline); err != nil {
+		t.Fatal(err)
+	}
+
+	violations, err = pv.Validate(ctx, actionsRunner, newTestPAJR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 0 {
+		t.Error(`len(violations) != 0`)
+	}
+
+	actionsRunner.Spec.PolicyRefs = []inlocov1alpha1.ActionsRunnerPolicyReference{{Name: "missing"}}
+	if err := c.Update(ctx, actionsRunner); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pv.Validate(ctx, actionsRunner, newTestPAJR(t)); err == nil {
+		t.Error(`_, err := pv.Validate(ctx, actionsRunner, newTestPAJR(t)); err == nil`)
+	}
+}
+
+func TestPolicyValidatorEnforcementActions(t *testing.T) {
+	trial := &inlocov1alpha1.ActionsRunnerPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "trial",
+		},
+		Spec: inlocov1alpha1.ActionsRunnerPolicySpec{
+			EnforcementAction: inlocov1alpha1.ActionsRunnerPolicyEnforcementActionWarn,
+			Must: []inlocov1alpha1.ActionsRunnerPolicyNamedRule{
+				{Name: "archived", ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.event.repository.archived`}},
+				{Name: "main", ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.ref == "refs/heads/main"`, EnforcementAction: inlocov1alpha1.ActionsRunnerPolicyEnforcementActionAudit}},
+				{Name: "tags", ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.ref | startswith("refs/tags/")`, EnforcementAction: inlocov1alpha1.ActionsRunnerPolicyEnforcementActionDeny}},
+				{Name: "never", ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `false`}},
+			},
+		},
+	}
+	actionsRunner := &inlocov1alpha1.ActionsRunner{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "runner",
+		},
+		Spec: inlocov1alpha1.ActionsRunnerSpec{
+			PolicyRefs: []inlocov1alpha1.ActionsRunnerPolicyReference{{Name: "trial"}},
+		},
+	}
+
+	pv := NewPolicyValidator(newTestClient(t, trial, actionsRunner))
+
+	violations, err := pv.Validate(context.Background(), actionsRunner, newTestPAJR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(violations) != 3 {
+		t.Fatal(`len(violations) != 3`)
+	}
+
+	if violations[0].Action != inlocov1alpha1.ActionsRunnerPolicyEnforcementActionWarn {
+		t.Error(`violations[0].Action != inlocov1alpha1.ActionsRunnerPolicyEnforcementActionWarn`)
+	}
+
+	if violations[1].Action != inlocov1alpha1.ActionsRunnerPolicyEnforcementActionAudit {
+		t.Error(`violations[1].Action != inlocov1alpha1.ActionsRunnerPolicyEnforcementActionAudit`)
+	}
+
+	if DeniedBy(violations) != violations[2] {
+		t.Error(`DeniedBy(violations) != violations[2]`)
+	}
+
+	var w Wire
+	issues, err := w.issuesForViolations(violations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(issues) != 2 || issues[0].Type != &task.IssueTypeValues.Warning || issues[1].Type != &task.IssueTypeValues.Error {
+		t.Error(`len(issues) != 2 || issues[0].Type != &task.IssueTypeValues.Warning || issues[1].Type != &task.IssueTypeValues.Error`)
+	}
+}
+
+func TestPolicyValidatorRequest(t *testing.T) {
+	pv := NewPolicyValidator(nil)
+	actionsRunner := &inlocov1alpha1.ActionsRunner{
+		Spec: inlocov1alpha1.ActionsRunnerSpec{
+			Policy: inlocov1alpha1.ActionsRunnerPolicyRules{
+				Must: []inlocov1alpha1.ActionsRunnerPolicyInlineRule{
+					{ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `$request.jobContainer.image | startswith("node:")`}},
+				},
+				MustNot: []inlocov1alpha1.ActionsRunnerPolicyInlineRule{
+					{ID: "docker-hub", ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `$request.jobServiceContainers[].image | startswith("docker.io/")`}},
+				},
+			},
+		},
+	}
+
+	pajr := newTestPAJR(t)
+	if err := json.Unmarshal([]byte(policyJobRequest), pajr); err != nil {
+		t.Fatal(err)
+	}
+
+	violations, err := pv.Validate(context.Background(), actionsRunner, pajr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(violations) != 1 || violations[0].Name != "docker-hub" {
+		t.Error(`len(violations) != 1 || violations[0].Name != "docker-hub"`)
+	}
+}
+
+func TestPolicyValidatorLists(t *testing.T) {
+	lists := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "kube-actions",
+			Name:      "policy-lists",
+		},
+		Data: map[string]string{
+			"organizations": "inloco\n",
+		},
+	}
+	actionsRunner := &inlocov1alpha1.ActionsRunner{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "runner",
+		},
+		Spec: inlocov1alpha1.ActionsRunnerSpec{
+			Policy: inlocov1alpha1.ActionsRunnerPolicyRules{
+				Must: []inlocov1alpha1.ActionsRunnerPolicyInlineRule{
+					{ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.event.organization.login | in_list("organizations")`}},
+				},
+			},
+		},
+	}
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "default",
+		},
+	}
+
+	defer func(configMap string) {
+		policyListsConfigMap = configMap
+	}(policyListsConfigMap)
+	policyListsConfigMap = "kube-actions/policy-lists"
+
+	pv := NewPolicyValidator(newTestClient(t, lists, actionsRunner, namespace))
+
+	violations, err := pv.Validate(context.Background(), actionsRunner, newTestPAJR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 0 {
+		t.Error(`len(violations) != 0`)
+	}
+
+	policyListsConfigMap = "kube-actions/missing"
+	if _, err := pv.Validate(context.Background(), actionsRunner, newTestPAJR(t)); err == nil {
+		t.Error(`err == nil`)
+	}
+}
+
+func TestPolicyValidatorEvaluate(t *testing.T) {
+	pv := NewPolicyValidator(nil)
+	actionsRunner := &inlocov1alpha1.ActionsRunner{
+		Spec: inlocov1alpha1.ActionsRunnerSpec{
+			Policy: inlocov1alpha1.ActionsRunnerPolicyRules{
+				Must: []inlocov1alpha1.ActionsRunnerPolicyInlineRule{
+					{ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.ref | startswith("refs/tags/")`}},
+					{ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.ref`}},
+				},
+				MustNot: []inlocov1alpha1.ActionsRunnerPolicyInlineRule{
+					{ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.event.repository.archived`}},
+				},
+			},
+		},
+	}
+
+	violations, err := pv.Validate(context.Background(), actionsRunner, newTestPAJR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 {
+		t.Error(`len(violations) != 1`)
+	}
+
+	evaluation, err := pv.Evaluate(context.Background(), actionsRunner, newTestPAJR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(evaluation.Rules) != 3 {
+		t.Fatal(`len(evaluation.Rules) != 3`)
+	}
+
+	if evaluation.Rules[1].Output != "refs/heads/master" || evaluation.Rules[1].Violation == nil {
+		t.Error(`evaluation.Rules[1].Output != "refs/heads/master" || evaluation.Rules[1].Violation == nil`)
+	}
+
+	if !evaluation.Rules[2].MustNot || evaluation.Rules[2].Output != false || evaluation.Rules[2].Violation != nil {
+		t.Error(`!evaluation.Rules[2].MustNot || evaluation.Rules[2].Output != false || evaluation.Rules[2].Violation != nil`)
+	}
+}
@@ -21,9 +21,13 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/microsoft/azure-devops-go-api/azuredevops/serviceendpoint"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/task"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/taskagent"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/strings"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -41,6 +45,17 @@ const (
 	runnerContextKey = contextKey("runner")
 )
 
+// reasons of the Events recorded on the ActionsRunner
+const (
+	reasonAgentRegistered        = "AgentRegistered"
+	reasonListenerStarted        = "ListenerStarted"
+	reasonListenerStopped        = "ListenerStopped"
+	reasonJobAccepted            = "JobAccepted"
+	reasonPolicyViolated         = "PolicyViolated"
+	reasonPolicyAudited          = "PolicyAudited"
+	reasonPolicyEvaluationFailed = "PolicyEvaluationFailed"
+)
+
 type Wire struct {
 	operatorNotifier chan<- event.GenericEvent
 
@@ -50,40 +65,55 @@ type Wire struct {
 	ghFacade  facades.GitHub
 	adoFacade facades.AzureDevOps
 
-	jobRequests chan struct{}
-	loopClose   chan struct{}
+	jobRequests   chan *inlocov1alpha1.ActionsRunnerJobSpec
+	loopClose     chan struct{}
+	loopCloseLock sync.Mutex
+
+	jobRequest     *PipelineAgentJobRequest
+	jobRequestLock sync.RWMutex
 
 	invalid bool
 
+	// labels the agent is currently registered with, they may lag behind the ActionsRunner while a job runs
+	labels []string
+
 	listening     bool
 	listeningLock sync.RWMutex
 
+	lastMessageType MessageType
+	lastMessageTime time.Time
+	lastMessageLock sync.RWMutex
+
 	validator *PolicyValidator
+	recorder  record.EventRecorder
+	capture   *MessageCapture
 }
 
 func (w *Wire) initGH(ctx context.Context) error {
-	if err := w.ghFacade.Init(ctx, w.actionsRunner.Spec.Repository.Owner, w.actionsRunner.Spec.Repository.Name); err != nil {
+	if err := w.ghFacade.Init(ctx, w.actionsRunner.Spec.Repository); err != nil {
 		return err
 	}
-	w.DotFiles.Runner.GitHubUrl = w.ghFacade.Repository.GetGitCommitsURL()
+	w.DotFiles.Runner.GitHubUrl = w.ghFacade.RunnerURL()
 
 	return nil
 }
 
 func (w *Wire) initADO(ctx context.Context, runnerEvent facades.RunnerEvent) error {
-	credential, err := facades.GetGitHubTenantCredential(ctx, w.ghFacade.Repository, runnerEvent)
+	credential, err := facades.GetGitHubTenantCredential(ctx, &w.ghFacade, runnerEvent)
 	if err != nil {
 		return err
 	}
 	w.DotFiles.Runner.ServerUrl = credential.GetURL()
 
-	return w.adoFacade.InitForCRUD(ctx, w.DotFiles, w.actionsRunner.Spec.Labels, credential.GetToken(), credential.GetURL())
+	return w.adoFacade.InitForCRUD(ctx, w.DotFiles, w.labels, w.actionsRunner.Spec.Repository.RunnerGroup, credential.GetToken(), credential.GetURL())
 }
 
 func (w *Wire) init(ctx context.Context) error {
 	// TODO: check if runner needs to be re-registered
 	logger := log.FromContext(ctx)
 
+	w.labels = w.actionsRunner.Spec.Labels
+
 	if err := w.initDotFiles(); err != nil {
 		logger.Error(err, "Error initializing dot files")
 		return err
@@ -99,10 +129,11 @@ func (w *Wire) init(ctx context.Context) error {
 		return err
 	}
 
-	if err := w.adoFacade.InitForRun(ctx, w.DotFiles, w.actionsRunner.Spec.Labels); err != nil {
+	if err := w.adoFacade.InitForRun(ctx, w.DotFiles, w.labels); err != nil {
 		logger.Error(err, "Error initializing Azure DevOps facade for run")
 		return err
 	}
+	w.event(corev1.EventTypeNormal, reasonAgentRegistered, "Registered agent %q with labels %v", w.DotFiles.Runner.AgentName, w.labels)
 
 	if err := w.adoFacade.InitAzureDevOpsTaskAgentSession(ctx); err != nil {
 		logger.Error(err, "Error initializing Azure DevOps task agent session")
@@ -115,37 +146,83 @@ func (w *Wire) init(ctx context.Context) error {
 	}
 
 	if w.jobRequests == nil {
-		w.jobRequests = make(chan struct{}, 1)
+		w.jobRequests = make(chan *inlocov1alpha1.ActionsRunnerJobSpec, 1)
 	}
 
 	if w.validator == nil {
-		w.validator = NewPolicyValidator()
+		w.validator = NewPolicyValidator(nil)
 	}
 
 	return nil
 }
 
+// Labels returns the labels the agent is registered with.
+func (w *Wire) Labels() []string {
+	return w.labels
+}
+
+// LabelsDrifted tells whether the agent must be replaced to match labels.
+func (w *Wire) LabelsDrifted(labels []string) bool {
+	return !sameLabels(w.labels, labels)
+}
+
+// UpdateLabels replaces the agent keeping its id and credentials. It refuses to while the listener runs so no job is
+// accepted with stale labels, callers must make sure no job is in flight, Close the wire and wait for it to stop
+// listening, then start listening again afterwards.
+func (w *Wire) UpdateLabels(ctx context.Context, labels []string) error {
+	if w.Listening() {
+		return ErrListening
+	}
+
+	previous := w.labels
+	w.labels = labels
+
+	if err := w.initADO(ctx, facades.RunnerEventRegister); err != nil {
+		w.labels = previous
+		return err
+	}
+
+	// the agent was already replaced, but it can't be run with, so the wire is rebuilt rather than trusted
+	if err := w.adoFacade.InitForRun(ctx, w.DotFiles, w.labels); err != nil {
+		w.labels = previous
+		w.invalid = true
+		return err
+	}
+	w.event(corev1.EventTypeNormal, reasonAgentRegistered, "Registered agent %q with labels %v", w.DotFiles.Runner.AgentName, w.labels)
+
+	return nil
+}
+
 func (w *Wire) GetRunnerName() string {
 	return fmt.Sprintf("%s/%s", w.actionsRunner.GetNamespace(), w.actionsRunner.GetName())
 }
 
+// Destroy removes the agent with a remove token, so it works even without a previously initialized Wire
 func (w *Wire) Destroy() error {
 	ctx := context.Background()
 
+	if w.DotFiles == nil {
+		w.DotFiles = &dot.Files{
+			Runner: dot.Runner{
+				AgentName: AgentName(w.actionsRunner),
+			},
+		}
+	}
+
 	if err := w.initGH(ctx); err != nil {
 		return err
 	}
 
-	if err := w.initADO(ctx, facades.RunnerEventRemove); err != nil {
+	credential, err := facades.GetGitHubTenantCredential(ctx, &w.ghFacade, facades.RunnerEventRemove)
+	if err != nil {
 		return err
 	}
 
-	// TODO: review agent deletion at this point
-	if err := w.adoFacade.DeleteAgent(ctx); err != nil {
+	if err := w.adoFacade.InitForRemoval(ctx, w.DotFiles, w.actionsRunner.Spec.Repository.RunnerGroup, credential.GetToken(), credential.GetURL()); err != nil {
 		return err
 	}
 
-	return nil
+	return w.adoFacade.RemoveAgent(ctx)
 }
 
 func (w *Wire) Init(ctx context.Context) error {
@@ -181,7 +258,7 @@ func (w *Wire) initDotFiles() error {
 
 	w.DotFiles = &dot.Files{
 		Runner: dot.Runner{
-			AgentName:     strings.ShortenString(fmt.Sprintf("KA %s %s", w.actionsRunner.GetNamespace(), w.actionsRunner.GetName()), 64),
+			AgentName:     AgentName(w.actionsRunner),
 			PoolId:        1,
 			PoolName:      "Default",
 			DisableUpdate: true,
@@ -195,7 +272,12 @@ func (w *Wire) initDotFiles() error {
 	return nil
 }
 
-func (w *Wire) JobRequests() <-chan struct{} {
+// AgentName is the name of the runner registered in GitHub for an ActionsRunner, e.g. "KA namespace name"
+func AgentName(actionsRunner *inlocov1alpha1.ActionsRunner) string {
+	return strings.ShortenString(fmt.Sprintf("KA %s %s", actionsRunner.GetNamespace(), actionsRunner.GetName()), 64)
+}
+
+func (w *Wire) JobRequests() <-chan *inlocov1alpha1.ActionsRunnerJobSpec {
 	return w.jobRequests
 }
 
@@ -210,6 +292,21 @@ func (w *Wire) Listening() bool {
 	return w.listening
 }
 
+func (w *Wire) LastMessage() (MessageType, time.Time) {
+	w.lastMessageLock.RLock()
+	defer w.lastMessageLock.RUnlock()
+
+	return w.lastMessageType, w.lastMessageTime
+}
+
+func (w *Wire) setLastMessage(messageType MessageType) {
+	w.lastMessageLock.Lock()
+	defer w.lastMessageLock.Unlock()
+
+	w.lastMessageType = messageType
+	w.lastMessageTime = time.Now()
+}
+
 func (w *Wire) Listen() {
 	w.listeningLock.Lock()
 	defer w.listeningLock.Unlock()
@@ -231,20 +328,25 @@ func (w *Wire) Listen() {
 		}
 
 		defer func() {
-			w.listening = false
-
 			if err := w.Close(); err != nil {
 				logger.Error(err, "Error closing agent session")
 			}
 
 			if r := recover(); r != nil {
 				logger.Error(fmt.Errorf("%v", r), "Recovering from error in wire listener")
+				w.event(corev1.EventTypeWarning, reasonListenerStopped, "Stopped listening for jobs: %v", r)
+			} else {
+				w.event(corev1.EventTypeNormal, reasonListenerStopped, "Stopped listening for jobs")
+			}
 
-				// trigger reconciliation on error to setup listener again
-				logger.Info("Trigger reconciliation to setup listener again")
-				if err := w.trySendEvent(genericEvent); err != nil {
-					logger.Error(err, "Error notifying event on recover")
-				}
+			w.listeningLock.Lock()
+			w.listening = false
+			w.listeningLock.Unlock()
+
+			// trigger reconciliation to setup listener again, reconciles waiting for the listener to stop included
+			logger.Info("Trigger reconciliation to setup listener again")
+			if err := w.trySendEvent(genericEvent); err != nil {
+				logger.Error(err, "Error notifying event on listener stop")
 			}
 		}()
 
@@ -259,6 +361,7 @@ func (w *Wire) Listen() {
 			logger.Info("Wire gone")
 			panic(err)
 		}
+		w.event(corev1.EventTypeNormal, reasonListenerStarted, "Listening for jobs as agent %q", w.DotFiles.Runner.AgentName)
 
 		var lastMessageId *uint64
 		for !w.isClosed() {
@@ -289,29 +392,49 @@ func (w *Wire) Listen() {
 			messageLogger := logger.WithValues("id", message.Id, "type", message.Type)
 
 			messageLogger.Info("Message received")
+			w.setLastMessage(message.Type)
 			metrics.IncGitHubActionsEventCounter(w.actionsRunner.GetNamespace(), w.GetRunnerName(), string(message.Type))
 
 			if message.Type == MessageTypePipelineAgentJobRequest {
-				pajr, err := toPipelineAgentJobRequest(message)
-				if err != nil {
-					panic(err)
+				outcome, err := processJobRequest(ctx, w.validator, w.actionsRunner, message)
+
+				cause := err
+				if outcome != nil && outcome.PolicyError != nil {
+					cause = outcome.PolicyError
 				}
+				w.capture.Capture(ctx, w.actionsRunner, message, cause)
 
-				violatedRule, err := w.validator.Validate(ctx, &w.actionsRunner.Spec.Policy, pajr)
 				if err != nil {
 					panic(err)
 				}
 
-				if violatedRule == nil {
-					messageLogger.Info("PipelineAgentJobRequest validated, notifying reconciler and disabling listener")
-					w.jobRequests <- struct{}{}
+				pajr, violations := outcome.JobRequest, outcome.Violations
+				w.recordPolicyViolations(pajr, violations)
+
+				if spec := outcome.Spec; spec != nil {
+					if err := w.onPolicyWarnings(ctx, pajr, violations); err != nil {
+						messageLogger.Error(err, "onPolicyWarnings failed")
+					}
+
+					messageLogger.Info("PipelineAgentJobRequest validated, notifying reconciler and disabling listener", "workflow", spec.Workflow, "job", spec.JobDisplayName)
+					w.event(corev1.EventTypeNormal, reasonJobAccepted, "Accepted job %q of workflow %q", spec.JobDisplayName, spec.Workflow)
+					w.setJobRequest(pajr)
+					w.jobRequests <- spec
 					w.operatorNotifier <- genericEvent
 					break
 				}
 
-				messageLogger.Info("PipelineAgentJobRequest aborted, job request violated rule", "violatedRule", violatedRule)
-				if err := w.onPolicyViolation(ctx, pajr, violatedRule); err != nil {
-					messageLogger.Error(err, "onPolicyViolation failed")
+				if outcome.PolicyError != nil {
+					messageLogger.Error(outcome.PolicyError, "PipelineAgentJobRequest aborted, unable to evaluate policies")
+					w.event(corev1.EventTypeWarning, reasonPolicyEvaluationFailed, "Job %q aborted, unable to evaluate policies: %v", jobDisplayName(pajr), outcome.PolicyError)
+					if err := w.onPolicyError(ctx, pajr, outcome.PolicyError); err != nil {
+						messageLogger.Error(err, "onPolicyError failed")
+					}
+				} else {
+					messageLogger.Info("PipelineAgentJobRequest aborted, job request violated rule", "violatedRule", DeniedBy(violations).String())
+					if err := w.onPolicyViolation(ctx, pajr, violations); err != nil {
+						messageLogger.Error(err, "onPolicyViolation failed")
+					}
 				}
 			}
 
@@ -329,6 +452,11 @@ func (w *Wire) Listen() {
 				}
 				messageLogger.Info("Agent deleted")
 			}
+
+			// trigger reconciliation so the last message shows up on ActionsRunnerStatus
+			if err := w.trySendEvent(genericEvent); err != nil {
+				messageLogger.Error(err, "Error notifying event on message")
+			}
 		}
 
 		logger.Info("Stop listening")
@@ -337,49 +465,160 @@ func (w *Wire) Listen() {
 	w.listening = true
 }
 
-func (w *Wire) onPolicyViolation(ctx context.Context, pajr *PipelineAgentJobRequest, violatedRule *inlocov1alpha1.ActionsRunnerPolicyRule) error {
-	if violatedRule == nil {
-		return errors.New("violatedRule == nil")
+func (w *Wire) setJobRequest(pajr *PipelineAgentJobRequest) {
+	w.jobRequestLock.Lock()
+	defer w.jobRequestLock.Unlock()
+
+	w.jobRequest = pajr
+}
+
+// JobConnection returns the SystemVssConnection of the accepted job jobId, so it outlives the Wire along with the job
+func (w *Wire) JobConnection(jobId string) (*serviceendpoint.ServiceEndpoint, error) {
+	w.jobRequestLock.RLock()
+	pajr := w.jobRequest
+	w.jobRequestLock.RUnlock()
+
+	if pajr == nil || pajr.JobId == nil || pajr.JobId.String() != jobId {
+		return nil, fmt.Errorf("job request %s not found", jobId)
+	}
+
+	if pajr.Resources == nil || pajr.Resources.Endpoints == nil {
+		return nil, errors.New("pajr.Resources.Endpoints == nil")
+	}
+
+	return util.GetSystemVssConnectionEndpoint(*pajr.Resources.Endpoints)
+}
+
+// OnJobDeadlineExceeded fails the job of an ActionsRunnerJob whose Pod was killed by its activeDeadlineSeconds, otherwise
+// it would only be failed by GitHub once the runner stops renewing the request, without any hint of what happened.
+func (w *Wire) OnJobDeadlineExceeded(ctx context.Context, actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob, serviceEndpoint *serviceendpoint.ServiceEndpoint) error {
+	job, err := jobReferenceForActionsRunnerJob(actionsRunnerJob, serviceEndpoint)
+	if err != nil {
+		return err
+	}
+
+	message := "This job was stopped because it exceeded the deadline of its runner Pod"
+	if timeout := actionsRunnerJob.Spec.TimeoutMinutes; timeout > 0 {
+		message = fmt.Sprintf("%s, derived from its timeout of %d minutes", message, timeout)
+	}
+	message += ". This is an infrastructure timeout, consider lowering timeout-minutes or raising the maxJobDuration of the ActionsRunner."
+
+	issues := []task.Issue{
+		{
+			Type:    &task.IssueTypeValues.Error,
+			Message: &message,
+		},
+	}
+	return w.failJob(ctx, job, issues)
+}
+
+func (w *Wire) onPolicyViolation(ctx context.Context, pajr *PipelineAgentJobRequest, violations []*PolicyViolation) error {
+	issues, err := w.issuesForViolations(violations)
+	if err != nil {
+		return err
+	}
+
+	job, err := jobReferenceForRequest(pajr)
+	if err != nil {
+		return err
+	}
+
+	return w.failJob(ctx, job, issues)
+}
+
+// onPolicyWarnings attaches the violations of warn rules to the timeline record of a job that is allowed to run
+func (w *Wire) onPolicyWarnings(ctx context.Context, pajr *PipelineAgentJobRequest, violations []*PolicyViolation) error {
+	issues, err := w.issuesForViolations(violations)
+	if err != nil {
+		return err
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+
+	job, err := jobReferenceForRequest(pajr)
+	if err != nil {
+		return err
+	}
+
+	timelineRecords, err := w.timelineRecordsForIssues(job, issues)
+	if err != nil {
+		return err
 	}
 
-	if pajr == nil {
-		return errors.New("pajr == nil")
+	// the job is still about to run, so only its issues are updated
+	for i := range timelineRecords {
+		timelineRecords[i].State = nil
+		timelineRecords[i].Result = nil
 	}
 
-	if pajr.JobId == nil {
-		return errors.New("pajr.JobId == nil")
+	if err := w.adoFacade.InitAzureDevOpsJobBroker(ctx, job.Plan, job.Timeline, job.Endpoints); err != nil {
+		return err
 	}
 
-	if pajr.RequestId == nil {
-		return errors.New("pajr.RequestId == nil")
+	_, err = w.adoFacade.UpdateRecord(ctx, timelineRecords)
+	return err
+}
+
+// recordPolicyViolations counts every violation and reports them as Events on the ActionsRunner
+func (w *Wire) recordPolicyViolations(pajr *PipelineAgentJobRequest, violations []*PolicyViolation) {
+	jobName := jobDisplayName(pajr)
+
+	for _, violation := range violations {
+		metrics.IncPolicyViolationsCounter(w.actionsRunner.GetNamespace(), w.GetRunnerName(), violation.Policy, violation.Name, string(violation.Action))
+
+		reason := reasonPolicyViolated
+		if violation.Action == inlocov1alpha1.ActionsRunnerPolicyEnforcementActionAudit {
+			reason = reasonPolicyAudited
+		}
+		w.event(corev1.EventTypeWarning, reason, "Job %q violated rule %s (%s): %s", jobName, RuleID(violation.Policy, violation.Name), violation.Action, violation)
 	}
+}
 
-	if pajr.Resources == nil {
-		return errors.New("pajr.Resources == nil")
+func jobDisplayName(pajr *PipelineAgentJobRequest) string {
+	if pajr == nil || pajr.JobDisplayName == nil {
+		return ""
 	}
 
-	if pajr.Resources.Endpoints == nil {
-		return errors.New("pajr.Resources.Endpoints == nil")
+	return *pajr.JobDisplayName
+}
+
+func (w *Wire) onPolicyError(ctx context.Context, pajr *PipelineAgentJobRequest, policyErr error) error {
+	message := fmt.Sprintf("This job was not allowed to run because the runner policies could not be evaluated: %v", policyErr)
+	issues := []task.Issue{
+		{
+			Type:    &task.IssueTypeValues.Error,
+			Message: &message,
+		},
 	}
 
-	timelineRecords, err := w.timelineRecordsForViolatedRule(pajr, violatedRule)
+	job, err := jobReferenceForRequest(pajr)
 	if err != nil {
 		return err
 	}
 
-	orchestrationId, err := util.GetOrchestrationId(*pajr.Resources.Endpoints)
+	return w.failJob(ctx, job, issues)
+}
+
+func (w *Wire) failJob(ctx context.Context, job *jobReference, issues []task.Issue) error {
+	if job == nil {
+		return errors.New("job == nil")
+	}
+
+	timelineRecords, err := w.timelineRecordsForIssues(job, issues)
 	if err != nil {
 		return err
 	}
 
 	request := taskagent.TaskAgentJobRequest{
-		RequestId: pajr.RequestId,
+		RequestId: &job.RequestId,
 	}
-	if _, err := w.adoFacade.UpdateAgentRequest(ctx, &request, &orchestrationId); err != nil {
+	if _, err := w.adoFacade.UpdateAgentRequest(ctx, &request, &job.OrchestrationId); err != nil {
 		return err
 	}
 
-	if err := w.adoFacade.InitAzureDevOpsTaskClient(pajr.Plan, pajr.Timeline, *pajr.Resources.Endpoints); err != nil {
+	if err := w.adoFacade.InitAzureDevOpsJobBroker(ctx, job.Plan, job.Timeline, job.Endpoints); err != nil {
 		return err
 	}
 
@@ -389,20 +628,15 @@ func (w *Wire) onPolicyViolation(ctx context.Context, pajr *PipelineAgentJobRequ
 
 	return w.adoFacade.RaisePlanEvent(ctx, &task.JobEvent{
 		Name:      JobCompleted.StringReference(),
-		JobId:     pajr.JobId,
-		RequestId: pajr.RequestId,
+		JobId:     &job.JobId,
+		RequestId: &job.RequestId,
 		Result:    &task.TaskResultValues.Failed,
 	})
 }
 
-func (w *Wire) timelineRecordsForViolatedRule(pajr *PipelineAgentJobRequest, violatedRule *inlocov1alpha1.ActionsRunnerPolicyRule) ([]task.TimelineRecord, error) {
-	if pajr == nil {
-		return nil, errors.New("pajr == nil")
-	}
-
-	issues, err := w.issuesForViolatedRule(violatedRule)
-	if err != nil {
-		return nil, err
+func (w *Wire) timelineRecordsForIssues(job *jobReference, issues []task.Issue) ([]task.TimelineRecord, error) {
+	if job == nil {
+		return nil, errors.New("job == nil")
 	}
 
 	var errorCount int
@@ -423,9 +657,9 @@ func (w *Wire) timelineRecordsForViolatedRule(pajr *PipelineAgentJobRequest, vio
 	workerName := w.GetRunnerName()
 	timelineRecord := task.TimelineRecord{
 		Type:         JobTimelineRecordType.StringReference(),
-		Id:           pajr.JobId,
-		RefName:      pajr.JobName,
-		Name:         pajr.JobDisplayName,
+		Id:           &job.JobId,
+		RefName:      job.JobName,
+		Name:         job.JobDisplayName,
 		State:        &task.TimelineRecordStateValues.Completed,
 		Result:       &task.TaskResultValues.Failed,
 		WorkerName:   &workerName,
@@ -440,20 +674,32 @@ func (w *Wire) timelineRecordsForViolatedRule(pajr *PipelineAgentJobRequest, vio
 	return timelineRecords, nil
 }
 
-func (w *Wire) issuesForViolatedRule(violatedRule *inlocov1alpha1.ActionsRunnerPolicyRule) ([]task.Issue, error) {
-	if violatedRule == nil {
-		return nil, errors.New("violatedRule == nil")
-	}
+// issuesForViolations makes an error of the denying violation and warnings of the warn ones, audited ones are left out
+func (w *Wire) issuesForViolations(violations []*PolicyViolation) ([]task.Issue, error) {
+	var issues []task.Issue
 
-	message := fmt.Sprintf("This job was not allowed to run because it violated a runner policy: %s", *violatedRule)
-	issue := &task.Issue{
-		Type:    &task.IssueTypeValues.Error,
-		Message: &message,
-	}
+	for _, violation := range violations {
+		if violation == nil {
+			return nil, errors.New("violation == nil")
+		}
 
-	issues := []task.Issue{
-		*issue,
+		switch violation.Action {
+		case inlocov1alpha1.ActionsRunnerPolicyEnforcementActionDeny:
+			message := fmt.Sprintf("This job was not allowed to run because it violated a runner policy: %s", violation)
+			issues = append(issues, task.Issue{
+				Type:    &task.IssueTypeValues.Error,
+				Message: &message,
+			})
+
+		case inlocov1alpha1.ActionsRunnerPolicyEnforcementActionWarn:
+			message := fmt.Sprintf("This job violated a runner policy, it was allowed to run because the rule only warns: %s", violation)
+			issues = append(issues, task.Issue{
+				Type:    &task.IssueTypeValues.Warning,
+				Message: &message,
+			})
+		}
 	}
+
 	return issues, nil
 }
 
@@ -461,9 +707,11 @@ func (w *Wire) Close() error {
 	ctx := context.Background()
 	logger := log.FromContext(ctx, "runner", w.GetRunnerName())
 
+	w.loopCloseLock.Lock()
 	if !w.isClosed() {
 		close(w.loopClose)
 	}
+	w.loopCloseLock.Unlock()
 
 	logger.Info("Closing wire")
 	if err := w.adoFacade.DeinitAzureDevOpsTaskAgentSession(ctx); err != nil {
@@ -483,6 +731,15 @@ func (w *Wire) isClosed() bool {
 	}
 }
 
+// event records an Event on the ActionsRunner, Wires made without a recorder only log
+func (w *Wire) event(eventType string, reason string, messageFmt string, args ...interface{}) {
+	if w.recorder == nil {
+		return
+	}
+
+	w.recorder.Eventf(w.actionsRunner, eventType, reason, messageFmt, args...)
+}
+
 func (w *Wire) trySendEvent(genericEvent event.GenericEvent) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -0,0 +1,178 @@
+package wire
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/task"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestRecordPolicyViolations(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	w := &Wire{
+		actionsRunner: &inlocov1alpha1.ActionsRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "runner",
+			},
+		},
+		recorder: recorder,
+	}
+
+	jobName := "build"
+	pajr := &PipelineAgentJobRequest{JobDisplayName: &jobName}
+	violations := []*PolicyViolation{
+		{Policy: "branches", Name: "master", Message: "only master", Action: inlocov1alpha1.ActionsRunnerPolicyEnforcementActionAudit},
+		{Policy: "forks", Name: "is-fork", Message: "no forks", Action: inlocov1alpha1.ActionsRunnerPolicyEnforcementActionDeny},
+	}
+
+	w.recordPolicyViolations(pajr, violations)
+
+	if len(recorder.Events) != 2 {
+		t.Fatal(`len(recorder.Events) != 2`)
+	}
+
+	audited := <-recorder.Events
+	if !strings.HasPrefix(audited, "Warning PolicyAudited") || !strings.Contains(audited, `"build"`) || !strings.Contains(audited, "branches/master") {
+		t.Error(`!strings.HasPrefix(audited, "Warning PolicyAudited") || !strings.Contains(audited, "\"build\"") || !strings.Contains(audited, "branches/master")`)
+	}
+
+	violated := <-recorder.Events
+	if !strings.HasPrefix(violated, "Warning PolicyViolated") || !strings.Contains(violated, "forks/is-fork") || !strings.Contains(violated, "no forks") {
+		t.Error(`!strings.HasPrefix(violated, "Warning PolicyViolated") || !strings.Contains(violated, "forks/is-fork") || !strings.Contains(violated, "no forks")`)
+	}
+
+	w.recorder = nil
+	w.recordPolicyViolations(pajr, violations)
+}
+
+func TestListenAcceptsJob(t *testing.T) {
+	w, server := newTestWire(t, inlocov1alpha1.ActionsRunnerPolicyRules{})
+	w.Listen()
+
+	if _, err := server.QueueMessage(w.DotFiles, string(MessageTypePipelineAgentJobRequest), newTestJobRequest(t, server, "refs/heads/master")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case spec := <-w.jobRequests:
+		if spec.JobName != "build" || spec.Workflow != "CI" || spec.RequestId != 42 {
+			t.Error(`spec.JobName != "build" || spec.Workflow != "CI" || spec.RequestId != 42`)
+		}
+
+	case <-time.After(testTimeout):
+		t.Fatal(`<-time.After(testTimeout)`)
+	}
+
+	// the listener stops and leaves the message for the runner to acknowledge
+	waitFor(t, func() bool {
+		return server.Sessions() == 0
+	})
+
+	if len(server.MessagesDeleted()) != 0 {
+		t.Error(`len(server.MessagesDeleted()) != 0`)
+	}
+
+	if len(server.PlanEvents()) != 0 {
+		t.Error(`len(server.PlanEvents()) != 0`)
+	}
+}
+
+func TestListenFailsDeniedJob(t *testing.T) {
+	w, server := newTestWire(t, inlocov1alpha1.ActionsRunnerPolicyRules{
+		MustNot: []inlocov1alpha1.ActionsRunnerPolicyInlineRule{
+			{ID: "tags", ActionsRunnerPolicyRuleSpec: inlocov1alpha1.ActionsRunnerPolicyRuleSpec{Rule: `.github.ref | startswith("refs/tags/")`}},
+		},
+	})
+	w.Listen()
+	defer w.Close()
+
+	messageId, err := server.QueueMessage(w.DotFiles, string(MessageTypePipelineAgentJobRequest), newTestJobRequest(t, server, "refs/tags/v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool {
+		deleted := server.MessagesDeleted()
+		return len(deleted) == 1 && deleted[0] == messageId
+	})
+
+	planEvents := server.PlanEvents()
+	if len(planEvents) != 1 || planEvents[0].Result == nil || *planEvents[0].Result != task.TaskResultValues.Failed {
+		t.Fatal(`len(planEvents) != 1 || planEvents[0].Result == nil || *planEvents[0].Result != task.TaskResultValues.Failed`)
+	}
+
+	agentRequestUpdates := server.AgentRequestUpdates()
+	if len(agentRequestUpdates) != 1 || agentRequestUpdates[0].OrchestrationId == "" {
+		t.Error(`len(agentRequestUpdates) != 1 || agentRequestUpdates[0].OrchestrationId == ""`)
+	}
+
+	timelineRecords := server.TimelineRecords()
+	if len(timelineRecords) != 1 || timelineRecords[0].Issues == nil || len(*timelineRecords[0].Issues) != 1 {
+		t.Error(`len(timelineRecords) != 1 || timelineRecords[0].Issues == nil || len(*timelineRecords[0].Issues) != 1`)
+	}
+
+	select {
+	case <-w.jobRequests:
+		t.Error(`<-w.jobRequests`)
+	default:
+	}
+}
+
+func TestListenNotifiesOnClose(t *testing.T) {
+	notifier := make(chan event.GenericEvent, 16)
+
+	w, _ := newTestWire(t, inlocov1alpha1.ActionsRunnerPolicyRules{})
+	w.operatorNotifier = notifier
+	w.Listen()
+
+	if err := w.UpdateLabels(context.Background(), []string{"gpu"}); !errors.Is(err, ErrListening) {
+		t.Error(`!errors.Is(err, ErrListening)`)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the reconciler is notified once the listener is gone, not only when it fails
+	waitFor(t, func() bool {
+		return !w.Listening()
+	})
+
+	select {
+	case <-notifier:
+	case <-time.After(testTimeout):
+		t.Error(`<-time.After(testTimeout)`)
+	}
+}
+
+func TestUpdateLabelsInvalidatesOnRunFailure(t *testing.T) {
+	w, server := newTestWire(t, inlocov1alpha1.ActionsRunnerPolicyRules{})
+	w.labels = []string{"cpu"}
+
+	// the agent is still replaced through the tenant credential, but its own client assertions are rejected
+	if err := server.Revoke(w.DotFiles); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.UpdateLabels(context.Background(), []string{"gpu"}); err == nil {
+		t.Error(`err == nil`)
+	}
+
+	if !reflect.DeepEqual(w.Labels(), []string{"cpu"}) {
+		t.Error(`!reflect.DeepEqual(w.Labels(), []string{"cpu"})`)
+	}
+
+	if w.Valid() {
+		t.Error(`w.Valid()`)
+	}
+}
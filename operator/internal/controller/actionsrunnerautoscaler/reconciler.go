@@ -0,0 +1,312 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsrunnerautoscaler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	controllers "github.com/inloco/kube-actions/operator/internal/controller"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
+	"github.com/inloco/kube-actions/operator/metrics"
+)
+
+const (
+	defaultScaleUpStabilizationWindow   = 0
+	defaultScaleDownStabilizationWindow = 5 * time.Minute
+	defaultPollInterval                 = 30 * time.Second
+
+	reasonTargetNotFound     = "TargetNotFound"
+	reasonScheduleActive     = "ScheduleActive"
+	reasonInvalidSpec        = "InvalidSpec"
+	reasonGitHubError        = "GitHubError"
+	reasonRateLimited        = "RateLimited"
+	reasonJobsCounted        = "JobsCounted"
+	reasonJobsCountTruncated = "JobsCountTruncated"
+	reasonTooFewReplicas     = "TooFewReplicas"
+	reasonTooManyReplicas    = "TooManyReplicas"
+	reasonDesiredWithinRange = "DesiredWithinRange"
+)
+
+func durationOrDefault(duration *metav1.Duration, defaultDuration time.Duration) time.Duration {
+	if duration == nil {
+		return defaultDuration
+	}
+
+	return duration.Duration
+}
+
+func setCondition(status *inlocov1alpha1.ActionsRunnerAutoscalerStatus, autoscaler *inlocov1alpha1.ActionsRunnerAutoscaler, conditionType inlocov1alpha1.ActionsRunnerAutoscalerConditionType, conditionStatus metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               string(conditionType),
+		Status:             conditionStatus,
+		ObservedGeneration: autoscaler.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// desiredRepositories qualifies repository names with the owner of the runners, which is also the default for repository scoped runners
+func desiredRepositories(autoscaler *inlocov1alpha1.ActionsRunnerAutoscaler, repository inlocov1alpha1.ActionsRunnerRepository) ([]string, error) {
+	if len(autoscaler.Spec.Repositories) == 0 {
+		if repository.GetScope() != inlocov1alpha1.ActionsRunnerScopeRepository {
+			return nil, fmt.Errorf("spec.repositories is required for %s scoped runners", repository.GetScope())
+		}
+
+		return []string{repository.String()}, nil
+	}
+
+	repositories := make([]string, 0, len(autoscaler.Spec.Repositories))
+	for _, name := range autoscaler.Spec.Repositories {
+		if !strings.Contains(name, "/") {
+			if repository.GetScope() == inlocov1alpha1.ActionsRunnerScopeEnterprise {
+				return nil, fmt.Errorf("repository %q must be in the owner/name form for enterprise scoped runners", name)
+			}
+
+			name = repository.Owner + "/" + name
+		}
+
+		repositories = append(repositories, name)
+	}
+
+	return repositories, nil
+}
+
+func recommendedReplicas(autoscaler *inlocov1alpha1.ActionsRunnerAutoscaler, counts *facades.WorkflowJobCounts) (uint, string) {
+	replicas := counts.Queued + counts.InProgress
+
+	if min := autoscaler.Spec.MinReplicas; replicas < min {
+		return min, reasonTooFewReplicas
+	}
+
+	if max := autoscaler.Spec.MaxReplicas; replicas > max {
+		return max, reasonTooManyReplicas
+	}
+
+	return replicas, reasonDesiredWithinRange
+}
+
+// Reconciler reconciles an ActionsRunnerAutoscaler object
+type Reconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	MaxConcurrentReconciles int
+
+	recommendations recommendations
+}
+
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerautoscalers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerreplicasets,verbs=get;list;watch;update;patch
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&inlocov1alpha1.ActionsRunnerAutoscaler{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx, "namespacedName", req.NamespacedName.String())
+
+	var autoscaler inlocov1alpha1.ActionsRunnerAutoscaler
+	switch err := r.Get(ctx, req.NamespacedName, &autoscaler); {
+	case apierrors.IsNotFound(err):
+		logger.Info("ActionsRunnerAutoscaler not found")
+		r.recommendations.forget(req.NamespacedName)
+		metrics.DeleteAutoscaler(req.Namespace, req.Name)
+		return ctrl.Result{}, nil
+	case err != nil:
+		logger.Error(err, "Failed to get ActionsRunnerAutoscaler")
+		return ctrl.Result{}, err
+	}
+	autoscaler.SetManagedFields(nil)
+
+	if controllers.IsBeingDeleted(&autoscaler) {
+		logger.Info("ActionsRunnerAutoscaler is being deleted")
+		return ctrl.Result{}, nil
+	}
+
+	pollInterval := durationOrDefault(autoscaler.Spec.PollInterval, defaultPollInterval)
+
+	status := autoscaler.Status.DeepCopy()
+	status.ObservedGeneration = autoscaler.GetGeneration()
+
+	if autoscaler.Spec.MinReplicas > autoscaler.Spec.MaxReplicas {
+		message := fmt.Sprintf("minReplicas (%d) is greater than maxReplicas (%d)", autoscaler.Spec.MinReplicas, autoscaler.Spec.MaxReplicas)
+		setCondition(status, &autoscaler, inlocov1alpha1.ActionsRunnerAutoscalerConditionScalingActive, metav1.ConditionFalse, reasonInvalidSpec, message)
+		return ctrl.Result{}, r.updateStatus(ctx, logger, &autoscaler, status)
+	}
+
+	var actionsRunnerReplicaSet inlocov1alpha1.ActionsRunnerReplicaSet
+	switch err := r.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: autoscaler.Spec.ScaleTargetRef.Name}, &actionsRunnerReplicaSet); {
+	case apierrors.IsNotFound(err):
+		logger.Info("ActionsRunnerReplicaSet not found")
+		setCondition(status, &autoscaler, inlocov1alpha1.ActionsRunnerAutoscalerConditionScalingActive, metav1.ConditionFalse, reasonTargetNotFound, fmt.Sprintf("ActionsRunnerReplicaSet %q not found", autoscaler.Spec.ScaleTargetRef.Name))
+		return ctrl.Result{RequeueAfter: pollInterval}, r.updateStatus(ctx, logger, &autoscaler, status)
+	case err != nil:
+		logger.Error(err, "Failed to get ActionsRunnerReplicaSet")
+		return ctrl.Result{}, err
+	}
+	actionsRunnerReplicaSet.SetManagedFields(nil)
+
+	// overrides and schedules take precedence over spec.replicas, which is all the autoscaler sets
+	if activeSchedule := actionsRunnerReplicaSet.Status.ActiveSchedule; activeSchedule != "" {
+		logger.Info("ActionsRunnerReplicaSet replicas are set by a schedule", "activeSchedule", activeSchedule)
+		status.CurrentReplicas = actionsRunnerReplicaSet.Status.Replicas
+		status.DesiredReplicas = actionsRunnerReplicaSet.Status.DesiredReplicas
+		setCondition(status, &autoscaler, inlocov1alpha1.ActionsRunnerAutoscalerConditionScalingActive, metav1.ConditionFalse, reasonScheduleActive, fmt.Sprintf("ActionsRunnerReplicaSet %q replicas are set by %q", actionsRunnerReplicaSet.GetName(), activeSchedule))
+		return ctrl.Result{RequeueAfter: pollInterval}, r.updateStatus(ctx, logger, &autoscaler, status)
+	}
+
+	repository := actionsRunnerReplicaSet.Spec.Template.Repository
+
+	repositories, err := desiredRepositories(&autoscaler, repository)
+	if err != nil {
+		setCondition(status, &autoscaler, inlocov1alpha1.ActionsRunnerAutoscalerConditionScalingActive, metav1.ConditionFalse, reasonInvalidSpec, err.Error())
+		return ctrl.Result{}, r.updateStatus(ctx, logger, &autoscaler, status)
+	}
+
+	var gh facades.GitHub
+	if err := gh.Init(ctx, repository); err != nil {
+		logger.Error(err, "Failed to initialize GitHub facade")
+		setCondition(status, &autoscaler, inlocov1alpha1.ActionsRunnerAutoscalerConditionScalingActive, metav1.ConditionFalse, reasonGitHubError, err.Error())
+		if err := r.updateStatus(ctx, logger, &autoscaler, status); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	counts, err := gh.CountWorkflowJobs(ctx, repositories, actionsRunnerReplicaSet.Spec.Template.Labels)
+	if rateLimitedError, ok := facades.IsRateLimited(err); ok {
+		logger.Info("GitHub rate limit reserve reached, waiting for reset", "reset", rateLimitedError.Reset)
+		setCondition(status, &autoscaler, inlocov1alpha1.ActionsRunnerAutoscalerConditionScalingActive, metav1.ConditionFalse, reasonRateLimited, err.Error())
+		return ctrl.Result{RequeueAfter: time.Until(rateLimitedError.Reset)}, r.updateStatus(ctx, logger, &autoscaler, status)
+	}
+	if err != nil {
+		logger.Error(err, "Failed to count workflow jobs")
+		setCondition(status, &autoscaler, inlocov1alpha1.ActionsRunnerAutoscalerConditionScalingActive, metav1.ConditionFalse, reasonGitHubError, err.Error())
+		if err := r.updateStatus(ctx, logger, &autoscaler, status); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	current := actionsRunnerReplicaSet.Spec.Replicas
+	recommended, reason := recommendedReplicas(&autoscaler, counts)
+
+	upWindow := durationOrDefault(autoscaler.Spec.ScaleUpStabilizationWindow, defaultScaleUpStabilizationWindow)
+	downWindow := durationOrDefault(autoscaler.Spec.ScaleDownStabilizationWindow, defaultScaleDownStabilizationWindow)
+	desired := r.recommendations.stabilize(req.NamespacedName, time.Now(), current, recommended, upWindow, downWindow)
+
+	if desired != current {
+		logger := logger.WithValues("actionsRunnerReplicaSet", actionsRunnerReplicaSet.GetName(), "current", current, "desired", desired)
+		logger.Info("Undesired replicas, updating ActionsRunnerReplicaSet")
+		actionsRunnerReplicaSet.Spec.Replicas = desired
+
+		if err := r.Update(ctx, &actionsRunnerReplicaSet, controllers.UpdateOpts...); err != nil {
+			logger.Error(err, "Failed to update ActionsRunnerReplicaSet")
+			return ctrl.Result{}, err
+		}
+
+		direction := "up"
+		if desired < current {
+			direction = "down"
+		}
+		metrics.IncAutoscalerScaleCounter(req.Namespace, req.Name, direction)
+
+		lastScaleTime := metav1.NewTime(time.Now().Truncate(time.Second))
+		status.LastScaleTime = &lastScaleTime
+	}
+
+	status.QueuedJobs = counts.Queued
+	status.InProgressJobs = counts.InProgress
+	status.CurrentReplicas = actionsRunnerReplicaSet.Status.Replicas
+	status.DesiredReplicas = desired
+
+	countedReason, countedMessage := reasonJobsCounted, fmt.Sprintf("%d queued and %d in progress jobs in %s", counts.Queued, counts.InProgress, strings.Join(repositories, ", "))
+	if counts.Truncated {
+		countedReason, countedMessage = reasonJobsCountTruncated, fmt.Sprintf("At least %d queued and %d in progress jobs in %s, too many runs or jobs to list them all", counts.Queued, counts.InProgress, strings.Join(repositories, ", "))
+	}
+	setCondition(status, &autoscaler, inlocov1alpha1.ActionsRunnerAutoscalerConditionScalingActive, metav1.ConditionTrue, countedReason, countedMessage)
+	setCondition(status, &autoscaler, inlocov1alpha1.ActionsRunnerAutoscalerConditionScalingLimited, limitedStatus(reason), reason, fmt.Sprintf("Recommended %d replicas", recommended))
+
+	metrics.SetAutoscalerJobs(req.Namespace, req.Name, counts.Queued, counts.InProgress)
+	metrics.SetAutoscalerReplicas(req.Namespace, req.Name, recommended, desired, status.CurrentReplicas)
+
+	return ctrl.Result{RequeueAfter: pollInterval}, r.updateStatus(ctx, logger, &autoscaler, status)
+}
+
+// NewScaleTargetLookup returns the names of the ActionsRunnerAutoscalers scaling an ActionsRunnerReplicaSet
+func NewScaleTargetLookup(reader client.Reader) func(ctx context.Context, actionsRunnerReplicaSet *inlocov1alpha1.ActionsRunnerReplicaSet) ([]string, error) {
+	return func(ctx context.Context, actionsRunnerReplicaSet *inlocov1alpha1.ActionsRunnerReplicaSet) ([]string, error) {
+		var autoscalers inlocov1alpha1.ActionsRunnerAutoscalerList
+		if err := reader.List(ctx, &autoscalers, client.InNamespace(actionsRunnerReplicaSet.GetNamespace())); err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, autoscaler := range autoscalers.Items {
+			if autoscaler.Spec.ScaleTargetRef.Name == actionsRunnerReplicaSet.GetName() {
+				names = append(names, autoscaler.GetName())
+			}
+		}
+
+		return names, nil
+	}
+}
+
+func limitedStatus(reason string) metav1.ConditionStatus {
+	if reason == reasonDesiredWithinRange {
+		return metav1.ConditionFalse
+	}
+
+	return metav1.ConditionTrue
+}
+
+func (r *Reconciler) updateStatus(ctx context.Context, logger logr.Logger, autoscaler *inlocov1alpha1.ActionsRunnerAutoscaler, status *inlocov1alpha1.ActionsRunnerAutoscalerStatus) error {
+	if equality.Semantic.DeepEqual(autoscaler.Status, *status) {
+		return nil
+	}
+
+	logger.Info("ActionsRunnerAutoscalerStatus needs to be updated")
+	autoscaler.Status = *status
+
+	if err := r.Status().Update(ctx, autoscaler); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to update ActionsRunnerAutoscalerStatus")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsrunnerautoscaler
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type recommendation struct {
+	time     time.Time
+	replicas uint
+}
+
+// recommendations keeps past recommendations of each autoscaler to stabilize them like the HPA does
+type recommendations struct {
+	history map[types.NamespacedName][]recommendation
+	mutex   sync.Mutex
+}
+
+func (r *recommendations) forget(key types.NamespacedName) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.history, key)
+}
+
+// stabilize records recommended and returns the replicas to scale to, scaling up to the lowest and down to the highest
+// recommendation within the respective windows
+func (r *recommendations) stabilize(key types.NamespacedName, now time.Time, current uint, recommended uint, upWindow time.Duration, downWindow time.Duration) uint {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.history == nil {
+		r.history = make(map[types.NamespacedName][]recommendation)
+	}
+
+	maxWindow := upWindow
+	if downWindow > maxWindow {
+		maxWindow = downWindow
+	}
+
+	history := []recommendation{{time: now, replicas: recommended}}
+	for _, past := range r.history[key] {
+		if now.Sub(past.time) <= maxWindow {
+			history = append(history, past)
+		}
+	}
+	r.history[key] = history
+
+	upReplicas, downReplicas := recommended, recommended
+	for _, past := range history {
+		age := now.Sub(past.time)
+
+		if age <= upWindow && past.replicas < upReplicas {
+			upReplicas = past.replicas
+		}
+
+		if age <= downWindow && past.replicas > downReplicas {
+			downReplicas = past.replicas
+		}
+	}
+
+	switch {
+	case upReplicas > current:
+		return upReplicas
+	case downReplicas < current:
+		return downReplicas
+	default:
+		return current
+	}
+}
@@ -0,0 +1,36 @@
+package actionsrunnerautoscaler
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestStabilize(t *testing.T) {
+	var r recommendations
+	key := types.NamespacedName{Namespace: "default", Name: "autoscaler"}
+	now := time.Now()
+
+	if r.stabilize(key, now, 0, 3, 0, 5*time.Minute) != 3 {
+		t.Error(`r.stabilize(key, now, 0, 3, 0, 5*time.Minute) != 3`)
+	}
+
+	if r.stabilize(key, now.Add(time.Minute), 3, 1, 0, 5*time.Minute) != 3 {
+		t.Error(`r.stabilize(key, now.Add(time.Minute), 3, 1, 0, 5*time.Minute) != 3`)
+	}
+
+	if r.stabilize(key, now.Add(6*time.Minute), 3, 0, 0, 5*time.Minute) != 1 {
+		t.Error(`r.stabilize(key, now.Add(6*time.Minute), 3, 0, 0, 5*time.Minute) != 1`)
+	}
+
+	if r.stabilize(key, now.Add(7*time.Minute), 1, 4, time.Minute, 5*time.Minute) != 1 {
+		t.Error(`r.stabilize(key, now.Add(7*time.Minute), 1, 4, time.Minute, 5*time.Minute) != 1`)
+	}
+
+	r.forget(key)
+
+	if r.stabilize(key, now.Add(8*time.Minute), 1, 4, time.Minute, 5*time.Minute) != 4 {
+		t.Error(`r.stabilize(key, now.Add(8*time.Minute), 1, 4, time.Minute, 5*time.Minute) != 4`)
+	}
+}
@@ -18,10 +18,17 @@ package actionsrunnerjob
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -33,17 +40,31 @@ import (
 	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/util"
 )
 
+const (
+	reasonPodCreated         = "PodCreated"
+	reasonPodBuildFailed     = "PodBuildFailed"
+	reasonPodTemplateInvalid = "PodTemplateInvalid"
+	reasonPodCreateFailed    = "PodCreateFailed"
+	reasonPodFailed          = "PodFailed"
+	reasonPodUnknown         = "PodUnknown"
+	reasonPVCLost            = "PersistentVolumeClaimLost"
+)
+
 // Reconciler reconciles an ActionsRunnerJob object
 type Reconciler struct {
 	client.Client
 	Scheme                  *runtime.Scheme
 	MaxConcurrentReconciles int
+
+	// Events are recorded on the ActionsRunner, as ActionsRunnerJobs are deleted along with their jobs
+	Recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerjobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerjobs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
@@ -138,6 +159,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		logger.Info("PersistentVolumeClaimPhase changed", "phase", pvcPhase)
 		actionsRunnerJob.Status.PersistentVolumeClaimPhase = pvcPhase
 
+		if pvcPhase == corev1.ClaimLost {
+			r.Recorder.Eventf(&actionsRunner, corev1.EventTypeWarning, reasonPVCLost, "PersistentVolumeClaim %q of job %q lost its volume", persistentVolumeClaim.GetName(), actionsRunnerJob.Spec.JobDisplayName)
+		}
+
 		logger.Info("ActionsRunnerJobStatus needs to be updated")
 		if err := r.Status().Update(ctx, &actionsRunnerJob); client.IgnoreNotFound(err) != nil {
 			logger.Error(err, "Failed to update ActionsRunnerJobStatus")
@@ -167,7 +192,18 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	case apierrors.IsNotFound(err):
 		desiredPod, err := util.ToPod(&actionsRunner, &actionsRunnerJob, r.Scheme)
 		if err != nil {
-			logger.Info("Failed to build desired Pod")
+			logger.Error(err, "Failed to build desired Pod")
+
+			reason := reasonPodBuildFailed
+			var podTemplateError *util.PodTemplateError
+			if errors.As(err, &podTemplateError) {
+				reason = reasonPodTemplateInvalid
+			}
+
+			if err := r.setPodCreatedCondition(ctx, logger, &actionsRunnerJob, metav1.ConditionFalse, reason, err.Error()); err != nil {
+				return ctrl.Result{}, err
+			}
+
 			return ctrl.Result{}, err
 		}
 		if desiredPod != nil {
@@ -175,8 +211,16 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 			if err := r.Create(ctx, desiredPod, controllers.CreateOpts...); controllers.IgnoreAlreadyExists(err) != nil {
 				logger.Error(err, "Failed to create Pod")
+
+				if err := r.setPodCreatedCondition(ctx, logger, &actionsRunnerJob, metav1.ConditionFalse, reasonPodCreateFailed, err.Error()); err != nil {
+					return ctrl.Result{}, err
+				}
+
 				return ctrl.Result{}, err
 			}
+
+			r.Recorder.Eventf(&actionsRunner, corev1.EventTypeNormal, reasonPodCreated, "Created Pod %q for job %q", desiredPod.GetName(), actionsRunnerJob.Spec.JobDisplayName)
+			return ctrl.Result{}, r.setPodCreatedCondition(ctx, logger, &actionsRunnerJob, metav1.ConditionTrue, reasonPodCreated, fmt.Sprintf("Pod %q created", desiredPod.GetName()))
 		}
 
 	case err != nil:
@@ -185,9 +229,18 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 
 	podPhase := pod.Status.Phase
-	if actionsRunnerJob.Status.PodPhase != podPhase {
-		logger.Info("PodPhase changed", "phase", podPhase)
+	podReason := pod.Status.Reason
+	if actionsRunnerJob.Status.PodPhase != podPhase || actionsRunnerJob.Status.PodReason != podReason {
+		logger.Info("PodPhase changed", "phase", podPhase, "reason", podReason)
 		actionsRunnerJob.Status.PodPhase = podPhase
+		actionsRunnerJob.Status.PodReason = podReason
+
+		switch podPhase {
+		case corev1.PodFailed:
+			r.Recorder.Eventf(&actionsRunner, corev1.EventTypeWarning, reasonPodFailed, "Pod %q of job %q failed%s", pod.GetName(), actionsRunnerJob.Spec.JobDisplayName, podStatusDetails(&pod))
+		case corev1.PodUnknown:
+			r.Recorder.Eventf(&actionsRunner, corev1.EventTypeWarning, reasonPodUnknown, "Pod %q of job %q is in an unknown state%s", pod.GetName(), actionsRunnerJob.Spec.JobDisplayName, podStatusDetails(&pod))
+		}
 
 		logger.Info("ActionsRunnerJobStatus needs to be updated")
 		if err := r.Status().Update(ctx, &actionsRunnerJob); client.IgnoreNotFound(err) != nil {
@@ -215,3 +268,41 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 	return ctrl.Result{}, nil
 }
+
+func (r *Reconciler) setPodCreatedCondition(ctx context.Context, logger logr.Logger, actionsRunnerJob *inlocov1alpha1.ActionsRunnerJob, conditionStatus metav1.ConditionStatus, reason string, message string) error {
+	status := actionsRunnerJob.Status.DeepCopy()
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               string(inlocov1alpha1.ActionsRunnerJobConditionPodCreated),
+		Status:             conditionStatus,
+		ObservedGeneration: actionsRunnerJob.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+	})
+
+	if equality.Semantic.DeepEqual(actionsRunnerJob.Status, *status) {
+		return nil
+	}
+
+	logger.Info("ActionsRunnerJobStatus needs to be updated")
+	actionsRunnerJob.Status = *status
+
+	if err := r.Status().Update(ctx, actionsRunnerJob); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to update ActionsRunnerJobStatus")
+		return err
+	}
+
+	return nil
+}
+
+// podStatusDetails suffixes Event messages with the reason and message the kubelet left on the Pod, if any
+func podStatusDetails(pod *corev1.Pod) string {
+	reason, message := pod.Status.Reason, pod.Status.Message
+	switch {
+	case reason != "" && message != "":
+		return fmt.Sprintf(": %s, %s", reason, message)
+	case reason != "" || message != "":
+		return ": " + reason + message
+	}
+
+	return ""
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsrunnerpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	controllers "github.com/inloco/kube-actions/operator/internal/controller"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/wire"
+)
+
+const (
+	reasonRulesCompiled = "RulesCompiled"
+	reasonInvalidRules  = "InvalidRules"
+)
+
+// invalidRules compiles every rule of policy the way runners do, returning the names of the invalid ones along with
+// their errors
+func invalidRules(policy *inlocov1alpha1.ActionsRunnerPolicy) ([]string, []string) {
+	var names []string
+	var messages []string
+
+	check := func(list string, namedRules []inlocov1alpha1.ActionsRunnerPolicyNamedRule) {
+		for _, namedRule := range namedRules {
+			_, err := wire.CompilePolicyRule(namedRule.Rule)
+			if err == nil {
+				_, err = wire.CompilePolicyMessage(namedRule.ActionsRunnerPolicyRuleSpec)
+			}
+
+			if err != nil {
+				names = append(names, namedRule.Name)
+				messages = append(messages, fmt.Sprintf("%s[%s]: %v", list, namedRule.Name, err))
+			}
+		}
+	}
+	check("must", policy.Spec.Must)
+	check("mustNot", policy.Spec.MustNot)
+
+	return names, messages
+}
+
+// Reconciler reconciles an ActionsRunnerPolicy object
+type Reconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	MaxConcurrentReconciles int
+}
+
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerpolicies/status,verbs=get;update;patch
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&inlocov1alpha1.ActionsRunnerPolicy{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx, "name", req.Name)
+
+	var policy inlocov1alpha1.ActionsRunnerPolicy
+	switch err := r.Get(ctx, req.NamespacedName, &policy); {
+	case apierrors.IsNotFound(err):
+		logger.Info("ActionsRunnerPolicy not found")
+		return ctrl.Result{}, nil
+	case err != nil:
+		logger.Error(err, "Failed to get ActionsRunnerPolicy")
+		return ctrl.Result{}, err
+	}
+	policy.SetManagedFields(nil)
+
+	if controllers.IsBeingDeleted(&policy) {
+		logger.Info("ActionsRunnerPolicy is being deleted")
+		return ctrl.Result{}, nil
+	}
+
+	status := policy.Status.DeepCopy()
+	status.ObservedGeneration = policy.GetGeneration()
+
+	names, messages := invalidRules(&policy)
+	status.InvalidRules = names
+
+	condition := metav1.Condition{
+		Type:               string(inlocov1alpha1.ActionsRunnerPolicyConditionValid),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: policy.GetGeneration(),
+		Reason:             reasonRulesCompiled,
+	}
+	if len(messages) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonInvalidRules
+		condition.Message = strings.Join(messages, "; ")
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+
+	return ctrl.Result{}, r.updateStatus(ctx, logger, &policy, status)
+}
+
+func (r *Reconciler) updateStatus(ctx context.Context, logger logr.Logger, policy *inlocov1alpha1.ActionsRunnerPolicy, status *inlocov1alpha1.ActionsRunnerPolicyStatus) error {
+	if equality.Semantic.DeepEqual(policy.Status, *status) {
+		return nil
+	}
+
+	logger.Info("ActionsRunnerPolicyStatus needs to be updated")
+	policy.Status = *status
+
+	if err := r.Status().Update(ctx, policy); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to update ActionsRunnerPolicyStatus")
+		return err
+	}
+
+	return nil
+}
@@ -20,11 +20,14 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -34,6 +37,17 @@ import (
 	"github.com/inloco/kube-actions/operator/internal/controller"
 )
 
+// job changes are reported on the status of owned ActionsRunners, this only covers missed events
+const busyRequeueAfter = time.Minute
+
+// reasons of the Events recorded on the ActionsRunnerReplicaSet, named after the ones of ReplicaSets
+const (
+	reasonSuccessfulCreate = "SuccessfulCreate"
+	reasonFailedCreate     = "FailedCreate"
+	reasonSuccessfulDelete = "SuccessfulDelete"
+	reasonFailedDelete     = "FailedDelete"
+)
+
 func matchingLabels(actionsRunnerReplicaSet inlocov1alpha1.ActionsRunnerReplicaSet) client.MatchingLabels {
 	return client.MatchingLabels{
 		"kube-actions.inloco.com.br/actions-runner-replica-set": actionsRunnerReplicaSet.GetName(),
@@ -94,8 +108,9 @@ func desiredSelector(actionsRunnerReplicaSet *inlocov1alpha1.ActionsRunnerReplic
 // Reconciler reconciles an ActionsRunnerReplicaSet object
 type Reconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 
 	MaxConcurrentReconciles int
 }
@@ -104,6 +119,8 @@ type Reconciler struct {
 // +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerreplicasets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunner,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunner/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerjobs,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
@@ -137,9 +154,22 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, err
 	}
 
-	if actionsRunnerReplicaSet.Status.Selector != selector {
+	replicas, activeSchedule, nextBoundary, err := desiredReplicas(&actionsRunnerReplicaSet, time.Now())
+	if err != nil {
+		logger.Error(err, "Failed to resolve ActionsRunnerReplicaSet schedules")
+		return ctrl.Result{}, err
+	}
+
+	result := ctrl.Result{}
+	if !nextBoundary.IsZero() {
+		result.RequeueAfter = time.Until(nextBoundary)
+	}
+
+	if status := actionsRunnerReplicaSet.Status; status.Selector != selector || status.DesiredReplicas != replicas || status.ActiveSchedule != activeSchedule {
 		logger.Info("ActionsRunnerReplicaSetStatus needs to be updated")
 		actionsRunnerReplicaSet.Status.Selector = selector
+		actionsRunnerReplicaSet.Status.DesiredReplicas = replicas
+		actionsRunnerReplicaSet.Status.ActiveSchedule = activeSchedule
 
 		if err := r.Status().Update(ctx, &actionsRunnerReplicaSet); client.IgnoreNotFound(err) != nil {
 			logger.Error(err, "Failed to update ActionsRunnerReplicaSetStatus")
@@ -155,72 +185,148 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 	items := actionsRunnerList.Items
 
+	var actionsRunnerJobList inlocov1alpha1.ActionsRunnerJobList
+	if err := r.List(ctx, &actionsRunnerJobList, client.InNamespace(actionsRunnerReplicaSet.GetNamespace())); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	actionsRunnerJobs := make(map[string]*inlocov1alpha1.ActionsRunnerJob, len(actionsRunnerJobList.Items))
+	for i := range actionsRunnerJobList.Items {
+		actionsRunnerJob := &actionsRunnerJobList.Items[i]
+		actionsRunnerJobs[actionsRunnerJob.GetName()] = actionsRunnerJob
+	}
+
+	status := actionsRunnerReplicaSet.Status.DeepCopy()
+	status.IdleReplicas, status.BusyReplicas, status.TerminatingReplicas = 0, 0, 0
+
 	actionsRunners := make([]inlocov1alpha1.ActionsRunner, 0, len(items))
 	for _, actionsRunner := range items {
-		if !controllers.IsBeingDeleted(&actionsRunner) {
-			actionsRunners = append(actionsRunners, actionsRunner)
+		switch {
+		case controllers.IsBeingDeleted(&actionsRunner):
+			status.TerminatingReplicas++
+			continue
+		case actionsRunnerStateOf(&actionsRunner, actionsRunnerJobs) == actionsRunnerStateIdle:
+			status.IdleReplicas++
+		default:
+			status.BusyReplicas++
 		}
+
+		actionsRunners = append(actionsRunners, actionsRunner)
 	}
 
 	actual := len(actionsRunners)
-	desired := int(actionsRunnerReplicaSet.Spec.Replicas)
+	status.Replicas = uint(actual)
+	desired := int(replicas)
 
-	if actual < desired {
-		actionsRunner, err := desiredActionsRunner(&actionsRunnerReplicaSet, r.Scheme)
-		if err != nil {
-			return ctrl.Result{}, err
-		}
+	// ActionsRunners are defaulted on admission, templates stored before defaulting existed may lack those fields
+	template := actionsRunnerReplicaSet.Spec.Template.DeepCopy()
+	inlocov1alpha1.DefaultActionsRunnerSpec(template)
 
-		logger := logger.WithValues("actionsRunner", actionsRunner.GetName())
-		logger.Info("Less replicas than desired, creating ActionsRunner")
+	outdated := make([]inlocov1alpha1.ActionsRunner, 0, len(actionsRunners))
+	status.UpdatedReplicas, status.AvailableReplicas = 0, 0
+	for _, actionsRunner := range actionsRunners {
+		if needsReplacement(&actionsRunner, template) {
+			outdated = append(outdated, actionsRunner)
+		} else {
+			status.UpdatedReplicas++
+		}
 
-		if err := r.Create(ctx, actionsRunner, controllers.CreateOpts...); err != nil {
-			return ctrl.Result{}, err
+		if isAvailable(&actionsRunner) {
+			status.AvailableReplicas++
 		}
+	}
+	status.ObservedGeneration = actionsRunnerReplicaSet.GetGeneration()
 
-		logger.Info("ActionsRunnerReplicaSetStatus needs to be updated")
-		actionsRunnerReplicaSet.Status.Replicas = uint(actual + 1)
+	maxSurge, maxUnavailable, err := rolloutLimits(actionsRunnerReplicaSet.Spec.Strategy, desired)
+	if err != nil {
+		logger.Error(err, "Failed to resolve ActionsRunnerReplicaSet strategy")
+		return ctrl.Result{}, err
+	}
 
-		if err := r.Status().Update(ctx, &actionsRunnerReplicaSet); client.IgnoreNotFound(err) != nil {
-			logger.Error(err, "Failed to update ActionsRunnerReplicaSetStatus")
-			return ctrl.Result{}, err
+	// how many more ActionsRunners may become unavailable, either drained for replacement or updated in place
+	budget := int(status.AvailableReplicas) - (desired - maxUnavailable)
+
+	rollingOut := len(outdated) > 0
+	if rollingOut {
+		if actual < desired+maxSurge && int(status.UpdatedReplicas) < desired {
+			return ctrl.Result{}, r.createActionsRunner(ctx, logger, &actionsRunnerReplicaSet, status, "Rolling out, creating ActionsRunner")
 		}
 
-		return ctrl.Result{}, nil
-	}
+		if actionsRunner := scaleDownCandidate(outdated, actionsRunnerJobs, false); actionsRunner != nil && actionsRunnerStateOf(actionsRunner, actionsRunnerJobs) == actionsRunnerStateIdle {
+			available := int(status.AvailableReplicas)
+			if isAvailable(actionsRunner) {
+				available--
+			}
 
-	if actual > desired {
-		// TODO: prioritize deletion of idle ARs
-		actionsRunner := &items[0]
+			if available >= desired-maxUnavailable {
+				return ctrl.Result{}, r.deleteActionsRunner(ctx, logger, &actionsRunnerReplicaSet, status, actionsRunner, actionsRunnerJobs, "Rolling out, deleting outdated ActionsRunner")
+			}
+		}
 
-		logger := logger.WithValues("actionsRunner", actionsRunner.GetName())
-		logger.Info("More replicas than desired, deleting ActionsRunner")
+		// outdated ActionsRunners stop taking jobs, so they become idle and can be deleted
+		for i := range outdated {
+			actionsRunner := &outdated[i]
+			if actionsRunner.IsDraining() {
+				continue
+			}
+
+			if isAvailable(actionsRunner) {
+				if budget <= 0 {
+					break
+				}
+				budget--
+			}
+
+			if err := r.drainActionsRunner(ctx, logger, actionsRunner, status); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
 
-		if err := r.Delete(ctx, actionsRunner, controllers.DeleteOpts...); err != nil {
-			return ctrl.Result{}, err
+		logger.Info("Rolling out, waiting for outdated ActionsRunners to finish their jobs")
+		if result.RequeueAfter == 0 || result.RequeueAfter > busyRequeueAfter {
+			result.RequeueAfter = busyRequeueAfter
 		}
+	}
 
-		logger.Info("ActionsRunnerReplicaSetStatus needs to be updated")
-		actionsRunnerReplicaSet.Status.Replicas = uint(actual - 1)
+	if !rollingOut && actual < desired {
+		return ctrl.Result{}, r.createActionsRunner(ctx, logger, &actionsRunnerReplicaSet, status, "Less replicas than desired, creating ActionsRunner")
+	}
 
-		if err := r.Status().Update(ctx, &actionsRunnerReplicaSet); client.IgnoreNotFound(err) != nil {
-			logger.Error(err, "Failed to update ActionsRunnerReplicaSetStatus")
-			return ctrl.Result{}, err
+	if !rollingOut && actual > desired {
+		actionsRunner := scaleDownCandidate(actionsRunners, actionsRunnerJobs, actionsRunnerReplicaSet.Spec.ForceScaleDown)
+		if actionsRunner == nil {
+			logger.Info("More replicas than desired, but all ActionsRunners are running jobs")
+
+			if err := r.updateStatus(ctx, logger, &actionsRunnerReplicaSet, status); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{RequeueAfter: busyRequeueAfter}, nil
 		}
 
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.deleteActionsRunner(ctx, logger, &actionsRunnerReplicaSet, status, actionsRunner, actionsRunnerJobs, "More replicas than desired, deleting ActionsRunner")
 	}
 
 	for _, actionsRunner := range actionsRunners {
-		if reflect.DeepEqual(actionsRunner.Spec, actionsRunnerReplicaSet.Spec.Template) {
+		if reflect.DeepEqual(actionsRunner.Spec, *template) || needsReplacement(&actionsRunner, template) {
 			continue
 		}
 
 		logger := logger.WithValues("actionsRunner", actionsRunner.GetName())
+
+		// ActionsRunners being updated are unavailable until their agents are registered again
+		if isAvailable(&actionsRunner) {
+			if budget <= 0 {
+				logger.Info("Undesired spec, waiting for other ActionsRunners to be updated")
+				break
+			}
+			budget--
+		}
+
 		actionsRunner.SetManagedFields(nil)
 
 		logger.Info("Undesired spec, updating ActionsRunner")
-		actionsRunner.Spec = actionsRunnerReplicaSet.Spec.Template
+		actionsRunner.Spec = *template
 
 		if err := r.Update(ctx, &actionsRunner, controllers.UpdateOpts...); client.IgnoreNotFound(err) != nil {
 			logger.Error(err, "Failed to update ActionsRunner")
@@ -228,5 +334,95 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		}
 	}
 
-	return ctrl.Result{}, nil
+	if err := r.updateStatus(ctx, logger, &actionsRunnerReplicaSet, status); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return result, nil
+}
+
+func (r *Reconciler) createActionsRunner(ctx context.Context, logger logr.Logger, actionsRunnerReplicaSet *inlocov1alpha1.ActionsRunnerReplicaSet, status *inlocov1alpha1.ActionsRunnerReplicaSetStatus, reason string) error {
+	actionsRunner, err := desiredActionsRunner(actionsRunnerReplicaSet, r.Scheme)
+	if err != nil {
+		return err
+	}
+
+	logger.WithValues("actionsRunner", actionsRunner.GetName()).Info(reason)
+
+	if err := r.Create(ctx, actionsRunner, controllers.CreateOpts...); err != nil {
+		r.Recorder.Eventf(actionsRunnerReplicaSet, corev1.EventTypeWarning, reasonFailedCreate, "Error creating ActionsRunner: %v", err)
+		return err
+	}
+	r.Recorder.Eventf(actionsRunnerReplicaSet, corev1.EventTypeNormal, reasonSuccessfulCreate, "Created ActionsRunner %q", actionsRunner.GetName())
+
+	status.Replicas++
+	status.IdleReplicas++
+	status.UpdatedReplicas++
+
+	return r.updateStatus(ctx, logger, actionsRunnerReplicaSet, status)
+}
+
+func (r *Reconciler) deleteActionsRunner(ctx context.Context, logger logr.Logger, actionsRunnerReplicaSet *inlocov1alpha1.ActionsRunnerReplicaSet, status *inlocov1alpha1.ActionsRunnerReplicaSetStatus, actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJobs map[string]*inlocov1alpha1.ActionsRunnerJob, reason string) error {
+	logger.WithValues("actionsRunner", actionsRunner.GetName()).Info(reason)
+
+	if err := r.Delete(ctx, actionsRunner, controllers.DeleteOpts...); err != nil {
+		r.Recorder.Eventf(actionsRunnerReplicaSet, corev1.EventTypeWarning, reasonFailedDelete, "Error deleting ActionsRunner %q: %v", actionsRunner.GetName(), err)
+		return err
+	}
+	r.Recorder.Eventf(actionsRunnerReplicaSet, corev1.EventTypeNormal, reasonSuccessfulDelete, "Deleted ActionsRunner %q", actionsRunner.GetName())
+
+	status.Replicas--
+	if actionsRunnerStateOf(actionsRunner, actionsRunnerJobs) == actionsRunnerStateIdle {
+		status.IdleReplicas--
+	} else {
+		status.BusyReplicas--
+	}
+	status.TerminatingReplicas++
+
+	if !needsReplacement(actionsRunner, &actionsRunnerReplicaSet.Spec.Template) {
+		status.UpdatedReplicas--
+	}
+
+	if isAvailable(actionsRunner) {
+		status.AvailableReplicas--
+	}
+
+	return r.updateStatus(ctx, logger, actionsRunnerReplicaSet, status)
+}
+
+func (r *Reconciler) drainActionsRunner(ctx context.Context, logger logr.Logger, actionsRunner *inlocov1alpha1.ActionsRunner, status *inlocov1alpha1.ActionsRunnerReplicaSetStatus) error {
+	logger = logger.WithValues("actionsRunner", actionsRunner.GetName())
+	logger.Info("Rolling out, draining outdated ActionsRunner")
+
+	available := isAvailable(actionsRunner)
+
+	actionsRunner.SetManagedFields(nil)
+	metav1.SetMetaDataAnnotation(&actionsRunner.ObjectMeta, inlocov1alpha1.ActionsRunnerDrainAnnotation, "true")
+
+	if err := r.Update(ctx, actionsRunner, controllers.UpdateOpts...); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to drain ActionsRunner")
+		return err
+	}
+
+	if available {
+		status.AvailableReplicas--
+	}
+
+	return nil
+}
+
+func (r *Reconciler) updateStatus(ctx context.Context, logger logr.Logger, actionsRunnerReplicaSet *inlocov1alpha1.ActionsRunnerReplicaSet, status *inlocov1alpha1.ActionsRunnerReplicaSetStatus) error {
+	if reflect.DeepEqual(actionsRunnerReplicaSet.Status, *status) {
+		return nil
+	}
+
+	logger.Info("ActionsRunnerReplicaSetStatus needs to be updated")
+	actionsRunnerReplicaSet.Status = *status
+
+	if err := r.Status().Update(ctx, actionsRunnerReplicaSet); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to update ActionsRunnerReplicaSetStatus")
+		return err
+	}
+
+	return nil
 }
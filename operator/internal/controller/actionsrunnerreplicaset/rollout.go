@@ -0,0 +1,81 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsrunnerreplicaset
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+var defaultMaxSurgeAndUnavailable = intstr.FromString("25%")
+
+// rolloutLimits resolves how many ActionsRunners may exceed and fall short of desired during a rollout
+func rolloutLimits(strategy inlocov1alpha1.ActionsRunnerReplicaSetStrategy, desired int) (int, int, error) {
+	maxSurge := strategy.MaxSurge
+	if maxSurge == nil {
+		maxSurge = &defaultMaxSurgeAndUnavailable
+	}
+
+	maxUnavailable := strategy.MaxUnavailable
+	if maxUnavailable == nil {
+		maxUnavailable = &defaultMaxSurgeAndUnavailable
+	}
+
+	surge, err := intstr.GetScaledValueFromIntOrPercent(maxSurge, desired, true)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	unavailable, err := intstr.GetScaledValueFromIntOrPercent(maxUnavailable, desired, false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// same as deployments, otherwise the rollout would never progress
+	if surge == 0 && unavailable == 0 {
+		surge = 1
+	}
+
+	return surge, unavailable, nil
+}
+
+// needsReplacement reports whether actionsRunner differs from template in fields that cannot be updated in place
+func needsReplacement(actionsRunner *inlocov1alpha1.ActionsRunner, template *inlocov1alpha1.ActionsRunnerSpec) bool {
+	return !reflect.DeepEqual(actionsRunner.Spec.Repository, template.Repository)
+}
+
+// isAvailable reports whether actionsRunner is registered, caught up with its spec and not draining
+func isAvailable(actionsRunner *inlocov1alpha1.ActionsRunner) bool {
+	if actionsRunner.IsDraining() {
+		return false
+	}
+
+	conditions := actionsRunner.Status.Conditions
+
+	registered := meta.FindStatusCondition(conditions, string(inlocov1alpha1.ActionsRunnerConditionRegistered))
+	if registered == nil || registered.Status != metav1.ConditionTrue || registered.ObservedGeneration < actionsRunner.GetGeneration() {
+		return false
+	}
+
+	// in-place updates are only done once the agent was registered again with the new labels
+	return !meta.IsStatusConditionFalse(conditions, string(inlocov1alpha1.ActionsRunnerConditionLabelsSynced))
+}
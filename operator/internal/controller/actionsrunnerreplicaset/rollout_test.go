@@ -0,0 +1,68 @@
+package actionsrunnerreplicaset
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestRolloutLimits(t *testing.T) {
+	maxSurge, maxUnavailable, err := rolloutLimits(inlocov1alpha1.ActionsRunnerReplicaSetStrategy{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if maxSurge != 3 || maxUnavailable != 2 {
+		t.Error(`maxSurge != 3 || maxUnavailable != 2`)
+	}
+
+	zero := intstr.FromInt(0)
+	maxSurge, maxUnavailable, err = rolloutLimits(inlocov1alpha1.ActionsRunnerReplicaSetStrategy{
+		MaxSurge:       &zero,
+		MaxUnavailable: &zero,
+	}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if maxSurge != 1 || maxUnavailable != 0 {
+		t.Error(`maxSurge != 1 || maxUnavailable != 0`)
+	}
+}
+
+func TestIsAvailable(t *testing.T) {
+	actionsRunner := inlocov1alpha1.ActionsRunner{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Status: inlocov1alpha1.ActionsRunnerStatus{
+			Conditions: []metav1.Condition{
+				{Type: string(inlocov1alpha1.ActionsRunnerConditionRegistered), Status: metav1.ConditionTrue, ObservedGeneration: 2},
+				{Type: string(inlocov1alpha1.ActionsRunnerConditionLabelsSynced), Status: metav1.ConditionTrue, ObservedGeneration: 2},
+			},
+		},
+	}
+
+	if !isAvailable(&actionsRunner) {
+		t.Error(`!isAvailable(&actionsRunner)`)
+	}
+
+	updated := actionsRunner.DeepCopy()
+	updated.SetGeneration(3)
+	if isAvailable(updated) {
+		t.Error(`isAvailable(updated)`)
+	}
+
+	unsynced := actionsRunner.DeepCopy()
+	unsynced.Status.Conditions[1].Status = metav1.ConditionFalse
+	if isAvailable(unsynced) {
+		t.Error(`isAvailable(unsynced)`)
+	}
+
+	draining := actionsRunner.DeepCopy()
+	metav1.SetMetaDataAnnotation(&draining.ObjectMeta, inlocov1alpha1.ActionsRunnerDrainAnnotation, "true")
+	if isAvailable(draining) {
+		t.Error(`isAvailable(draining)`)
+	}
+}
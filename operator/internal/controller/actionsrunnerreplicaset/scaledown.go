@@ -0,0 +1,75 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsrunnerreplicaset
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+type actionsRunnerState int
+
+// ordered by deletion priority
+const (
+	actionsRunnerStateIdle actionsRunnerState = iota
+	actionsRunnerStatePending
+	actionsRunnerStateRunning
+)
+
+// ActionsRunnerJobs are named after their ActionsRunners
+func actionsRunnerStateOf(actionsRunner *inlocov1alpha1.ActionsRunner, actionsRunnerJobs map[string]*inlocov1alpha1.ActionsRunnerJob) actionsRunnerState {
+	actionsRunnerJob, ok := actionsRunnerJobs[actionsRunner.GetName()]
+	if !ok {
+		return actionsRunnerStateIdle
+	}
+
+	if actionsRunnerJob.Status.PodPhase == corev1.PodRunning {
+		return actionsRunnerStateRunning
+	}
+
+	return actionsRunnerStatePending
+}
+
+// scaleDownCandidate returns the ActionsRunner to be deleted first, or nil if only running ones are left and force is unset
+func scaleDownCandidate(actionsRunners []inlocov1alpha1.ActionsRunner, actionsRunnerJobs map[string]*inlocov1alpha1.ActionsRunnerJob, force bool) *inlocov1alpha1.ActionsRunner {
+	candidates := make([]*inlocov1alpha1.ActionsRunner, 0, len(actionsRunners))
+	for i := range actionsRunners {
+		candidates = append(candidates, &actionsRunners[i])
+	}
+
+	// newest first within the same state
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iState, jState := actionsRunnerStateOf(candidates[i], actionsRunnerJobs), actionsRunnerStateOf(candidates[j], actionsRunnerJobs)
+		if iState != jState {
+			return iState < jState
+		}
+
+		iCreation, jCreation := candidates[i].GetCreationTimestamp(), candidates[j].GetCreationTimestamp()
+		return jCreation.Before(&iCreation)
+	})
+
+	for _, candidate := range candidates {
+		if force || actionsRunnerStateOf(candidate, actionsRunnerJobs) != actionsRunnerStateRunning {
+			return candidate
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package actionsrunnerreplicaset
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestScaleDownCandidate(t *testing.T) {
+	now := time.Now()
+
+	actionsRunner := func(name string, age time.Duration) inlocov1alpha1.ActionsRunner {
+		return inlocov1alpha1.ActionsRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+		}
+	}
+
+	actionsRunnerJob := func(podPhase corev1.PodPhase) *inlocov1alpha1.ActionsRunnerJob {
+		return &inlocov1alpha1.ActionsRunnerJob{
+			Status: inlocov1alpha1.ActionsRunnerJobStatus{
+				PodPhase: podPhase,
+			},
+		}
+	}
+
+	actionsRunners := []inlocov1alpha1.ActionsRunner{
+		actionsRunner("running", time.Minute),
+		actionsRunner("pending", 2*time.Minute),
+		actionsRunner("idle-old", 3*time.Hour),
+		actionsRunner("idle-new", 3*time.Minute),
+	}
+	actionsRunnerJobs := map[string]*inlocov1alpha1.ActionsRunnerJob{
+		"running": actionsRunnerJob(corev1.PodRunning),
+		"pending": actionsRunnerJob(corev1.PodPending),
+	}
+
+	if candidate := scaleDownCandidate(actionsRunners, actionsRunnerJobs, false); candidate == nil || candidate.GetName() != "idle-new" {
+		t.Error(`candidate == nil || candidate.GetName() != "idle-new"`)
+	}
+
+	if candidate := scaleDownCandidate(actionsRunners[:2], actionsRunnerJobs, false); candidate == nil || candidate.GetName() != "pending" {
+		t.Error(`candidate == nil || candidate.GetName() != "pending"`)
+	}
+
+	if candidate := scaleDownCandidate(actionsRunners[:1], actionsRunnerJobs, false); candidate != nil {
+		t.Error(`candidate != nil`)
+	}
+
+	if candidate := scaleDownCandidate(actionsRunners[:1], actionsRunnerJobs, true); candidate == nil || candidate.GetName() != "running" {
+		t.Error(`candidate == nil || candidate.GetName() != "running"`)
+	}
+}
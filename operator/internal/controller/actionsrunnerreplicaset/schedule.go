@@ -0,0 +1,144 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsrunnerreplicaset
+
+import (
+	"errors"
+	"time"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+const (
+	activeScheduleOverride = inlocov1alpha1.ActionsRunnerReplicaSetActiveScheduleOverride
+
+	scheduleTimeLayout = "15:04"
+)
+
+type scheduleWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+func scheduleDays(schedule inlocov1alpha1.ActionsRunnerReplicaSetSchedule) map[time.Weekday]struct{} {
+	days := make(map[time.Weekday]struct{})
+	for _, day := range schedule.Days {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if string(day) == weekday.String() {
+				days[weekday] = struct{}{}
+			}
+		}
+	}
+
+	return days
+}
+
+// scheduleWindowOn returns the window of schedule starting on the day of date, if any
+func scheduleWindowOn(schedule inlocov1alpha1.ActionsRunnerReplicaSetSchedule, date time.Time) (*scheduleWindow, error) {
+	if days := scheduleDays(schedule); len(days) > 0 {
+		if _, ok := days[date.Weekday()]; !ok {
+			return nil, nil
+		}
+	}
+
+	start, err := time.Parse(scheduleTimeLayout, schedule.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := time.Parse(scheduleTimeLayout, schedule.End)
+	if err != nil {
+		return nil, err
+	}
+
+	window := scheduleWindow{
+		start: time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), 0, 0, date.Location()),
+		end:   time.Date(date.Year(), date.Month(), date.Day(), end.Hour(), end.Minute(), 0, 0, date.Location()),
+	}
+	if !window.end.After(window.start) {
+		window.end = time.Date(date.Year(), date.Month(), date.Day()+1, end.Hour(), end.Minute(), 0, 0, date.Location())
+	}
+
+	return &window, nil
+}
+
+// resolveSchedule returns whether schedule is active at now and when that changes
+func resolveSchedule(schedule inlocov1alpha1.ActionsRunnerReplicaSetSchedule, now time.Time) (bool, time.Time, error) {
+	location, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	local := now.In(location)
+
+	var next time.Time
+	for offset := -1; offset <= 7; offset++ {
+		window, err := scheduleWindowOn(schedule, time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, location))
+		if err != nil {
+			return false, time.Time{}, err
+		}
+
+		if window == nil {
+			continue
+		}
+
+		if !local.Before(window.start) && local.Before(window.end) {
+			return true, window.end, nil
+		}
+
+		if window.start.After(local) && (next.IsZero() || window.start.Before(next)) {
+			next = window.start
+		}
+	}
+
+	return false, next, nil
+}
+
+// desiredReplicas resolves the replicas of actionsRunnerReplicaSet at now, which source they come from and when they
+// may change next, a zero time meaning never
+func desiredReplicas(actionsRunnerReplicaSet *inlocov1alpha1.ActionsRunnerReplicaSet, now time.Time) (uint, string, time.Time, error) {
+	if actionsRunnerReplicaSet == nil {
+		return 0, "", time.Time{}, errors.New("actionsRunnerReplicaSet == nil")
+	}
+	spec := actionsRunnerReplicaSet.Spec
+
+	if override := spec.Override; override != nil && now.Before(override.Until.Time) {
+		return override.Replicas, activeScheduleOverride, override.Until.Time, nil
+	}
+
+	var next time.Time
+	for _, schedule := range spec.Schedules {
+		active, boundary, err := resolveSchedule(schedule, now)
+		if err != nil {
+			return 0, "", time.Time{}, err
+		}
+
+		if active {
+			// windows of schedules listed before this one may start earlier than it ends
+			if next.IsZero() || boundary.Before(next) {
+				next = boundary
+			}
+
+			return schedule.Replicas, schedule.Name, next, nil
+		}
+
+		if !boundary.IsZero() && (next.IsZero() || boundary.Before(next)) {
+			next = boundary
+		}
+	}
+
+	return spec.Replicas, "", next, nil
+}
@@ -0,0 +1,92 @@
+package actionsrunnerreplicaset
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestDesiredReplicas(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actionsRunnerReplicaSet := inlocov1alpha1.ActionsRunnerReplicaSet{
+		Spec: inlocov1alpha1.ActionsRunnerReplicaSetSpec{
+			Replicas: 2,
+			Schedules: []inlocov1alpha1.ActionsRunnerReplicaSetSchedule{
+				{
+					Name:     "office-hours",
+					Days:     []inlocov1alpha1.Weekday{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
+					Start:    "08:00",
+					End:      "20:00",
+					TimeZone: "America/Sao_Paulo",
+					Replicas: 20,
+				},
+				{
+					Name:     "nightly",
+					Start:    "22:00",
+					End:      "02:00",
+					TimeZone: "America/Sao_Paulo",
+					Replicas: 5,
+				},
+			},
+		},
+	}
+
+	// Friday
+	replicas, active, next, err := desiredReplicas(&actionsRunnerReplicaSet, time.Date(2021, time.October, 1, 9, 0, 0, 0, saoPaulo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replicas != 20 || active != "office-hours" {
+		t.Error(`replicas != 20 || active != "office-hours"`)
+	}
+
+	if !next.Equal(time.Date(2021, time.October, 1, 20, 0, 0, 0, saoPaulo)) {
+		t.Error(`!next.Equal(time.Date(2021, time.October, 1, 20, 0, 0, 0, saoPaulo))`)
+	}
+
+	// Saturday
+	replicas, active, next, err = desiredReplicas(&actionsRunnerReplicaSet, time.Date(2021, time.October, 2, 9, 0, 0, 0, saoPaulo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replicas != 2 || active != "" {
+		t.Error(`replicas != 2 || active != ""`)
+	}
+
+	if !next.Equal(time.Date(2021, time.October, 2, 22, 0, 0, 0, saoPaulo)) {
+		t.Error(`!next.Equal(time.Date(2021, time.October, 2, 22, 0, 0, 0, saoPaulo))`)
+	}
+
+	// Sunday, within the window started on Saturday
+	replicas, active, _, err = desiredReplicas(&actionsRunnerReplicaSet, time.Date(2021, time.October, 3, 1, 0, 0, 0, saoPaulo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replicas != 5 || active != "nightly" {
+		t.Error(`replicas != 5 || active != "nightly"`)
+	}
+
+	actionsRunnerReplicaSet.Spec.Override = &inlocov1alpha1.ActionsRunnerReplicaSetOverride{
+		Replicas: 0,
+		Until:    metav1.NewTime(time.Date(2021, time.October, 3, 12, 0, 0, 0, saoPaulo)),
+	}
+
+	replicas, active, _, err = desiredReplicas(&actionsRunnerReplicaSet, time.Date(2021, time.October, 3, 1, 0, 0, 0, saoPaulo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replicas != 0 || active != activeScheduleOverride {
+		t.Error(`replicas != 0 || active != activeScheduleOverride`)
+	}
+}
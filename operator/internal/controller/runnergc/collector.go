@@ -0,0 +1,462 @@
+/*
+Copyright 2020 In Loco Tecnologia da Informação S.A.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runnergc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v32/github"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/dot"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/facades"
+	"github.com/inloco/kube-actions/operator/internal/controller/actionsrunner/wire"
+	"github.com/inloco/kube-actions/operator/metrics"
+)
+
+const (
+	// Namespaces annotated with "false" keep the orphaned runners named after them
+	optOutAnnotation = "kube-actions.inloco.com.br/runner-gc"
+
+	// Namespaces annotated with a comma separated list of ActionsRunner names keep the orphaned runners of those only,
+	// the annotation goes on the Namespace because orphans have no ActionsRunner left to carry it
+	keepAnnotation = "kube-actions.inloco.com.br/runner-gc-keep"
+
+	agentNamePrefix = "KA "
+)
+
+// target is where runners are registered, e.g. a repository or an organization of a GitHub instance
+type target struct {
+	apiEndpoint string
+	path        string
+}
+
+type orphanKey struct {
+	target   target
+	runnerId int64
+}
+
+// registeredAgents are the agents known to belong to existing ActionsRunners
+type registeredAgents struct {
+	names map[string]struct{}
+	ids   map[int64]struct{}
+}
+
+func newRegisteredAgents() *registeredAgents {
+	return &registeredAgents{
+		names: make(map[string]struct{}),
+		ids:   make(map[int64]struct{}),
+	}
+}
+
+func (r *registeredAgents) has(runner *github.Runner) bool {
+	if _, ok := r.names[runner.GetName()]; ok {
+		return true
+	}
+
+	_, ok := r.ids[runner.GetID()]
+	return ok
+}
+
+// Collector periodically removes offline runners named like operator agents whose ActionsRunner is gone
+type Collector struct {
+	client.Client
+	Log logr.Logger
+
+	Interval    time.Duration
+	GracePeriod time.Duration
+	DryRun      bool
+	Targets     []inlocov1alpha1.ActionsRunnerRepository // swept even when no ActionsRunner points to them, e.g. after the operator restarted
+
+	firstSeen      map[orphanKey]time.Time
+	firstSeenMutex sync.Mutex
+
+	// targets of deleted ActionsRunners, swept until no orphan is left in them
+	knownTargets map[target]inlocov1alpha1.ActionsRunnerRepository
+}
+
+var _ manager.LeaderElectionRunnable = (*Collector)(nil)
+
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunners,verbs=get;list;watch
+// +kubebuilder:rbac:groups=inloco.com.br,resources=actionsrunnerreplicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+func (c *Collector) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(c)
+}
+
+func (c *Collector) NeedLeaderElection() bool {
+	return true
+}
+
+func (c *Collector) Start(ctx context.Context) error {
+	logger := c.Log.WithName("runnergc").WithValues("dryRun", c.DryRun)
+
+	if c.Interval <= 0 {
+		logger.Info("Runner garbage collector disabled")
+		return nil
+	}
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.sweep(ctx, logger); err != nil {
+			logger.Error(err, "Failed to sweep runners")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Collector) sweep(ctx context.Context, logger logr.Logger) error {
+	var actionsRunners inlocov1alpha1.ActionsRunnerList
+	if err := c.List(ctx, &actionsRunners); err != nil {
+		return err
+	}
+
+	var actionsRunnerReplicaSets inlocov1alpha1.ActionsRunnerReplicaSetList
+	if err := c.List(ctx, &actionsRunnerReplicaSets); err != nil {
+		return err
+	}
+
+	if c.knownTargets == nil {
+		c.knownTargets = make(map[target]inlocov1alpha1.ActionsRunnerRepository)
+	}
+
+	repositories := make(map[target]inlocov1alpha1.ActionsRunnerRepository)
+	for t, repository := range c.knownTargets {
+		repositories[t] = repository
+	}
+	for _, repository := range c.Targets {
+		repositories[targetOf(repository)] = repository
+	}
+	for _, actionsRunnerReplicaSet := range actionsRunnerReplicaSets.Items {
+		repository := actionsRunnerReplicaSet.Spec.Template.Repository
+		repositories[targetOf(repository)] = repository
+	}
+
+	registered := make(map[target]*registeredAgents)
+	for i := range actionsRunners.Items {
+		actionsRunner := &actionsRunners.Items[i]
+
+		repository := actionsRunner.Spec.Repository
+		t := targetOf(repository)
+		repositories[t] = repository
+		c.knownTargets[t] = repository
+
+		agents, ok := registered[t]
+		if !ok {
+			agents = newRegisteredAgents()
+			registered[t] = agents
+		}
+		agents.names[wire.AgentName(actionsRunner)] = struct{}{}
+
+		// the agent is still known by its default name, so one unreadable ConfigMap doesn't stop the whole sweep
+		runner, err := c.registeredRunner(ctx, client.ObjectKeyFromObject(actionsRunner))
+		if err != nil {
+			logger.Error(err, "Failed to get registered runner", "actionsRunner", client.ObjectKeyFromObject(actionsRunner))
+			continue
+		}
+		if runner != nil {
+			agents.names[runner.AgentName] = struct{}{}
+			agents.ids[int64(runner.AgentId)] = struct{}{}
+		}
+	}
+
+	seen := make(map[orphanKey]struct{})
+	optOuts := make(map[string]*optOut)
+	for t, repository := range repositories {
+		targetLogger := logger.WithValues("target", t.path)
+
+		var gh facades.GitHub
+		if err := gh.Init(ctx, repository); err != nil {
+			targetLogger.Error(err, "Failed to initialize GitHub facade")
+			continue
+		}
+
+		runners, err := gh.ListRunners(ctx)
+		if err != nil {
+			targetLogger.Error(err, "Failed to list runners")
+			continue
+		}
+
+		orphaned := orphans(runners, registered[t])
+
+		var orphanCount int
+		for _, runner := range orphaned {
+			runnerLogger := targetLogger.WithValues("runner", runner.GetName(), "id", runner.GetID())
+
+			namespace := namespaceOf(runner.GetName())
+			if _, ok := optOuts[namespace]; !ok {
+				namespaceOptOut, err := c.optOutOf(ctx, namespace)
+				if err != nil {
+					return err
+				}
+				optOuts[namespace] = namespaceOptOut
+			}
+			if optOuts[namespace].keeps(runner) {
+				continue
+			}
+
+			orphanCount++
+
+			key := orphanKey{
+				target:   t,
+				runnerId: runner.GetID(),
+			}
+			seen[key] = struct{}{}
+
+			if !c.due(key, time.Now()) {
+				runnerLogger.Info("Orphaned runner found, waiting grace period")
+				continue
+			}
+
+			if c.DryRun {
+				runnerLogger.Info("Orphaned runner would be removed")
+				metrics.IncRunnerGCRemovedCounter(t.path, c.DryRun)
+				continue
+			}
+
+			runnerLogger.Info("Orphaned runner needs to be removed")
+			if err := gh.RemoveRunner(ctx, runner.GetID()); err != nil {
+				runnerLogger.Error(err, "Failed to remove orphaned runner")
+				metrics.IncRunnerGCFailedCounter(t.path)
+				continue
+			}
+			metrics.IncRunnerGCRemovedCounter(t.path, c.DryRun)
+		}
+
+		metrics.SetRunnerGCOrphansGauge(t.path, orphanCount)
+
+		// orphans kept by an opt-out don't count, they would hold the target forever otherwise
+		if orphanCount == 0 && registered[t] == nil {
+			delete(c.knownTargets, t)
+		}
+	}
+
+	c.forgetExcept(seen)
+
+	return nil
+}
+
+// registeredRunner reads the .runner file from the ConfigMap of an ActionsRunner, if any
+func (c *Collector) registeredRunner(ctx context.Context, key client.ObjectKey) (*dot.Runner, error) {
+	var configMap corev1.ConfigMap
+	switch err := c.Get(ctx, key, &configMap); {
+	case apierrors.IsNotFound(err):
+		return nil, nil
+
+	case err != nil:
+		return nil, err
+	}
+
+	data, ok := configMap.BinaryData[".runner"]
+	if !ok {
+		return nil, nil
+	}
+
+	var runner dot.Runner
+	if err := json.Unmarshal(data, &runner); err != nil {
+		return nil, nil
+	}
+
+	return &runner, nil
+}
+
+// optOut holds what the annotations of a Namespace keep from the garbage collector
+type optOut struct {
+	namespace  bool
+	agentNames map[string]struct{}
+}
+
+func (o *optOut) keeps(runner *github.Runner) bool {
+	if o == nil {
+		return false
+	}
+
+	if o.namespace {
+		return true
+	}
+
+	_, ok := o.agentNames[runner.GetName()]
+	return ok
+}
+
+func (c *Collector) optOutOf(ctx context.Context, namespace string) (*optOut, error) {
+	if namespace == "" {
+		return nil, nil
+	}
+
+	var ns corev1.Namespace
+	switch err := c.Get(ctx, client.ObjectKey{Name: namespace}, &ns); {
+	case apierrors.IsNotFound(err):
+		return nil, nil
+
+	case err != nil:
+		return nil, err
+	}
+
+	return newOptOut(&ns), nil
+}
+
+func newOptOut(ns *corev1.Namespace) *optOut {
+	annotations := ns.GetAnnotations()
+
+	o := optOut{
+		namespace:  annotations[optOutAnnotation] == "false",
+		agentNames: make(map[string]struct{}),
+	}
+
+	for _, name := range strings.Split(annotations[keepAnnotation], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		var actionsRunner inlocov1alpha1.ActionsRunner
+		actionsRunner.SetNamespace(ns.GetName())
+		actionsRunner.SetName(name)
+		o.agentNames[wire.AgentName(&actionsRunner)] = struct{}{}
+	}
+
+	return &o
+}
+
+// due tells whether an orphan has been seen for longer than the grace period, the first sighting starts the clock
+func (c *Collector) due(key orphanKey, now time.Time) bool {
+	c.firstSeenMutex.Lock()
+	defer c.firstSeenMutex.Unlock()
+
+	if c.firstSeen == nil {
+		c.firstSeen = make(map[orphanKey]time.Time)
+	}
+
+	firstSeen, ok := c.firstSeen[key]
+	if !ok {
+		c.firstSeen[key] = now
+		return c.GracePeriod <= 0
+	}
+
+	return now.Sub(firstSeen) >= c.GracePeriod
+}
+
+func (c *Collector) forgetExcept(seen map[orphanKey]struct{}) {
+	c.firstSeenMutex.Lock()
+	defer c.firstSeenMutex.Unlock()
+
+	for key := range c.firstSeen {
+		if _, ok := seen[key]; !ok {
+			delete(c.firstSeen, key)
+		}
+	}
+}
+
+// orphans filters offline runners named like operator agents that no ActionsRunner accounts for, online ones may
+// belong to another cluster sharing the same target
+func orphans(runners []*github.Runner, registered *registeredAgents) []*github.Runner {
+	var orphaned []*github.Runner
+	for _, runner := range runners {
+		if !strings.HasPrefix(runner.GetName(), agentNamePrefix) {
+			continue
+		}
+
+		if runner.GetStatus() != facades.RunnerStatusOffline {
+			continue
+		}
+
+		if registered != nil && registered.has(runner) {
+			continue
+		}
+
+		orphaned = append(orphaned, runner)
+	}
+
+	return orphaned
+}
+
+func targetOf(repository inlocov1alpha1.ActionsRunnerRepository) target {
+	return target{
+		apiEndpoint: repository.APIEndpoint,
+		path:        repository.String(),
+	}
+}
+
+// ParseTargets reads a comma separated list of runner targets like "owner/name", "owner" or "enterprises/owner"
+func ParseTargets(value string) ([]inlocov1alpha1.ActionsRunnerRepository, error) {
+	var repositories []inlocov1alpha1.ActionsRunnerRepository
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		parts := strings.Split(path, "/")
+		switch {
+		case len(parts) == 1:
+			repositories = append(repositories, inlocov1alpha1.ActionsRunnerRepository{
+				Scope: inlocov1alpha1.ActionsRunnerScopeOrganization,
+				Owner: parts[0],
+			})
+
+		case len(parts) == 2 && parts[0] == "enterprises":
+			repositories = append(repositories, inlocov1alpha1.ActionsRunnerRepository{
+				Scope: inlocov1alpha1.ActionsRunnerScopeEnterprise,
+				Owner: parts[1],
+			})
+
+		case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+			repositories = append(repositories, inlocov1alpha1.ActionsRunnerRepository{
+				Scope: inlocov1alpha1.ActionsRunnerScopeRepository,
+				Owner: parts[0],
+				Name:  parts[1],
+			})
+
+		default:
+			return nil, errors.New("invalid runner target: " + path)
+		}
+	}
+
+	return repositories, nil
+}
+
+// namespaceOf parses agent names like "KA namespace name"
+func namespaceOf(agentName string) string {
+	parts := strings.SplitN(strings.TrimPrefix(agentName, agentNamePrefix), " ", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+
+	return parts[0]
+}
@@ -0,0 +1,128 @@
+package runnergc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	inlocov1alpha1 "github.com/inloco/kube-actions/operator/api/v1alpha1"
+)
+
+func TestOrphans(t *testing.T) {
+	registered := newRegisteredAgents()
+	registered.names["KA default runner-a"] = struct{}{}
+	registered.ids[3] = struct{}{}
+
+	runners := []*github.Runner{
+		{ID: github.Int64(1), Name: github.String("KA default runner-a"), Status: github.String("offline")},
+		{ID: github.Int64(2), Name: github.String("KA default runner-b"), Status: github.String("offline")},
+		{ID: github.Int64(3), Name: github.String("KA default runner-c"), Status: github.String("offline")},
+		{ID: github.Int64(4), Name: github.String("KA default runner-d"), Status: github.String("online")},
+		{ID: github.Int64(5), Name: github.String("someone-elses-runner"), Status: github.String("offline")},
+	}
+
+	orphaned := orphans(runners, registered)
+	if len(orphaned) != 1 {
+		t.Fatal(`len(orphaned) != 1`)
+	}
+
+	if orphaned[0].GetID() != 2 {
+		t.Error(`orphaned[0].GetID() != 2`)
+	}
+}
+
+func TestNamespaceOf(t *testing.T) {
+	if namespaceOf("KA default runner-a") != "default" {
+		t.Error(`namespaceOf("KA default runner-a") != "default"`)
+	}
+
+	if namespaceOf("KA default") != "" {
+		t.Error(`namespaceOf("KA default") != ""`)
+	}
+}
+
+func TestDue(t *testing.T) {
+	c := Collector{
+		GracePeriod: time.Hour,
+	}
+
+	key := orphanKey{
+		target:   target{path: "owner/name"},
+		runnerId: 1,
+	}
+	now := time.Now()
+
+	if c.due(key, now) {
+		t.Error(`c.due(key, now)`)
+	}
+
+	if c.due(key, now.Add(30*time.Minute)) {
+		t.Error(`c.due(key, now.Add(30*time.Minute))`)
+	}
+
+	if !c.due(key, now.Add(time.Hour)) {
+		t.Error(`!c.due(key, now.Add(time.Hour))`)
+	}
+
+	c.forgetExcept(nil)
+	if c.due(key, now.Add(2*time.Hour)) {
+		t.Error(`c.due(key, now.Add(2*time.Hour))`)
+	}
+}
+
+func TestOptOutKeeps(t *testing.T) {
+	ns := corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "default",
+			Annotations: map[string]string{keepAnnotation: "runner-a, runner-b"},
+		},
+	}
+
+	o := newOptOut(&ns)
+	if !o.keeps(&github.Runner{Name: github.String("KA default runner-a")}) {
+		t.Error(`!o.keeps(&github.Runner{Name: github.String("KA default runner-a")})`)
+	}
+
+	if o.keeps(&github.Runner{Name: github.String("KA default runner-c")}) {
+		t.Error(`o.keeps(&github.Runner{Name: github.String("KA default runner-c")})`)
+	}
+
+	ns.Annotations = map[string]string{optOutAnnotation: "false"}
+	if !newOptOut(&ns).keeps(&github.Runner{Name: github.String("KA default runner-c")}) {
+		t.Error(`!newOptOut(&ns).keeps(&github.Runner{Name: github.String("KA default runner-c")})`)
+	}
+
+	var none *optOut
+	if none.keeps(&github.Runner{Name: github.String("KA default runner-a")}) {
+		t.Error(`none.keeps(&github.Runner{Name: github.String("KA default runner-a")})`)
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	repositories, err := ParseTargets("inloco/kube-actions, inloco,enterprises/incognia,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"inloco/kube-actions", "inloco", "enterprises/incognia"}
+	if len(repositories) != len(expected) {
+		t.Fatal(`len(repositories) != len(expected)`)
+	}
+
+	for i, repository := range repositories {
+		if repository.String() != expected[i] {
+			t.Errorf(`repositories[%d].String() != %q`, i, expected[i])
+		}
+	}
+
+	if repositories[1].GetScope() != inlocov1alpha1.ActionsRunnerScopeOrganization {
+		t.Error(`repositories[1].GetScope() != inlocov1alpha1.ActionsRunnerScopeOrganization`)
+	}
+
+	if _, err := ParseTargets("inloco/kube-actions/operator"); err == nil {
+		t.Error(`err == nil`)
+	}
+}
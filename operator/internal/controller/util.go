@@ -36,7 +36,7 @@ func HasActionsRunnerRequestedStorage(actionsRunner *inlocov1alpha1.ActionsRunne
 		return false
 	}
 
-	res, ok := actionsRunner.Spec.Resources["runner"]
+	res, ok := actionsRunner.Spec.Resources[inlocov1alpha1.ActionsRunnerResourcesRunner]
 	if !ok {
 		return false
 	}
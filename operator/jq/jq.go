@@ -0,0 +1,221 @@
+package jq
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// ListsVariable holds the named lists read by in_list, a map of names to arrays of strings
+const ListsVariable = "$lists"
+
+// library is written in jq when functions need the variables of the rule
+const library = `
+def in_list($name): . as $item | any(($lists[$name] // [])[]; . == $item);
+def is_fork: (.github.event.pull_request // null) as $pr |
+	$pr != null and (($pr.head.repo.fork // false) or $pr.head.repo.full_name != $pr.base.repo.full_name);
+`
+
+// Parse parses query with the kube-actions function library defined before its own functions:
+//
+//	glob($pattern)       whether the input matches $pattern, * and ? do not match / while ** does
+//	semver_ge($version)  whether the input is a semantic version, with or without a v, greater or equal to $version
+//	in_cidr($cidr)       whether the input is an IP address inside $cidr
+//	in_list($name)       whether the input is in the list $name of $lists, unknown lists are empty
+//	is_fork              whether the job context is of a pull request from another repository
+//
+// Functions that inspect their input are false for anything but strings, so rules checked against a null context
+// do not fail.
+func Parse(query string) (*gojq.Query, error) {
+	q, err := gojq.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// parsed again for every query so queries never share definitions
+	lib, err := gojq.Parse(library + ".")
+	if err != nil {
+		return nil, err
+	}
+
+	q.FuncDefs = append(lib.FuncDefs, q.FuncDefs...)
+	return q, nil
+}
+
+// CompilerOptions registers the native functions of the library and declares variables followed by $lists, values
+// are given to Run in the same order
+func CompilerOptions(variables ...string) []gojq.CompilerOption {
+	return []gojq.CompilerOption{
+		gojq.WithVariables(append(append([]string{}, variables...), ListsVariable)),
+		gojq.WithFunction("glob", 1, 1, glob),
+		gojq.WithFunction("semver_ge", 1, 1, semverGE),
+		gojq.WithFunction("in_cidr", 1, 1, inCIDR),
+	}
+}
+
+// Lists parses the data of a ConfigMap, each key is a list with an item per line, blank lines and # comments are
+// skipped
+func Lists(data map[string]string) map[string]interface{} {
+	lists := make(map[string]interface{}, len(data))
+	for name, value := range data {
+		items := []interface{}{}
+		for _, line := range strings.Split(value, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				items = append(items, line)
+			}
+		}
+
+		lists[name] = items
+	}
+
+	return lists
+}
+
+func glob(v interface{}, args []interface{}) interface{} {
+	pattern, ok := args[0].(string)
+	if !ok {
+		return fmt.Errorf("glob: pattern is not a string: %v", args[0])
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return fmt.Errorf("glob: invalid pattern %q: %w", pattern, err)
+	}
+
+	return re.MatchString(s)
+}
+
+func semverGE(v interface{}, args []interface{}) interface{} {
+	minimum, ok := args[0].(string)
+	if !ok {
+		return fmt.Errorf("semver_ge: version is not a string: %v", args[0])
+	}
+
+	m, ok := parseSemver(minimum)
+	if !ok {
+		return fmt.Errorf("semver_ge: invalid version %q", minimum)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+
+	// refs like branches or commit SHAs are not versions
+	version, ok := parseSemver(s)
+	if !ok {
+		return false
+	}
+
+	return compareSemver(version, m) >= 0
+}
+
+type semver struct {
+	core       [3]uint64
+	prerelease string
+}
+
+// parseSemver accepts partial versions like v4 or v4.1, missing numbers are 0
+func parseSemver(s string) (semver, bool) {
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var version semver
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		s, version.prerelease = s[:i], s[i+1:]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return semver{}, false
+	}
+
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return semver{}, false
+		}
+
+		version.core[i] = n
+	}
+
+	return version, true
+}
+
+// compareSemver ranks prereleases before their release, prereleases themselves are compared as strings
+func compareSemver(a, b semver) int {
+	for i := range a.core {
+		switch {
+		case a.core[i] < b.core[i]:
+			return -1
+
+		case a.core[i] > b.core[i]:
+			return 1
+		}
+	}
+
+	switch {
+	case a.prerelease == b.prerelease:
+		return 0
+
+	case a.prerelease == "":
+		return 1
+
+	case b.prerelease == "":
+		return -1
+
+	default:
+		return strings.Compare(a.prerelease, b.prerelease)
+	}
+}
+
+func inCIDR(v interface{}, args []interface{}) interface{} {
+	cidr, ok := args[0].(string)
+	if !ok {
+		return fmt.Errorf("in_cidr: cidr is not a string: %v", args[0])
+	}
+
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("in_cidr: %w", err)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+
+	ip := net.ParseIP(s)
+	return ip != nil && ipNet.Contains(ip)
+}
@@ -0,0 +1,123 @@
+package jq
+
+import (
+	"testing"
+
+	"github.com/itchyny/gojq"
+)
+
+func run(t *testing.T, query string, input interface{}, lists map[string]interface{}) interface{} {
+	q, err := Parse(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := gojq.Compile(q, CompilerOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, ok := c.Run(input, lists).Next()
+	if !ok {
+		t.Fatal(`!ok`)
+	}
+
+	return v
+}
+
+func TestGlob(t *testing.T) {
+	if run(t, `glob("refs/heads/*")`, "refs/heads/main", nil) != true {
+		t.Error(`run(t, "glob(\"refs/heads/*\")", "refs/heads/main", nil) != true`)
+	}
+
+	if run(t, `glob("refs/heads/*")`, "refs/heads/feature/x", nil) != false {
+		t.Error(`run(t, "glob(\"refs/heads/*\")", "refs/heads/feature/x", nil) != false`)
+	}
+
+	if run(t, `glob("refs/heads/**")`, "refs/heads/feature/x", nil) != true {
+		t.Error(`run(t, "glob(\"refs/heads/**\")", "refs/heads/feature/x", nil) != true`)
+	}
+
+	if run(t, `glob("refs/tags/v?.*")`, "refs/tags/v1.2", nil) != true {
+		t.Error(`run(t, "glob(\"refs/tags/v?.*\")", "refs/tags/v1.2", nil) != true`)
+	}
+
+	if run(t, `glob("*")`, nil, nil) != false {
+		t.Error(`run(t, "glob(\"*\")", nil, nil) != false`)
+	}
+}
+
+func TestSemverGE(t *testing.T) {
+	cases := map[string]bool{
+		"v4":          true,
+		"v4.1.0":      true,
+		"3.9.9":       false,
+		"v4.0.0-rc.1": false,
+		"main":        false,
+	}
+
+	for version, expected := range cases {
+		if run(t, `semver_ge("v4")`, version, nil) != expected {
+			t.Errorf(`run(t, "semver_ge(\"v4\")", %q, nil) != %t`, version, expected)
+		}
+	}
+
+	if _, ok := run(t, `semver_ge("latest")`, "v4", nil).(error); !ok {
+		t.Error(`_, ok := run(t, "semver_ge(\"latest\")", "v4", nil).(error); !ok`)
+	}
+}
+
+func TestInCIDR(t *testing.T) {
+	if run(t, `in_cidr("10.0.0.0/8")`, "10.1.2.3", nil) != true {
+		t.Error(`run(t, "in_cidr(\"10.0.0.0/8\")", "10.1.2.3", nil) != true`)
+	}
+
+	if run(t, `in_cidr("10.0.0.0/8")`, "192.168.0.1", nil) != false {
+		t.Error(`run(t, "in_cidr(\"10.0.0.0/8\")", "192.168.0.1", nil) != false`)
+	}
+}
+
+func TestInList(t *testing.T) {
+	lists := Lists(map[string]string{
+		"teams": "# maintainers\nplatform\n\n  security  \n",
+	})
+
+	if run(t, `in_list("teams")`, "security", lists) != true {
+		t.Error(`run(t, "in_list(\"teams\")", "security", lists) != true`)
+	}
+
+	if run(t, `in_list("teams")`, "# maintainers", lists) != false {
+		t.Error(`run(t, "in_list(\"teams\")", "# maintainers", lists) != false`)
+	}
+
+	if run(t, `in_list("unknown")`, "security", lists) != false {
+		t.Error(`run(t, "in_list(\"unknown\")", "security", lists) != false`)
+	}
+}
+
+func TestIsFork(t *testing.T) {
+	pr := func(head, base string) map[string]interface{} {
+		return map[string]interface{}{
+			"github": map[string]interface{}{
+				"event": map[string]interface{}{
+					"pull_request": map[string]interface{}{
+						"head": map[string]interface{}{"repo": map[string]interface{}{"full_name": head}},
+						"base": map[string]interface{}{"repo": map[string]interface{}{"full_name": base}},
+					},
+				},
+			},
+		}
+	}
+
+	if run(t, `is_fork`, pr("someone/kube-actions", "inloco/kube-actions"), nil) != true {
+		t.Error(`run(t, "is_fork", pr("someone/kube-actions", "inloco/kube-actions"), nil) != true`)
+	}
+
+	if run(t, `is_fork`, pr("inloco/kube-actions", "inloco/kube-actions"), nil) != false {
+		t.Error(`run(t, "is_fork", pr("inloco/kube-actions", "inloco/kube-actions"), nil) != false`)
+	}
+
+	if run(t, `is_fork`, nil, nil) != false {
+		t.Error(`run(t, "is_fork", nil, nil) != false`)
+	}
+}
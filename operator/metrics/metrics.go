@@ -79,7 +79,7 @@ var (
 			Subsystem: "actions",
 			Name:      "job_alive",
 		},
-		[]string{"repository", "runner_job"},
+		[]string{"repository", "runner_job", "scope"},
 	)
 
 	githubActionsJobStartedTimestampGauge = prometheus.NewGaugeVec(
@@ -88,7 +88,7 @@ var (
 			Subsystem: "actions",
 			Name:      "job_started_timestamp_seconds",
 		},
-		[]string{"repository", "runner_job"},
+		[]string{"repository", "runner_job", "scope"},
 	)
 
 	githubActionsJobFinishedTimestampGauge = prometheus.NewGaugeVec(
@@ -97,7 +97,77 @@ var (
 			Subsystem: "actions",
 			Name:      "job_finished_timestamp_seconds",
 		},
-		[]string{"repository", "runner_job"},
+		[]string{"repository", "runner_job", "scope"},
+	)
+
+	autoscalerJobsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kubeactions",
+			Subsystem: "autoscaler",
+			Name:      "jobs",
+			Help:      "Workflow jobs seen by the autoscaler.",
+		},
+		[]string{"namespace", "autoscaler", "status"},
+	)
+
+	autoscalerReplicasGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kubeactions",
+			Subsystem: "autoscaler",
+			Name:      "replicas",
+			Help:      "Replicas recommended, desired and current for the autoscaler target.",
+		},
+		[]string{"namespace", "autoscaler", "kind"},
+	)
+
+	autoscalerScaleCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kubeactions",
+			Subsystem: "autoscaler",
+			Name:      "scale_events",
+			Help:      "Number of times the autoscaler changed the target replicas.",
+		},
+		[]string{"namespace", "autoscaler", "direction"},
+	)
+
+	runnerGCOrphansGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kubeactions",
+			Subsystem: "runner_gc",
+			Name:      "orphans",
+			Help:      "Offline runners registered by the operator without an ActionsRunner.",
+		},
+		[]string{"target"},
+	)
+
+	runnerGCRemovedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kubeactions",
+			Subsystem: "runner_gc",
+			Name:      "removed",
+			Help:      "Number of orphaned runners removed, or that would be removed on dry run.",
+		},
+		[]string{"target", "dry_run"},
+	)
+
+	runnerGCFailedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kubeactions",
+			Subsystem: "runner_gc",
+			Name:      "failed",
+			Help:      "Number of orphaned runners that could not be removed.",
+		},
+		[]string{"target"},
+	)
+
+	policyViolationsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kubeactions",
+			Subsystem: "policy",
+			Name:      "violations",
+			Help:      "Number of jobs that violated a policy rule, by enforcement action.",
+		},
+		[]string{"namespace", "runner", "policy", "rule", "action"},
 	)
 )
 
@@ -111,6 +181,13 @@ func init() {
 		githubActionsJobAliveGauge,
 		githubActionsJobStartedTimestampGauge,
 		githubActionsJobFinishedTimestampGauge,
+		autoscalerJobsGauge,
+		autoscalerReplicasGauge,
+		autoscalerScaleCounter,
+		runnerGCOrphansGauge,
+		runnerGCRemovedCounter,
+		runnerGCFailedCounter,
+		policyViolationsCounter,
 	)
 }
 
@@ -150,12 +227,51 @@ func IncGitHubActionsEventCounter(repository, runner, event string) {
 	githubActionsEventCounter.WithLabelValues(repository, runner, event).Inc()
 }
 
-func SetGitHubActionsJobAlive(repository, job string) {
-	githubActionsJobAliveGauge.WithLabelValues(repository, job).Set(1)
-	githubActionsJobStartedTimestampGauge.WithLabelValues(repository, job).SetToCurrentTime()
+// SetGitHubActionsJobAlive takes the runner target as repository, e.g. owner/name, owner or enterprises/owner
+func SetGitHubActionsJobAlive(repository, job, scope string) {
+	githubActionsJobAliveGauge.WithLabelValues(repository, job, scope).Set(1)
+	githubActionsJobStartedTimestampGauge.WithLabelValues(repository, job, scope).SetToCurrentTime()
+}
+
+func SetGitHubActionsJobDone(repository, job, scope string) {
+	githubActionsJobAliveGauge.WithLabelValues(repository, job, scope).Set(0)
+	githubActionsJobFinishedTimestampGauge.WithLabelValues(repository, job, scope).SetToCurrentTime()
+}
+
+func SetAutoscalerJobs(namespace, autoscaler string, queued, inProgress uint) {
+	autoscalerJobsGauge.WithLabelValues(namespace, autoscaler, "queued").Set(float64(queued))
+	autoscalerJobsGauge.WithLabelValues(namespace, autoscaler, "in_progress").Set(float64(inProgress))
+}
+
+func SetAutoscalerReplicas(namespace, autoscaler string, recommended, desired, current uint) {
+	autoscalerReplicasGauge.WithLabelValues(namespace, autoscaler, "recommended").Set(float64(recommended))
+	autoscalerReplicasGauge.WithLabelValues(namespace, autoscaler, "desired").Set(float64(desired))
+	autoscalerReplicasGauge.WithLabelValues(namespace, autoscaler, "current").Set(float64(current))
+}
+
+func IncAutoscalerScaleCounter(namespace, autoscaler, direction string) {
+	autoscalerScaleCounter.WithLabelValues(namespace, autoscaler, direction).Inc()
+}
+
+func DeleteAutoscaler(namespace, autoscaler string) {
+	labels := prometheus.Labels{"namespace": namespace, "autoscaler": autoscaler}
+	autoscalerJobsGauge.DeletePartialMatch(labels)
+	autoscalerReplicasGauge.DeletePartialMatch(labels)
+	autoscalerScaleCounter.DeletePartialMatch(labels)
+}
+
+func SetRunnerGCOrphansGauge(target string, orphans int) {
+	runnerGCOrphansGauge.WithLabelValues(target).Set(float64(orphans))
+}
+
+func IncRunnerGCRemovedCounter(target string, dryRun bool) {
+	runnerGCRemovedCounter.WithLabelValues(target, strconv.FormatBool(dryRun)).Inc()
+}
+
+func IncRunnerGCFailedCounter(target string) {
+	runnerGCFailedCounter.WithLabelValues(target).Inc()
 }
 
-func SetGitHubActionsJobDone(repository, job string) {
-	githubActionsJobAliveGauge.WithLabelValues(repository, job).Set(0)
-	githubActionsJobFinishedTimestampGauge.WithLabelValues(repository, job).SetToCurrentTime()
+func IncPolicyViolationsCounter(namespace, runner, policy, rule, action string) {
+	policyViolationsCounter.WithLabelValues(namespace, runner, policy, rule, action).Inc()
 }
@@ -51,9 +51,10 @@ var (
 	gitHubActionsRunnerPath = "/opt/actions-runner/run.sh"
 	gitHubActionsRunnerArgs = []string{"--once"}
 
+	arRepositoryScope = os.Getenv("KUBEACTIONS_ACTIONSRUNNER_REPOSITORY_SCOPE")
 	arRepositoryOwner = os.Getenv("KUBEACTIONS_ACTIONSRUNNER_REPOSITORY_OWNER")
 	arRepositoryName  = os.Getenv("KUBEACTIONS_ACTIONSRUNNER_REPOSITORY_NAME")
-	arRepository      = arRepositoryOwner + "/" + arRepositoryName
+	arRepository      = repositoryPath(arRepositoryScope, arRepositoryOwner, arRepositoryName)
 
 	arjName = os.Getenv("KUBEACTIONS_ACTIONSRUNNERJOB_NAME")
 
@@ -355,6 +356,20 @@ func requestDindTermination() error {
 	return nil
 }
 
+// repositoryPath is built like ActionsRunnerRepository.String in the operator, e.g. owner/name, owner or enterprises/owner
+func repositoryPath(scope string, owner string, name string) string {
+	switch scope {
+	case "organization":
+		return owner
+
+	case "enterprise":
+		return "enterprises/" + owner
+
+	default:
+		return owner + "/" + name
+	}
+}
+
 func getGitHubActionsRunnerArgs() []string {
 	if argsRaw, ok := os.LookupEnv(gitHubActionsRunnerArgsEnv); ok {
 		if argsRaw == "" {